@@ -0,0 +1,110 @@
+// Package batch writes records in the background, in batches, so serving a
+// request never waits on the writes it causes
+package batch
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	flushInterval = time.Second
+	maxBatchSize  = 500
+)
+
+// Recorder queues records and hands them to its write function in batches of
+// up to maxBatchSize, at least every flushInterval. Records are dropped (and
+// counted) if the buffer is full or the recorder is closed; the write
+// function counts the records it fails to write, and must not keep the
+// slice it is given.
+type Recorder[T any] struct {
+	write   func(records []T)
+	dropped prometheus.Counter
+	queue   chan T
+	done    chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewRecorder starts a recorder buffering up to bufferSize records
+func NewRecorder[T any](bufferSize int, dropped prometheus.Counter, write func(records []T)) *Recorder[T] {
+	r := &Recorder[T]{
+		write:   write,
+		dropped: dropped,
+		queue:   make(chan T, bufferSize),
+		done:    make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// Record queues records for writing
+func (r *Recorder[T]) Record(records ...T) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, record := range records {
+		if r.closed {
+			r.dropped.Inc()
+			continue
+		}
+
+		select {
+		case r.queue <- record:
+		default:
+			r.dropped.Inc()
+		}
+	}
+}
+
+// Close stops accepting records and waits for the buffer to be written or
+// for ctx to end
+func (r *Recorder[T]) Close(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.queue)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Recorder[T]) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	var batch []T
+	flush := func() {
+		if len(batch) > 0 {
+			r.write(batch)
+			batch = batch[:0]
+		}
+	}
+
+	for {
+		select {
+		case record, ok := <-r.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, record)
+			if len(batch) >= maxBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
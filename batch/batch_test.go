@@ -0,0 +1,62 @@
+package batch
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRecorder(t *testing.T) {
+	tests := []struct {
+		name        string
+		records     int
+		closeFirst  bool
+		wantBatches []int // sizes of the batches written
+		wantDropped float64
+	}{
+		{name: "writes on close", records: 3, wantBatches: []int{3}},
+		{name: "splits large batches", records: maxBatchSize + 2, wantBatches: []int{maxBatchSize, 2}},
+		{name: "drops records once closed", records: 2, closeFirst: true, wantDropped: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dropped := prometheus.NewCounter(prometheus.CounterOpts{Name: "dropped"})
+			var written []int
+			var batches []int
+			recorder := NewRecorder(tt.records, dropped, func(records []int) {
+				batches = append(batches, len(records))
+				written = append(written, records...)
+			})
+			if tt.closeFirst {
+				if err := recorder.Close(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var want []int
+			for i := 0; i < tt.records; i++ {
+				recorder.Record(i)
+				if !tt.closeFirst {
+					want = append(want, i)
+				}
+			}
+			if err := recorder.Close(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(written, want) {
+				t.Errorf("written = %v, want %v", written, want)
+			}
+			if !reflect.DeepEqual(batches, tt.wantBatches) {
+				t.Errorf("batches = %v, want %v", batches, tt.wantBatches)
+			}
+			if got := testutil.ToFloat64(dropped); got != tt.wantDropped {
+				t.Errorf("dropped = %v, want %v", got, tt.wantDropped)
+			}
+		})
+	}
+}
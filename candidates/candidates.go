@@ -0,0 +1,109 @@
+// Package candidates gathers the posts a recommendation page is ranked from
+// out of several sources, each proposing posts for a different reason
+package candidates
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/CircleConnectApp/feed-service/metrics"
+)
+
+// Source names
+const (
+	Popular              = "popular"               // most liked posts overall
+	PreferredCommunities = "preferred_communities" // most liked posts in the user's preferred communities
+	PreferredTags        = "preferred_tags"        // most liked posts with the user's preferred tags
+	EngagedAuthors       = "engaged_authors"       // recent posts by authors the user engaged with
+	Fresh                = "fresh"                 // newest posts overall
+)
+
+// Source proposes up to Limit posts
+type Source[T any] struct {
+	Name  string
+	Limit int
+	// Fetch returns the source's posts and how many it has in total
+	Fetch func(ctx context.Context, limit int) ([]T, int, error)
+}
+
+// Candidate is a post with the sources that proposed it
+type Candidate[T any] struct {
+	Post    T
+	Sources []string
+}
+
+// Result is the merged output of the sources
+type Result[T any] struct {
+	Candidates []Candidate[T]
+	Totals     map[string]int // total posts per source that succeeded
+	Returned   map[string]int // posts returned per source that succeeded
+}
+
+// Remaining returns how many posts the sources have beyond those they
+// returned; posts several sources have are counted once per source
+func (r *Result[T]) Remaining() int {
+	remaining := 0
+	for name, total := range r.Totals {
+		remaining += max(total-r.Returned[name], 0)
+	}
+	return remaining
+}
+
+// Generate queries the sources concurrently and merges their posts, keeping
+// the first occurrence of each post in source order. A failed source is
+// logged and skipped; Generate only fails when every source does.
+func Generate[T any](ctx context.Context, feed string, sources []Source[T], key func(T) string) (*Result[T], error) {
+	type output struct {
+		posts []T
+		total int
+		err   error
+	}
+	outputs := make([]output, len(sources))
+
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		go func(i int, source Source[T]) {
+			defer wg.Done()
+			posts, total, err := source.Fetch(ctx, source.Limit)
+			outputs[i] = output{posts: posts, total: total, err: err}
+		}(i, source)
+	}
+	wg.Wait()
+
+	result := &Result[T]{Totals: make(map[string]int, len(sources)), Returned: make(map[string]int, len(sources))}
+	index := make(map[string]int)
+	var errs []error
+	for i, out := range outputs {
+		name := sources[i].Name
+		if out.err != nil {
+			metrics.CandidateSourceErrors.WithLabelValues(feed, name).Inc()
+			slog.WarnContext(ctx, "Candidate source failed", "feed", feed, "source", name, "error", out.err)
+			errs = append(errs, out.err)
+			continue
+		}
+		result.Totals[name] = out.total
+		result.Returned[name] = len(out.posts)
+		metrics.Candidates.WithLabelValues(feed, name).Add(float64(len(out.posts)))
+
+		for _, post := range out.posts {
+			k := key(post)
+			if j, ok := index[k]; ok {
+				c := &result.Candidates[j]
+				if c.Sources[len(c.Sources)-1] != name {
+					c.Sources = append(c.Sources, name)
+				}
+				continue
+			}
+			index[k] = len(result.Candidates)
+			result.Candidates = append(result.Candidates, Candidate[T]{Post: post, Sources: []string{name}})
+		}
+	}
+
+	if len(sources) > 0 && len(errs) == len(sources) {
+		return nil, errors.Join(errs...)
+	}
+	return result, nil
+}
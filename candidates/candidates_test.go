@@ -0,0 +1,80 @@
+package candidates
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// static is a source returning posts out of total, or err
+func static(name string, posts []string, total int, err error) Source[string] {
+	return Source[string]{Name: name, Limit: len(posts), Fetch: func(ctx context.Context, limit int) ([]string, int, error) {
+		return posts, total, err
+	}}
+}
+
+func TestGenerate(t *testing.T) {
+	failed := errors.New("unavailable")
+
+	tests := []struct {
+		name          string
+		sources       []Source[string]
+		wantPosts     []string
+		wantSources   [][]string
+		wantRemaining int
+		wantErr       bool
+	}{
+		{
+			name:          "merges in source order",
+			sources:       []Source[string]{static(Popular, []string{"a", "b"}, 10, nil), static(Fresh, []string{"c"}, 1, nil)},
+			wantPosts:     []string{"a", "b", "c"},
+			wantSources:   [][]string{{Popular}, {Popular}, {Fresh}},
+			wantRemaining: 8,
+		},
+		{
+			name:          "keeps the first occurrence of a post",
+			sources:       []Source[string]{static(Popular, []string{"a", "b"}, 2, nil), static(PreferredTags, []string{"b", "c", "b"}, 5, nil)},
+			wantPosts:     []string{"a", "b", "c"},
+			wantSources:   [][]string{{Popular}, {Popular, PreferredTags}, {PreferredTags}},
+			wantRemaining: 2,
+		},
+		{
+			name:          "skips a failed source",
+			sources:       []Source[string]{static(Popular, nil, 0, failed), static(Fresh, []string{"c"}, 3, nil)},
+			wantPosts:     []string{"c"},
+			wantSources:   [][]string{{Fresh}},
+			wantRemaining: 2,
+		},
+		{
+			name:    "fails when every source does",
+			sources: []Source[string]{static(Popular, nil, 0, failed), static(Fresh, nil, 0, failed)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Generate(context.Background(), "test", tt.sources, func(post string) string { return post })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var posts []string
+			var sources [][]string
+			for _, c := range result.Candidates {
+				posts = append(posts, c.Post)
+				sources = append(sources, c.Sources)
+			}
+			if !reflect.DeepEqual(posts, tt.wantPosts) || !reflect.DeepEqual(sources, tt.wantSources) {
+				t.Errorf("candidates = %v from %v, want %v from %v", posts, sources, tt.wantPosts, tt.wantSources)
+			}
+			if got := result.Remaining(); got != tt.wantRemaining {
+				t.Errorf("remaining = %d, want %d", got, tt.wantRemaining)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv unsets every configuration variable for the test
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, f := range append(fields(), field{env: "CONFIG_FILE"}) {
+		t.Setenv(f.env, "")
+		os.Unsetenv(f.env)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string // YAML config file contents
+		env     map[string]string
+		args    []string
+		check   func(t *testing.T, cfg Config)
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, cfg Config) {
+				if cfg.Port != Default().Port || cfg.LogFormat != "text" {
+					t.Errorf("port %q, log format %q, want the defaults", cfg.Port, cfg.LogFormat)
+				}
+			},
+		},
+		{
+			name: "flags override env, env overrides the file",
+			file: "port: 9000\ngrpc_port: 9001\nfeed_request_timeout: 3s\n",
+			env:  map[string]string{"PORT": "9100"},
+			args: []string{"--port", "9200"},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.Port != "9200" || cfg.GRPCPort != "9001" || cfg.FeedRequestTimeout != 3*time.Second {
+					t.Errorf("port %q, grpc port %q, timeout %v, want 9200, 9001 and 3s", cfg.Port, cfg.GRPCPort, cfg.FeedRequestTimeout)
+				}
+			},
+		},
+		{
+			name: "production logs JSON",
+			env:  map[string]string{"ENVIRONMENT": "production", "JWT_SECRET": strings.Repeat("x", minProductionSecretLength), "POSTGRES_URI": "postgres://feed:secret@db:5432/feed"},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.LogFormat != "json" {
+					t.Errorf("log format %q, want json", cfg.LogFormat)
+				}
+			},
+		},
+		{name: "production refuses default secrets", env: map[string]string{"ENVIRONMENT": "production"}, wantErr: "development default"},
+		{name: "production refuses short secrets", env: map[string]string{"ENVIRONMENT": "production", "JWT_SECRET": "short"}, wantErr: "at least 32 characters"},
+		{name: "invalid duration", env: map[string]string{"FEED_REQUEST_TIMEOUT": "soon"}, wantErr: "invalid duration"},
+		{name: "invalid URL scheme", env: map[string]string{"POST_SERVICE_URL": "ftp://posts"}, wantErr: "post_service_url scheme"},
+		{name: "unknown file setting", file: "colour: blue\n", wantErr: `unknown setting "colour"`},
+		{name: "nested file setting", file: "port:\n  http: 80\n", wantErr: "must be a scalar"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			args := tt.args
+			if tt.file != "" {
+				path := filepath.Join(t.TempDir(), "config.yaml")
+				if err := os.WriteFile(path, []byte(tt.file), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				args = append([]string{"--config", path}, args...)
+			}
+
+			cfg, err := Load(args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Default()
+	cfg.JWTSecret = "s3cret"
+	cfg.PostgresURI = "postgres://feed:hunter2@db:5432/feed"
+
+	redacted := cfg.Redacted()
+	if redacted["jwt_secret"] != "[REDACTED]" {
+		t.Errorf("jwt_secret = %v, want it redacted", redacted["jwt_secret"])
+	}
+	if uri, _ := redacted["postgres_uri"].(string); strings.Contains(uri, "hunter2") || !strings.Contains(uri, "db:5432") {
+		t.Errorf("postgres_uri = %q, want the password redacted", uri)
+	}
+	if redacted["port"] != cfg.Port {
+		t.Errorf("port = %v, want %q", redacted["port"], cfg.Port)
+	}
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/logger"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Load builds the configuration from, in increasing precedence: defaults, the
+// optional YAML or TOML file named by --config or CONFIG_FILE, environment
+// variables, and command-line flags. The result is validated before it is
+// returned.
+func Load(args []string) (Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("feed-service", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file (env CONFIG_FILE)")
+	flagValues := make(map[string]*string)
+	for _, f := range fields() {
+		flagValues[f.key] = fs.String(strings.ReplaceAll(f.key, "_", "-"), "", fmt.Sprintf("overrides %s (env %s)", f.key, f.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	if *configFile != "" {
+		values, err := readFile(*configFile)
+		if err != nil {
+			return cfg, err
+		}
+		for key, value := range values {
+			if err := cfg.set(key, value); err != nil {
+				return cfg, fmt.Errorf("%s: %w", *configFile, err)
+			}
+		}
+	}
+
+	for _, f := range fields() {
+		if value, exists := os.LookupEnv(f.env); exists {
+			if err := cfg.set(f.key, value); err != nil {
+				return cfg, fmt.Errorf("environment variable %s: %w", f.env, err)
+			}
+		}
+	}
+
+	var flagErr error
+	fs.Visit(func(fl *flag.Flag) {
+		key := strings.ReplaceAll(fl.Name, "-", "_")
+		if value, ok := flagValues[key]; ok && flagErr == nil {
+			if err := cfg.set(key, *value); err != nil {
+				flagErr = fmt.Errorf("flag --%s: %w", fl.Name, err)
+			}
+		}
+	})
+	if flagErr != nil {
+		return cfg, flagErr
+	}
+
+	if cfg.LogFormat == "" {
+		cfg.LogFormat = "text"
+		if cfg.Environment == "production" {
+			cfg.LogFormat = "json"
+		}
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// Redacted returns the effective configuration keyed by field key, with
+// secrets masked, for printing at startup
+func (c Config) Redacted() map[string]interface{} {
+	out := make(map[string]interface{})
+	v := reflect.ValueOf(c)
+	for _, f := range fields() {
+		value := v.Field(f.index)
+		switch f.secret {
+		case "true":
+			if value.String() != "" {
+				out[f.key] = "[REDACTED]"
+			} else {
+				out[f.key] = ""
+			}
+		case "url":
+			out[f.key] = logger.RedactURL(value.String())
+		default:
+			if d, ok := value.Interface().(time.Duration); ok {
+				out[f.key] = d.String()
+			} else {
+				out[f.key] = value.Interface()
+			}
+		}
+	}
+	return out
+}
+
+type field struct {
+	index  int
+	key    string
+	env    string
+	secret string
+}
+
+func fields() []field {
+	t := reflect.TypeOf(Config{})
+	out := make([]field, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		out = append(out, field{
+			index:  i,
+			key:    sf.Tag.Get("key"),
+			env:    sf.Tag.Get("env"),
+			secret: sf.Tag.Get("secret"),
+		})
+	}
+	return out
+}
+
+// set parses raw into the field named key
+func (c *Config) set(key, raw string) error {
+	for _, f := range fields() {
+		if f.key != key {
+			continue
+		}
+
+		value := reflect.ValueOf(c).Elem().Field(f.index)
+		raw = strings.TrimSpace(raw)
+
+		switch value.Interface().(type) {
+		case string:
+			value.SetString(raw)
+		case time.Duration:
+			d, err := time.ParseDuration(raw)
+			if err != nil {
+				return fmt.Errorf("%s: invalid duration %q", key, raw)
+			}
+			value.SetInt(int64(d))
+		case int:
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				return fmt.Errorf("%s: invalid integer %q", key, raw)
+			}
+			value.SetInt(int64(n))
+		case float64:
+			n, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return fmt.Errorf("%s: invalid number %q", key, raw)
+			}
+			value.SetFloat(n)
+		case bool:
+			b, err := strconv.ParseBool(raw)
+			if err != nil {
+				return fmt.Errorf("%s: invalid boolean %q", key, raw)
+			}
+			value.SetBool(b)
+		default:
+			return fmt.Errorf("%s: unsupported field type %s", key, value.Type())
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown setting %q", key)
+}
+
+// readFile reads a flat YAML or TOML document into raw string values
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return nil, fmt.Errorf("%s: config file must be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	values := make(map[string]string, len(doc))
+	for key, value := range doc {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			return nil, fmt.Errorf("%s: setting %q must be a scalar", path, key)
+		}
+		values[key] = fmt.Sprint(value)
+	}
+	return values, nil
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/logger"
+	"github.com/CircleConnectApp/feed-service/ratelimit"
+)
+
+const minProductionSecretLength = 32
+
+// Validate checks every setting and reports all problems at once. In
+// production it also refuses the development default secrets.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("port: %q is not a valid TCP port", c.Port))
+	}
+	if port, err := strconv.Atoi(c.GRPCPort); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("grpc_port: %q is not a valid TCP port", c.GRPCPort))
+	} else if c.GRPCPort == c.Port {
+		errs = append(errs, errors.New("grpc_port: must differ from port"))
+	}
+
+	check(validateURL("mongo_uri", c.MongoURI, "mongodb", "mongodb+srv"))
+	check(validateURL("postgres_uri", c.PostgresURI, "postgres", "postgresql"))
+	check(validateURL("user_service_url", c.UserServiceURL, "http", "https"))
+	check(validateURL("post_service_url", c.PostServiceURL, "http", "https"))
+	check(validateURL("community_service_url", c.CommunityServiceURL, "http", "https"))
+	check(validateURL("web_app_url", c.WebAppURL, "http", "https"))
+
+	if c.MongoDBName == "" {
+		errs = append(errs, errors.New("mongo_db_name: must not be empty"))
+	}
+	check(validateOneOf("environment", c.Environment, "development", "test", "staging", "production"))
+	if c.JWTSecret == "" {
+		errs = append(errs, errors.New("jwt_secret: must not be empty"))
+	}
+
+	check(validateOneOf("rate_limit_backend", c.RateLimitBackend, "memory", "mongo"))
+	for _, limit := range []struct{ key, spec string }{
+		{"rate_limit_feed_user", c.RateLimitFeedUser},
+		{"rate_limit_feed_ip", c.RateLimitFeedIP},
+		{"rate_limit_preferences_user", c.RateLimitPreferencesUser},
+		{"rate_limit_preferences_ip", c.RateLimitPreferencesIP},
+	} {
+		if _, err := ratelimit.ParseRule(limit.spec); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", limit.key, err))
+		}
+	}
+
+	check(validateOneOf("tracing_exporter", c.TracingExporter, "otlp", "none"))
+	if c.TracingSampleRatio < 0 || c.TracingSampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("tracing_sample_ratio: %v must be between 0 and 1", c.TracingSampleRatio))
+	}
+
+	check(validateOneOf("log_level", c.LogLevel, "debug", "info", "warn", "error"))
+	check(validateOneOf("log_format", c.LogFormat, "json", "text"))
+
+	for _, timeout := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"health_check_timeout", c.HealthCheckTimeout},
+		{"http_read_timeout", c.HTTPReadTimeout},
+		{"http_read_header_timeout", c.HTTPReadHeaderTimeout},
+		{"http_write_timeout", c.HTTPWriteTimeout},
+		{"http_idle_timeout", c.HTTPIdleTimeout},
+		{"shutdown_timeout", c.ShutdownTimeout},
+		{"runtime_settings_reload_interval", c.RuntimeSettingsReloadInterval},
+		{"feed_request_timeout", c.FeedRequestTimeout},
+	} {
+		if timeout.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s: must be positive", timeout.key))
+		}
+	}
+	if c.ShutdownDrainDelay < 0 {
+		errs = append(errs, errors.New("shutdown_drain_delay: must not be negative"))
+	}
+	if c.HTTPMaxHeaderBytes < 1024 {
+		errs = append(errs, fmt.Errorf("http_max_header_bytes: %d is below the 1024 byte minimum", c.HTTPMaxHeaderBytes))
+	}
+	if c.InteractionBufferSize < 1 {
+		errs = append(errs, errors.New("interaction_buffer_size: must be at least 1"))
+	}
+
+	if c.Environment == "production" {
+		if c.JWTSecret == defaultJWTSecret {
+			errs = append(errs, errors.New("jwt_secret: the development default must not be used in production"))
+		} else if len(c.JWTSecret) < minProductionSecretLength {
+			errs = append(errs, fmt.Errorf("jwt_secret: must be at least %d characters in production", minProductionSecretLength))
+		}
+		if c.PostgresURI == defaultPostgresURI {
+			errs = append(errs, errors.New("postgres_uri: the development default credentials must not be used in production"))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateURL(key, raw string, schemes ...string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%s: %q is not a valid URL", key, logger.RedactURL(raw))
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%s: %q has no host", key, logger.RedactURL(raw))
+	}
+	return validateOneOf(key+" scheme", u.Scheme, schemes...)
+}
+
+func validateOneOf(key, value string, allowed ...string) error {
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: %q must be one of %v", key, value, allowed)
+}
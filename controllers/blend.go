@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"github.com/CircleConnectApp/feed-service/models"
+	"github.com/CircleConnectApp/feed-service/settings"
+)
+
+// homeSlots is the number of places on a page of size limit that the home
+// feed gives to recommendations and to trending posts
+func homeSlots(limit int, rules settings.HomeFeed) (recommended, trending int) {
+	recommended = min(int(float64(limit)*rules.RecommendedRatio+0.5), limit)
+	trending = min(int(float64(limit)*rules.TrendingRatio+0.5), limit-recommended)
+	return recommended, trending
+}
+
+// blendFeeds interleaves joined-community posts with recommendations and
+// trending posts. A recommended or trending post is placed once the page has
+// reached the first allowed position, enough joined posts separate it from
+// the previous one and its source's share so far is below the configured
+// ratio; recommendations take precedence. When a source runs out the others
+// fill the rest of the page. Posts are deduplicated by PostID, keeping the
+// first occurrence.
+func blendFeeds(joined, recommended, trending []models.FeedItem, limit int, rules settings.HomeFeed) []models.FeedItem {
+	blended := make([]models.FeedItem, 0, limit)
+	seen := make(map[string]bool, len(joined)+len(recommended)+len(trending))
+	sinceInserted := rules.MinRecommendedGap
+
+	next := func(items *[]models.FeedItem) (models.FeedItem, bool) {
+		for len(*items) > 0 {
+			item := (*items)[0]
+			*items = (*items)[1:]
+			if key := item.PostID.Hex(); !seen[key] {
+				seen[key] = true
+				return item, true
+			}
+		}
+		return models.FeedItem{}, false
+	}
+
+	inserted := []struct {
+		items  *[]models.FeedItem
+		ratio  float64
+		placed int
+	}{
+		{&recommended, rules.RecommendedRatio, 0},
+		{&trending, rules.TrendingRatio, 0},
+	}
+
+	for len(blended) < limit {
+		position := len(blended)
+		placed := false
+		if position >= rules.FirstRecommendedPosition && sinceInserted >= rules.MinRecommendedGap {
+			for i := range inserted {
+				source := &inserted[i]
+				if float64(source.placed) >= source.ratio*float64(position+1) {
+					continue
+				}
+				if item, ok := next(source.items); ok {
+					blended = append(blended, item)
+					source.placed++
+					sinceInserted = 0
+					placed = true
+					break
+				}
+			}
+		}
+		if placed {
+			continue
+		}
+		if item, ok := next(&joined); ok {
+			blended = append(blended, item)
+			sinceInserted++
+			continue
+		}
+		// Joined posts ran out; fill with the others regardless of spacing
+		for i := range inserted {
+			if item, ok := next(inserted[i].items); ok {
+				blended = append(blended, item)
+				inserted[i].placed++
+				sinceInserted = 0
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			break
+		}
+	}
+
+	return blended
+}
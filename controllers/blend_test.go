@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/CircleConnectApp/feed-service/models"
+	"github.com/CircleConnectApp/feed-service/settings"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// blendItems returns one feed item per name, using the name as the last
+// bytes of its post ID so results read back as names
+func blendItems(names ...string) []models.FeedItem {
+	items := make([]models.FeedItem, len(names))
+	for i, name := range names {
+		var id primitive.ObjectID
+		copy(id[len(id)-len(name):], name)
+		items[i] = models.FeedItem{PostID: id}
+	}
+	return items
+}
+
+func blendNames(items []models.FeedItem) string {
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = strings.TrimLeft(string(item.PostID[:]), "\x00")
+	}
+	return strings.Join(names, " ")
+}
+
+func TestHomeSlots(t *testing.T) {
+	tests := []struct {
+		limit           int
+		rules           settings.HomeFeed
+		wantRecommended int
+		wantTrending    int
+	}{
+		{limit: 20, rules: settings.HomeFeed{RecommendedRatio: 0.25, TrendingRatio: 0.1}, wantRecommended: 5, wantTrending: 2},
+		{limit: 3, rules: settings.HomeFeed{RecommendedRatio: 0.25, TrendingRatio: 0.1}, wantRecommended: 1, wantTrending: 0},
+		{limit: 10, rules: settings.HomeFeed{RecommendedRatio: 1}, wantRecommended: 10, wantTrending: 0},
+		{limit: 10, rules: settings.HomeFeed{RecommendedRatio: 0.5, TrendingRatio: 0.5}, wantRecommended: 5, wantTrending: 5},
+		{limit: 1, rules: settings.HomeFeed{RecommendedRatio: 0.5, TrendingRatio: 0.5}, wantRecommended: 1, wantTrending: 0},
+	}
+	for _, tt := range tests {
+		recommended, trending := homeSlots(tt.limit, tt.rules)
+		if recommended != tt.wantRecommended || trending != tt.wantTrending {
+			t.Errorf("homeSlots(%d, %+v) = %d, %d, want %d, %d", tt.limit, tt.rules, recommended, trending, tt.wantRecommended, tt.wantTrending)
+		}
+	}
+}
+
+func TestBlendFeeds(t *testing.T) {
+	rules := settings.HomeFeed{RecommendedRatio: 0.25, TrendingRatio: 0.25, FirstRecommendedPosition: 1, MinRecommendedGap: 1}
+
+	tests := []struct {
+		name        string
+		joined      []string
+		recommended []string
+		trending    []string
+		limit       int
+		rules       settings.HomeFeed
+		want        string
+	}{
+		{
+			name:        "interleaves by ratio and gap",
+			joined:      []string{"j1", "j2", "j3", "j4", "j5"},
+			recommended: []string{"r1", "r2"},
+			trending:    []string{"t1", "t2"},
+			limit:       8,
+			rules:       rules,
+			want:        "j1 r1 j2 t1 j3 r2 j4 t2",
+		},
+		{
+			name:        "recommendations wait for the first position",
+			joined:      []string{"j1", "j2", "j3"},
+			recommended: []string{"r1"},
+			limit:       4,
+			rules:       settings.HomeFeed{RecommendedRatio: 0.5, FirstRecommendedPosition: 2},
+			want:        "j1 j2 r1 j3",
+		},
+		{
+			name:     "trending fills in for missing recommendations",
+			joined:   []string{"j1", "j2", "j3"},
+			trending: []string{"t1", "t2"},
+			limit:    5,
+			rules:    rules,
+			want:     "j1 t1 j2 j3 t2",
+		},
+		{
+			name:        "joined posts running out",
+			joined:      []string{"j1"},
+			recommended: []string{"r1", "r2"},
+			trending:    []string{"t1"},
+			limit:       5,
+			rules:       rules,
+			want:        "j1 r1 r2 t1",
+		},
+		{
+			name:        "duplicates keep their first place",
+			joined:      []string{"j1", "x", "j2"},
+			recommended: []string{"x", "r1"},
+			trending:    []string{"j1", "t1"},
+			limit:       6,
+			rules:       rules,
+			want:        "j1 x j2 t1 r1",
+		},
+		{
+			name:   "page limit",
+			joined: []string{"j1", "j2", "j3"},
+			limit:  2,
+			rules:  rules,
+			want:   "j1 j2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := blendFeeds(blendItems(tt.joined...), blendItems(tt.recommended...), blendItems(tt.trending...), tt.limit, tt.rules)
+			if names := blendNames(got); names != tt.want {
+				t.Errorf("blendFeeds() = %q, want %q", names, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/candidates"
+	"github.com/CircleConnectApp/feed-service/interactions"
+	"github.com/CircleConnectApp/feed-service/models"
+	"github.com/CircleConnectApp/feed-service/settings"
+)
+
+// maxRecommendedCandidates bounds the posts each candidate source is asked
+// for, and so how deep recommendations page
+const maxRecommendedCandidates = 500
+
+// maxBackfillRounds bounds how many times the sources are asked for more
+// candidates when filtering leaves a recommendations page short
+const maxBackfillRounds = 3
+
+// recommendationSources returns the enabled candidate sources for the first
+// depth recommendations of a user. Each source is asked for the first posts
+// of its list, its share of depth, so that pages are cut from one merged
+// ranking. Sources that depend on preferences the user has not set are left
+// out, and preferred communities in excluded are not queried.
+func (fc *FeedController) recommendationSources(userID int, pref *models.UserPreference, excluded map[int]bool, depth int, snap *settings.Snapshot) []candidates.Source[upstreamPost] {
+	shares := snap.Candidates
+	var sources []candidates.Source[upstreamPost]
+	add := func(name string, share float64, fetch func(ctx context.Context, limit int) ([]upstreamPost, int, error)) {
+		limit := min(int(math.Ceil(share*float64(depth))), maxRecommendedCandidates)
+		if limit > 0 {
+			sources = append(sources, candidates.Source[upstreamPost]{Name: name, Limit: limit, Fetch: fetch})
+		}
+	}
+	list := func(operation string, params url.Values) func(ctx context.Context, limit int) ([]upstreamPost, int, error) {
+		return func(ctx context.Context, limit int) ([]upstreamPost, int, error) {
+			params.Set("page", "1")
+			params.Set("limit", strconv.Itoa(limit))
+			return fc.listPosts(ctx, operation, params)
+		}
+	}
+
+	add(candidates.Popular, shares.Popular, list("popular_posts", url.Values{"sort": {"popular"}}))
+	var preferredCommunities []int
+	if pref != nil {
+		for _, communityID := range pref.PreferedCommunities {
+			if !excluded[communityID] {
+				preferredCommunities = append(preferredCommunities, communityID)
+			}
+		}
+	}
+	if len(preferredCommunities) > 0 {
+		add(candidates.PreferredCommunities, shares.PreferredCommunities,
+			list("preferred_community_posts", url.Values{"sort": {"popular"}, "community_id": {joinIDs(preferredCommunities)}}))
+	}
+	if pref != nil && len(pref.PreferedTags) > 0 {
+		add(candidates.PreferredTags, shares.PreferredTags,
+			list("preferred_tag_posts", url.Values{"sort": {"popular"}, "tags": {strings.Join(pref.PreferedTags, ",")}}))
+	}
+	add(candidates.EngagedAuthors, shares.EngagedAuthors, func(ctx context.Context, limit int) ([]upstreamPost, int, error) {
+		since := time.Now().AddDate(0, 0, -shares.EngagementLookbackDays)
+		authors, err := interactions.EngagedAuthors(ctx, fc.pgDB, userID, since, shares.MaxEngagedAuthors)
+		if err != nil || len(authors) == 0 {
+			return nil, 0, err
+		}
+		return list("engaged_author_posts", url.Values{"author_id": {joinIDs(authors)}})(ctx, limit)
+	})
+	add(candidates.Fresh, shares.Fresh, list("fresh_posts", url.Values{}))
+	return sources
+}
+
+// listPosts retrieves a page of posts from the post service
+func (fc *FeedController) listPosts(ctx context.Context, operation string, params url.Values) ([]upstreamPost, int, error) {
+	postsURL := fmt.Sprintf("%s/posts?%s", fc.config.PostServiceURL, params.Encode())
+	resp, err := fc.getUpstream(ctx, "post", operation, postsURL)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("failed to get posts: status %d", resp.StatusCode)
+	}
+
+	var postsResp postsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&postsResp); err != nil {
+		return nil, 0, err
+	}
+	return postsResp.Posts, postsResp.Total, nil
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/candidates"
+	"github.com/CircleConnectApp/feed-service/models"
+	"github.com/CircleConnectApp/feed-service/settings"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestRecommendationSources(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	createdAt := time.Now().Add(-time.Hour)
+	ps := &postService{posts: []upstreamPost{
+		{ID: testPostID(1), UserID: 2, CommunityID: 5, Tags: []string{"go"}, LikeCount: 3, CreatedAt: createdAt},
+		{ID: testPostID(2), UserID: 3, CommunityID: 6, Tags: []string{"rust", "c"}, LikeCount: 2, CreatedAt: createdAt},
+		{ID: testPostID(3), UserID: 4, CommunityID: 7, Tags: []string{"zig"}, LikeCount: 1, CreatedAt: createdAt},
+	}}
+	pref := &models.UserPreference{PreferedTags: []string{"go", "rust"}, PreferedCommunities: []int{5, 6}}
+
+	tests := []struct {
+		name       string
+		source     string
+		depth      int
+		wantParams map[string]string
+		wantPosts  []string
+	}{
+		{name: "popular", source: candidates.Popular, depth: 10, wantParams: map[string]string{"sort": "popular", "page": "1", "limit": "10"}, wantPosts: []string{testPostID(1), testPostID(2), testPostID(3)}},
+		// Community 6 is excluded
+		{name: "preferred communities", source: candidates.PreferredCommunities, depth: 10, wantParams: map[string]string{"sort": "popular", "community_id": "5", "page": "1", "limit": "5"}, wantPosts: []string{testPostID(1)}},
+		// A post matches when it carries any of the tags
+		{name: "preferred tags", source: candidates.PreferredTags, depth: 10, wantParams: map[string]string{"sort": "popular", "tags": "go,rust", "page": "1", "limit": "5"}, wantPosts: []string{testPostID(1), testPostID(2)}},
+		{name: "fresh", source: candidates.Fresh, depth: 10, wantParams: map[string]string{"sort": "", "page": "1", "limit": "3"}, wantPosts: []string{testPostID(1), testPostID(2), testPostID(3)}},
+		{name: "limits are capped", source: candidates.Popular, depth: 10000, wantParams: map[string]string{"limit": "500"}, wantPosts: []string{testPostID(1), testPostID(2), testPostID(3)}},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			upstream := http.NewServeMux()
+			upstream.Handle("/posts", ps)
+			fc := testController(mt, upstream, func(s *settings.Settings) {
+				s.Candidates.EngagedAuthors = 0
+			})
+			ps.queries = nil
+
+			var source *candidates.Source[upstreamPost]
+			sources := fc.recommendationSources(1, pref, map[int]bool{6: true}, tt.depth, fc.settings.Current())
+			for i := range sources {
+				if sources[i].Name == tt.source {
+					source = &sources[i]
+				}
+			}
+			if source == nil {
+				mt.Fatalf("no %s source", tt.source)
+			}
+			posts, total, err := source.Fetch(context.Background(), source.Limit)
+			if err != nil {
+				mt.Fatal(err)
+			}
+
+			if len(ps.queries) != 1 {
+				mt.Fatalf("%d requests, want 1", len(ps.queries))
+			}
+			for param, want := range tt.wantParams {
+				if got := ps.queries[0].Get(param); got != want {
+					mt.Errorf("%s = %q, want %q", param, got, want)
+				}
+			}
+			var ids []string
+			for _, post := range posts {
+				ids = append(ids, post.ID)
+			}
+			if len(ids) != len(tt.wantPosts) || total != len(tt.wantPosts) {
+				mt.Fatalf("posts = %v of %d, want %v", ids, total, tt.wantPosts)
+			}
+			for i := range ids {
+				if ids[i] != tt.wantPosts[i] {
+					mt.Errorf("posts = %v, want %v", ids, tt.wantPosts)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,382 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/metrics"
+	"github.com/CircleConnectApp/feed-service/models"
+	"github.com/CircleConnectApp/feed-service/pins"
+	"github.com/CircleConnectApp/feed-service/ranking"
+	"github.com/CircleConnectApp/feed-service/tracing"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	errCommunityNotFound = errors.New("community not found")
+	errPostNotFound      = errors.New("post not found")
+)
+
+// community is a community as returned by the community service
+type community struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Icon      string `json:"icon"`
+	IsPrivate bool   `json:"is_private"`
+}
+
+// GetCommunityFeed retrieves the feed of a single community. Private
+// communities are only visible to their members.
+func (fc *FeedController) GetCommunityFeed(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	communityID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || communityID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid community ID"})
+		return
+	}
+
+	var query models.CommunityFeedQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	view, ok := bindView(c)
+	if !ok {
+		return
+	}
+
+	feed, err := fc.CommunityFeed(c.Request.Context(), userID.(int), communityID, query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	fc.recordImpressions(c.Request.Context(), userID.(int), "community", feed)
+
+	respondFeed(c, feed, view)
+}
+
+// CommunityFeed builds a community's feed for a user
+func (fc *FeedController) CommunityFeed(ctx context.Context, userID, communityID int, query models.CommunityFeedQuery) (*models.Feed, error) {
+	ctx, cancel := fc.withBudget(ctx)
+	defer cancel()
+
+	// Set default values
+	fc.applyPageLimits(&query.Page, &query.Limit)
+	if query.Sort == "" {
+		query.Sort = "hot"
+	}
+	if query.Sort == "top" && query.Period == "" {
+		query.Period = "day"
+	}
+
+	if _, err := fc.viewableCommunity(ctx, userID, communityID); err != nil {
+		return nil, err
+	}
+
+	feed, err := fc.buildCommunityFeed(ctx, communityID, query)
+	if err != nil {
+		return nil, stageError(err, "Failed to build community feed")
+	}
+
+	fc.hydrate(ctx, feed.Items)
+	return feed, nil
+}
+
+// PinPost pins a post to the top of a community feed
+func (fc *FeedController) PinPost(c *gin.Context) {
+	communityID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || communityID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid community ID"})
+		return
+	}
+	postID := c.Param("postId")
+	if _, err := primitive.ObjectIDFromHex(postID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	var req struct {
+		PinnedBy int `json:"pinned_by"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	post, err := fc.getPost(ctx, postID)
+	if err != nil {
+		if errors.Is(err, errPostNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get post"})
+		return
+	}
+	if post.CommunityID != communityID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found in this community"})
+		return
+	}
+
+	pin := models.CommunityPin{
+		CommunityID: communityID,
+		PostID:      postID,
+		PinnedBy:    req.PinnedBy,
+		PinnedAt:    time.Now().UTC(),
+	}
+	if err := fc.pins.Pin(ctx, pin); err != nil {
+		if errors.Is(err, pins.ErrTooMany) {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("A community can pin at most %d posts", pins.MaxPerCommunity)})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pin post"})
+		return
+	}
+
+	c.JSON(http.StatusOK, pin)
+}
+
+// UnpinPost removes a pinned post from a community feed
+func (fc *FeedController) UnpinPost(c *gin.Context) {
+	communityID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || communityID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid community ID"})
+		return
+	}
+
+	postID := c.Param("postId")
+	if _, err := primitive.ObjectIDFromHex(postID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	if err := fc.pins.Unpin(c.Request.Context(), communityID, postID); err != nil {
+		if errors.Is(err, pins.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post is not pinned"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unpin post"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// viewableCommunity retrieves a community a user may view. Private
+// communities are only visible to members.
+func (fc *FeedController) viewableCommunity(ctx context.Context, userID, communityID int) (*community, error) {
+	info, err := fc.getCommunity(ctx, communityID)
+	if err != nil {
+		if errors.Is(err, errCommunityNotFound) {
+			return nil, fail(http.StatusNotFound, "Community not found")
+		}
+		return nil, fail(http.StatusInternalServerError, "Failed to get community")
+	}
+	if !info.IsPrivate {
+		return info, nil
+	}
+
+	joinedCommunities, err := fc.getJoinedCommunities(ctx, userID)
+	if err != nil {
+		return nil, fail(http.StatusInternalServerError, "Failed to get joined communities")
+	}
+	for _, id := range joinedCommunities {
+		if id == communityID {
+			return info, nil
+		}
+	}
+	return nil, fail(http.StatusForbidden, "Only members can view this community")
+}
+
+// getCommunity retrieves a community from the community service
+func (fc *FeedController) getCommunity(ctx context.Context, communityID int) (info *community, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "getCommunity")
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	url := fmt.Sprintf("%s/communities/%d", fc.config.CommunityServiceURL, communityID)
+	resp, err := fc.getUpstream(ctx, "community", "get_community", url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errCommunityNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get community: status %d", resp.StatusCode)
+	}
+
+	info = &community{}
+	if err := json.NewDecoder(resp.Body).Decode(info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Posts loads the posts with ids, hydrated together as one response, keyed
+// by ID. Posts that are invalid or cannot be fetched are left out.
+func (fc *FeedController) Posts(ctx context.Context, ids []string) map[string]*models.FeedItem {
+	valid := make([]string, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if primitive.IsValidObjectID(id) && !seen[id] {
+			seen[id] = true
+			valid = append(valid, id)
+		}
+	}
+	fetched := fc.getPosts(ctx, valid)
+
+	ordered := make([]string, 0, len(fetched))
+	items := make([]models.FeedItem, 0, len(fetched))
+	for _, id := range valid {
+		post, ok := fetched[id]
+		if !ok {
+			continue
+		}
+		postID, _ := primitive.ObjectIDFromHex(id)
+		ordered = append(ordered, id)
+		items = append(items, post.toFeedItem(postID))
+	}
+	fc.hydrate(ctx, items)
+
+	posts := make(map[string]*models.FeedItem, len(items))
+	for i, id := range ordered {
+		posts[id] = &items[i]
+	}
+	return posts
+}
+
+// getPost retrieves a single post from the post service
+func (fc *FeedController) getPost(ctx context.Context, postID string) (*upstreamPost, error) {
+	url := fmt.Sprintf("%s/posts/%s", fc.config.PostServiceURL, postID)
+	resp, err := fc.getUpstream(ctx, "post", "get_post", url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errPostNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get post: status %d", resp.StatusCode)
+	}
+
+	var post upstreamPost
+	if err := json.NewDecoder(resp.Body).Decode(&post); err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// buildCommunityFeed constructs a community's feed. It is ranked here over a
+// window of the community's posts, so pinned posts can take the first places
+// of the feed, within the page limit, and be left out of the ranked posts.
+func (fc *FeedController) buildCommunityFeed(ctx context.Context, communityID int, query models.CommunityFeedQuery) (feed *models.Feed, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "buildCommunityFeed")
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+	span.SetAttributes(attribute.Int("feed.community_id", communityID), attribute.String("feed.sort_by", query.Sort))
+
+	// Community feeds call date sorting "new"
+	sortBy := query.Sort
+	if sortBy == "new" {
+		sortBy = "date"
+	}
+
+	posts, err := fc.windowPosts(ctx, "community_posts", url.Values{"community_id": {strconv.Itoa(communityID)}})
+	if err != nil {
+		return nil, err
+	}
+
+	communityPins, err := fc.pins.List(ctx, communityID)
+	if err != nil {
+		// Serve the feed without pins rather than failing it
+		slog.WarnContext(ctx, "Failed to get pinned posts", "community_id", communityID, "error", err)
+		communityPins = nil
+	}
+	pinned := make(map[string]bool, len(communityPins))
+	for _, pin := range communityPins {
+		pinned[pin.PostID] = true
+	}
+
+	// Only the pins on this page are fetched; a pin that cannot be leaves
+	// the page short rather than moving the ranked posts
+	offset := (query.Page - 1) * query.Limit
+	pagePins := communityPins[min(offset, len(communityPins)):min(offset+query.Limit, len(communityPins))]
+	pinnedItems := make([]models.FeedItem, 0, len(pagePins))
+	for _, pin := range pagePins {
+		post, err := fc.getPost(ctx, pin.PostID)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to get pinned post", "community_id", communityID, "post_id", pin.PostID, "error", err)
+			continue
+		}
+		postID, _ := primitive.ObjectIDFromHex(pin.PostID)
+		item := post.toFeedItem(postID)
+		item.Pinned = true
+		pinnedItems = append(pinnedItems, item)
+	}
+
+	now := time.Now()
+	feedItems := make([]models.FeedItem, 0, len(posts))
+	for _, post := range posts {
+		postID, err := primitive.ObjectIDFromHex(post.ID)
+		if err != nil {
+			// Skip invalid post IDs
+			metrics.FeedItemsFiltered.WithLabelValues("community", "invalid_post_id").Inc()
+			continue
+		}
+		if pinned[post.ID] {
+			continue
+		}
+		if !inPeriod(post.CreatedAt, sortBy, query.Period, now) {
+			metrics.FeedItemsFiltered.WithLabelValues("community", "outside_period").Inc()
+			continue
+		}
+
+		feedItem := post.toFeedItem(postID)
+		feedItem.Signals = ranking.Signals{LikeCount: post.LikeCount, CreatedAt: post.CreatedAt}
+		feedItems = append(feedItems, feedItem)
+	}
+
+	// The ranked posts follow the pins, filling the rest of the page
+	sortFeedItems(feedItems, sortBy, now)
+	total := len(communityPins) + len(feedItems)
+	rankedOffset := min(max(offset-len(communityPins), 0), len(feedItems))
+	feedItems = feedItems[rankedOffset:min(rankedOffset+query.Limit-len(pagePins), len(feedItems))]
+
+	// Every post shares the community, so only the author rules apply
+	rules := fc.settings.Current().Diversity
+	rules.MaxConsecutiveCommunity, rules.MaxPerCommunity = 0, 0
+	feedItems = diversify("community", feedItems, rules)
+	metrics.FeedItems.WithLabelValues("community").Observe(float64(len(pinnedItems) + len(feedItems)))
+
+	return &models.Feed{
+		Items: append(pinnedItems, feedItems...),
+		Total: total,
+		Page:  query.Page,
+		Limit: query.Limit,
+	}, nil
+}
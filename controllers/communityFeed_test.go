@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestBuildCommunityFeed(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	// Community 10 has a fresh post, a popular recent post, a post without
+	// likes and the most liked post, which is ten days old
+	now := time.Now()
+	ps := &postService{posts: []upstreamPost{
+		{ID: testPostID(1), UserID: 1, CommunityID: 10, LikeCount: 1, CreatedAt: now.Add(-time.Hour)},
+		{ID: testPostID(2), UserID: 2, CommunityID: 10, LikeCount: 500, CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: testPostID(3), UserID: 3, CommunityID: 10, LikeCount: 0, CreatedAt: now.Add(-3 * time.Hour)},
+		{ID: testPostID(4), UserID: 4, CommunityID: 10, LikeCount: 1000, CreatedAt: now.Add(-10 * 24 * time.Hour)},
+		{ID: testPostID(5), UserID: 5, CommunityID: 20, LikeCount: 50, CreatedAt: now.Add(-time.Hour)},
+	}}
+	window := []string{"community_id=10&limit=200&page=1", "community_id=10&limit=200&page=1&sort=popular"}
+
+	tests := []struct {
+		name        string
+		query       models.CommunityFeedQuery
+		pins        []int
+		wantQueries []string
+		wantPosts   []string
+		wantPinned  int // leading posts marked as pinned
+		wantTotal   int
+	}{
+		{
+			name:        "new",
+			query:       models.CommunityFeedQuery{Sort: "new", Page: 2, Limit: 2},
+			wantQueries: window,
+			wantPosts:   []string{testPostID(3), testPostID(4)}, wantTotal: 4,
+		},
+		{
+			name:        "hot",
+			query:       models.CommunityFeedQuery{Sort: "hot", Page: 1, Limit: 4},
+			wantQueries: window,
+			wantPosts:   []string{testPostID(2), testPostID(1), testPostID(3), testPostID(4)}, wantTotal: 4,
+		},
+		{
+			name:        "hot second page",
+			query:       models.CommunityFeedQuery{Sort: "hot", Page: 2, Limit: 3},
+			wantQueries: window,
+			wantPosts:   []string{testPostID(4)}, wantTotal: 4,
+		},
+		{
+			name:        "rising",
+			query:       models.CommunityFeedQuery{Sort: "rising", Page: 1, Limit: 4},
+			wantQueries: window,
+			wantPosts:   []string{testPostID(2), testPostID(4), testPostID(1), testPostID(3)}, wantTotal: 4,
+		},
+		{
+			name:        "top of the week",
+			query:       models.CommunityFeedQuery{Sort: "top", Period: "week", Page: 1, Limit: 4},
+			wantQueries: window,
+			wantPosts:   []string{testPostID(2), testPostID(1), testPostID(3)}, wantTotal: 3,
+		},
+		{
+			name:        "top of all time",
+			query:       models.CommunityFeedQuery{Sort: "top", Period: "all", Page: 1, Limit: 4},
+			wantQueries: window,
+			wantPosts:   []string{testPostID(4), testPostID(2), testPostID(1), testPostID(3)}, wantTotal: 4,
+		},
+		{
+			name:        "pins lead the first page within the limit",
+			query:       models.CommunityFeedQuery{Sort: "hot", Page: 1, Limit: 3},
+			pins:        []int{3, 2},
+			wantQueries: window,
+			wantPosts:   []string{testPostID(3), testPostID(2), testPostID(1)}, wantPinned: 2, wantTotal: 4,
+		},
+		{
+			name:        "pinned posts are not ranked again",
+			query:       models.CommunityFeedQuery{Sort: "hot", Page: 2, Limit: 3},
+			pins:        []int{3, 2},
+			wantQueries: window,
+			wantPosts:   []string{testPostID(4)}, wantTotal: 4,
+		},
+		{
+			name:        "pins span pages",
+			query:       models.CommunityFeedQuery{Sort: "hot", Page: 2, Limit: 1},
+			pins:        []int{3, 2},
+			wantQueries: window,
+			wantPosts:   []string{testPostID(2)}, wantPinned: 1, wantTotal: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			upstream := http.NewServeMux()
+			upstream.Handle("/posts", ps)
+			upstream.HandleFunc("/posts/", func(w http.ResponseWriter, r *http.Request) {
+				for _, post := range ps.posts {
+					if post.ID == strings.TrimPrefix(r.URL.Path, "/posts/") {
+						writeJSON(w, post)
+						return
+					}
+				}
+				http.NotFound(w, r)
+			})
+			fc := testController(mt, upstream, nil)
+			var pinDocs []bson.D
+			for _, n := range tt.pins {
+				pinDocs = append(pinDocs, bson.D{{Key: "community_id", Value: 10}, {Key: "post_id", Value: testPostID(n)}})
+			}
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, "feed.pins", mtest.FirstBatch, pinDocs...))
+			ps.queries = nil
+
+			feed, err := fc.buildCommunityFeed(context.Background(), 10, tt.query)
+			if err != nil {
+				mt.Fatal(err)
+			}
+
+			var queries []string
+			for _, q := range ps.queries {
+				queries = append(queries, q.Encode())
+			}
+			sort.Strings(queries)
+			if !reflect.DeepEqual(queries, tt.wantQueries) {
+				mt.Errorf("queries = %q, want %q", queries, tt.wantQueries)
+			}
+			if got := postIDs(feed.Items); !reflect.DeepEqual(got, tt.wantPosts) {
+				mt.Errorf("posts = %v, want %v", got, tt.wantPosts)
+			}
+			for i, item := range feed.Items {
+				if item.Pinned != (i < tt.wantPinned) {
+					mt.Errorf("post %d pinned = %v, want the first %d pinned", i, item.Pinned, tt.wantPinned)
+				}
+			}
+			if feed.Total != tt.wantTotal {
+				mt.Errorf("total = %d, want %d", feed.Total, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestPinPost(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	upstream := http.NewServeMux()
+	upstream.HandleFunc("/posts/", func(w http.ResponseWriter, r *http.Request) {
+		switch id := strings.TrimPrefix(r.URL.Path, "/posts/"); id {
+		case testPostID(1):
+			writeJSON(w, upstreamPost{ID: id, CommunityID: 10})
+		case testPostID(2):
+			writeJSON(w, upstreamPost{ID: id, CommunityID: 20})
+		case testPostID(3):
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	matched := func(n int) bson.D { return mtest.CreateSuccessResponse(bson.E{Key: "n", Value: n}) }
+	taken := mtest.CreateWriteErrorsResponse(mtest.WriteError{Code: 11000, Message: "duplicate key"})
+
+	tests := []struct {
+		name       string
+		postID     string
+		responses  []bson.D // to the pin's writes
+		wantStatus int
+		wantPins   bool // whether the pins are written to
+	}{
+		{name: "pins a post", postID: testPostID(1), responses: []bson.D{matched(0), matched(1)}, wantStatus: http.StatusOK, wantPins: true},
+		{name: "repins a pinned post at the limit", postID: testPostID(1), responses: []bson.D{matched(1)}, wantStatus: http.StatusOK, wantPins: true},
+		{name: "too many pins", postID: testPostID(1), responses: []bson.D{matched(0), taken, taken, taken, matched(0)}, wantStatus: http.StatusConflict, wantPins: true},
+		{name: "invalid post ID", postID: "nope", wantStatus: http.StatusBadRequest},
+		{name: "unknown post", postID: testPostID(9), wantStatus: http.StatusNotFound},
+		{name: "post in another community", postID: testPostID(2), wantStatus: http.StatusNotFound},
+		{name: "post service failure", postID: testPostID(3), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			fc := testController(mt, upstream, nil)
+			mt.AddMockResponses(tt.responses...)
+			mt.ClearEvents()
+
+			router := gin.New()
+			router.PUT("/communities/:id/pins/:postId", fc.PinPost)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/communities/10/pins/"+tt.postID, nil))
+
+			if w.Code != tt.wantStatus {
+				mt.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			wrote := false
+			for _, event := range mt.GetAllStartedEvents() {
+				if event.CommandName == "update" || event.CommandName == "insert" {
+					wrote = true
+				}
+			}
+			if wrote != tt.wantPins {
+				mt.Errorf("wrote pins = %v, want %v", wrote, tt.wantPins)
+			}
+		})
+	}
+}
+
+func TestUnpinPost(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	tests := []struct {
+		name       string
+		postID     string
+		deleted    int
+		wantStatus int
+	}{
+		{name: "unpins a post", postID: testPostID(1), deleted: 1, wantStatus: http.StatusNoContent},
+		{name: "post is not pinned", postID: testPostID(1), wantStatus: http.StatusNotFound},
+		{name: "invalid post ID", postID: "nope", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			fc := testController(mt, http.NotFoundHandler(), nil)
+			mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: tt.deleted}))
+
+			router := gin.New()
+			router.DELETE("/communities/:id/pins/:postId", fc.UnpinPost)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/communities/10/pins/"+tt.postID, nil))
+
+			if w.Code != tt.wantStatus {
+				mt.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package controllers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/interactions"
+	"github.com/CircleConnectApp/feed-service/memberships"
+	"github.com/CircleConnectApp/feed-service/models"
+	"github.com/CircleConnectApp/feed-service/settings"
+	"github.com/CircleConnectApp/feed-service/tracing"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// defaultCommunitySuggestions is the number of communities suggested when
+	// the request does not ask for a number
+	defaultCommunitySuggestions = 10
+	// tagOverlapPosts is the number of popular posts with the user's preferred
+	// tags whose communities are considered
+	tagOverlapPosts = 100
+	// suggestionCandidates is how many communities each signal proposes per
+	// suggestion requested, leaving room for private and unknown ones
+	suggestionCandidates = 3
+)
+
+// communitySignals are the raw signals suggesting one community
+type communitySignals struct {
+	tagPosts     int
+	tags         []string
+	members      int
+	overlap      int
+	engagedPosts int
+}
+
+// GetRecommendedCommunities suggests communities the user has not joined
+func (fc *FeedController) GetRecommendedCommunities(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var query models.CommunitySuggestionQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	suggestions, err := fc.SuggestCommunities(c.Request.Context(), userID.(int), query.Limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"communities": suggestions})
+}
+
+// SuggestCommunities ranks communities the user has not joined by how well
+// they match the user's preferred tags, what users sharing the user's
+// communities joined, and which communities' posts the user engaged with.
+// Private communities are never suggested.
+func (fc *FeedController) SuggestCommunities(ctx context.Context, userID, limit int) (suggestions []models.CommunitySuggestion, err error) {
+	ctx, cancel := fc.withBudget(ctx)
+	defer cancel()
+
+	if limit == 0 {
+		limit = defaultCommunitySuggestions
+	}
+
+	in, err := fc.loadInputs(ctx, userID, inputs{communities: true})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, "suggestCommunities")
+	defer func() {
+		span.SetAttributes(attribute.Int("feed.suggestions", len(suggestions)))
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	rules := fc.settings.Current().Communities
+	signals, err := fc.communitySignals(ctx, userID, in, rules, limit*suggestionCandidates)
+	if err != nil {
+		return nil, stageError(err, "Failed to suggest communities")
+	}
+	ranked := rankSuggestions(signals, rules)
+	if len(ranked) > limit*suggestionCandidates {
+		ranked = ranked[:limit*suggestionCandidates]
+	}
+
+	// Fill in names and icons, dropping communities that are private or no
+	// longer exist
+	ids := make([]int, len(ranked))
+	for i, suggestion := range ranked {
+		ids[i] = suggestion.CommunityID
+	}
+	communities, err := fc.communities.load(ctx, ids, fc.getCommunities)
+	if err != nil {
+		return nil, stageError(err, "Failed to get communities")
+	}
+
+	suggestions = []models.CommunitySuggestion{}
+	for _, suggestion := range ranked {
+		community, ok := communities[suggestion.CommunityID]
+		if !ok || community.IsPrivate {
+			continue
+		}
+		suggestion.Name = community.Name
+		suggestion.Icon = community.Icon
+		suggestions = append(suggestions, suggestion)
+		if len(suggestions) == limit {
+			break
+		}
+	}
+	return suggestions, nil
+}
+
+// communitySignals gathers the enabled signals concurrently, each proposing
+// up to perSignal communities the user has not joined. A failing signal is
+// skipped; an error is returned only if every enabled signal fails.
+func (fc *FeedController) communitySignals(ctx context.Context, userID int, in *feedInputs, rules settings.CommunitySuggestions, perSignal int) (map[int]*communitySignals, error) {
+	joined := make(map[int]bool, len(in.communities))
+	for _, communityID := range in.communities {
+		joined[communityID] = true
+	}
+
+	var (
+		tagPosts      []upstreamPost
+		coMemberships []memberships.CoMembership
+		engaged       []interactions.Engagement
+
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		enabled  int
+		failures []error
+	)
+	gather := func(signal string, fetch func() error) {
+		enabled++
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := fetch(); err != nil {
+				slog.WarnContext(ctx, "Failed to gather community suggestion signal", "signal", signal, "user_id", userID, "error", err)
+				mu.Lock()
+				failures = append(failures, err)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	var preferredTags map[string]bool
+	if rules.TagOverlap > 0 && in.pref != nil && len(in.pref.PreferedTags) > 0 {
+		preferredTags = make(map[string]bool, len(in.pref.PreferedTags))
+		for _, tag := range in.pref.PreferedTags {
+			preferredTags[tag] = true
+		}
+		gather(models.ReasonTagOverlap, func() (err error) {
+			tagPosts, _, err = fc.listPosts(ctx, "community_tag_posts", url.Values{
+				"sort":  {"popular"},
+				"tags":  {strings.Join(in.pref.PreferedTags, ",")},
+				"limit": {strconv.Itoa(tagOverlapPosts)},
+			})
+			return err
+		})
+	}
+	if rules.CoMembership > 0 && len(in.communities) > 0 {
+		gather(models.ReasonCoMembership, func() (err error) {
+			coMemberships, err = fc.memberships.CoMemberships(ctx, userID, in.communities, rules.MaxSimilarUsers, perSignal)
+			return err
+		})
+	}
+	if rules.Engagement > 0 {
+		gather(models.ReasonEngagement, func() (err error) {
+			// Joined communities are dropped afterwards, so ask for enough
+			// to fill the signal without them
+			since := time.Now().AddDate(0, 0, -rules.LookbackDays)
+			engaged, err = interactions.EngagedCommunities(ctx, fc.pgDB, userID, since, perSignal+len(joined))
+			return err
+		})
+	}
+
+	wg.Wait()
+	if enabled > 0 && len(failures) == enabled {
+		return nil, failures[0]
+	}
+
+	signals := make(map[int]*communitySignals)
+	get := func(communityID int) *communitySignals {
+		s, ok := signals[communityID]
+		if !ok {
+			s = &communitySignals{}
+			signals[communityID] = s
+		}
+		return s
+	}
+
+	// Tag overlap counts the sampled posts in each community and the
+	// preferred tags they carry
+	proposed := make(map[int]bool)
+	for _, post := range tagPosts {
+		if post.CommunityID <= 0 || joined[post.CommunityID] || !proposed[post.CommunityID] && len(proposed) == perSignal {
+			continue
+		}
+		proposed[post.CommunityID] = true
+		s := get(post.CommunityID)
+		s.tagPosts++
+		for _, tag := range post.Tags {
+			if preferredTags[tag] && !containsTag(s.tags, tag) {
+				s.tags = append(s.tags, tag)
+			}
+		}
+	}
+	for _, m := range coMemberships {
+		s := get(m.CommunityID)
+		s.members = m.Members
+		s.overlap = m.Overlap
+	}
+	proposedEngaged := 0
+	for _, e := range engaged {
+		if joined[e.ID] || proposedEngaged == perSignal {
+			continue
+		}
+		proposedEngaged++
+		get(e.ID).engagedPosts = e.Posts
+	}
+	return signals, nil
+}
+
+// rankSuggestions scores communities by their weighted signals, each scaled
+// to the strongest community's, and orders them best first. Scores are
+// between 0 and 1.
+func rankSuggestions(signals map[int]*communitySignals, rules settings.CommunitySuggestions) []models.CommunitySuggestion {
+	var maxTagPosts, maxOverlap, maxEngaged int
+	for _, s := range signals {
+		maxTagPosts = max(maxTagPosts, s.tagPosts)
+		maxOverlap = max(maxOverlap, s.overlap)
+		maxEngaged = max(maxEngaged, s.engagedPosts)
+	}
+	totalWeight := rules.TagOverlap + rules.CoMembership + rules.Engagement
+
+	ranked := make([]models.CommunitySuggestion, 0, len(signals))
+	for communityID, s := range signals {
+		suggestion := models.CommunitySuggestion{CommunityID: communityID, Reasons: []models.SuggestionReason{}}
+		var score float64
+		if s.tagPosts > 0 {
+			score += rules.TagOverlap * float64(s.tagPosts) / float64(maxTagPosts)
+			suggestion.Reasons = append(suggestion.Reasons, models.SuggestionReason{Type: models.ReasonTagOverlap, Tags: s.tags, Posts: s.tagPosts})
+		}
+		if s.overlap > 0 {
+			score += rules.CoMembership * float64(s.overlap) / float64(maxOverlap)
+			suggestion.Reasons = append(suggestion.Reasons, models.SuggestionReason{Type: models.ReasonCoMembership, Members: s.members})
+		}
+		if s.engagedPosts > 0 {
+			score += rules.Engagement * float64(s.engagedPosts) / float64(maxEngaged)
+			suggestion.Reasons = append(suggestion.Reasons, models.SuggestionReason{Type: models.ReasonEngagement, Posts: s.engagedPosts})
+		}
+		suggestion.Score = score / totalWeight
+		ranked = append(ranked, suggestion)
+	}
+
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].CommunityID < ranked[j].CommunityID
+	})
+	return ranked
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
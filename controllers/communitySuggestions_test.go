@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/CircleConnectApp/feed-service/models"
+	"github.com/CircleConnectApp/feed-service/settings"
+)
+
+func TestRankSuggestions(t *testing.T) {
+	rules := settings.CommunitySuggestions{TagOverlap: 1, CoMembership: 2, Engagement: 1}
+
+	tests := []struct {
+		name    string
+		signals map[int]*communitySignals
+		want    []models.CommunitySuggestion
+	}{
+		{name: "no signals", signals: map[int]*communitySignals{}, want: []models.CommunitySuggestion{}},
+		{
+			name: "weighted signals scaled to the strongest",
+			signals: map[int]*communitySignals{
+				10: {tagPosts: 4, tags: []string{"go"}},
+				20: {tagPosts: 2, tags: []string{"rust"}, members: 3, overlap: 6},
+				30: {engagedPosts: 5},
+				40: {members: 1, overlap: 3},
+			},
+			want: []models.CommunitySuggestion{
+				{CommunityID: 20, Score: 0.625, Reasons: []models.SuggestionReason{
+					{Type: models.ReasonTagOverlap, Tags: []string{"rust"}, Posts: 2},
+					{Type: models.ReasonCoMembership, Members: 3},
+				}},
+				// Ties are broken by community ID
+				{CommunityID: 10, Score: 0.25, Reasons: []models.SuggestionReason{{Type: models.ReasonTagOverlap, Tags: []string{"go"}, Posts: 4}}},
+				{CommunityID: 30, Score: 0.25, Reasons: []models.SuggestionReason{{Type: models.ReasonEngagement, Posts: 5}}},
+				{CommunityID: 40, Score: 0.25, Reasons: []models.SuggestionReason{{Type: models.ReasonCoMembership, Members: 1}}},
+			},
+		},
+		{
+			// A user without joined communities or engagement is suggested
+			// communities from tag overlap alone
+			name: "cold start",
+			signals: map[int]*communitySignals{
+				11: {tagPosts: 1, tags: []string{"go"}},
+				10: {tagPosts: 2, tags: []string{"go", "rust"}},
+			},
+			want: []models.CommunitySuggestion{
+				{CommunityID: 10, Score: 0.25, Reasons: []models.SuggestionReason{{Type: models.ReasonTagOverlap, Tags: []string{"go", "rust"}, Posts: 2}}},
+				{CommunityID: 11, Score: 0.125, Reasons: []models.SuggestionReason{{Type: models.ReasonTagOverlap, Tags: []string{"go"}, Posts: 1}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rankSuggestions(tt.signals, rules); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rankSuggestions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
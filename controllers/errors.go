@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Error is a request failure with the HTTP status and message reported to the
+// client. The REST handlers and the gRPC server share the controller logic and
+// translate it into their own responses.
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func fail(status int, message string) *Error {
+	return &Error{Status: status, Message: message}
+}
+
+// respondError writes err as a JSON error response
+func respondError(c *gin.Context, err error) {
+	var reqErr *Error
+	if errors.As(err, &reqErr) {
+		c.JSON(reqErr.Status, gin.H{"error": reqErr.Message})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+}
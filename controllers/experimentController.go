@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/CircleConnectApp/feed-service/interactions"
+	"github.com/CircleConnectApp/feed-service/settings"
+	"github.com/gin-gonic/gin"
+)
+
+type ExperimentController struct {
+	pgDB     *sql.DB
+	settings *settings.Store
+}
+
+// NewExperimentController creates a new instance of ExperimentController
+func NewExperimentController(pgDB *sql.DB, settingsStore *settings.Store) *ExperimentController {
+	return &ExperimentController{pgDB: pgDB, settings: settingsStore}
+}
+
+// GetExperiments returns the configured experiments with exposure counts per variant
+func (ec *ExperimentController) GetExperiments(c *gin.Context) {
+	summary, err := interactions.ExposureSummary(c.Request.Context(), ec.pgDB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get experiment exposures"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"experiments": ec.settings.Current().Experiments,
+		"exposures":   summary,
+	})
+}
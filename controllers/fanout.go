@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/models"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/sync/errgroup"
+)
+
+// lookupShare is the share of a feed request's time budget given to the
+// lookups that precede building the feed; building gets the rest
+const lookupShare = 0.4
+
+// feedInputs are the per-user lookups a feed is built from
+type feedInputs struct {
+	pref        *models.UserPreference
+	communities []int
+	userInfo    map[string]interface{}
+}
+
+// inputs selects the lookups loadInputs performs
+type inputs struct {
+	communities bool
+	// optionalCommunities builds the feed without the joined communities if
+	// they cannot be loaded, rather than failing it
+	optionalCommunities bool
+	userInfo            bool
+}
+
+// withBudget bounds a feed request by the configured request timeout
+func (fc *FeedController) withBudget(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, fc.config.RequestTimeout)
+}
+
+// stage returns a context for a stage given share of the time left before
+// ctx's deadline
+func stage(ctx context.Context, share float64) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(float64(time.Until(deadline))*share))
+}
+
+// loadInputs fetches a user's preferences and the selected lookups
+// concurrently within the lookup stage's budget. Preferences and, unless
+// optional, joined communities are required: the first of them to fail
+// cancels the others. User info is optional, and the feed is built without
+// it if it fails or runs out of time.
+func (fc *FeedController) loadInputs(ctx context.Context, userID int, want inputs) (*feedInputs, error) {
+	ctx, cancel := stage(ctx, lookupShare)
+	defer cancel()
+
+	var in feedInputs
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		pref, err := fc.getUserPreferences(gctx, userID)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return stageError(err, "Failed to get user preferences")
+		}
+		in.pref = pref
+		return nil
+	})
+	if want.communities {
+		g.Go(func() error {
+			communities, err := fc.getJoinedCommunities(gctx, userID)
+			if err != nil && want.optionalCommunities {
+				slog.WarnContext(ctx, "Failed to get joined communities", "user_id", userID, "error", err)
+				// Continue without joined communities
+				return nil
+			}
+			if err != nil {
+				return stageError(err, "Failed to get joined communities")
+			}
+			in.communities = communities
+			return nil
+		})
+	}
+	if want.userInfo {
+		g.Go(func() error {
+			userInfo, err := fc.getUserInfo(gctx, userID)
+			if err != nil {
+				slog.WarnContext(ctx, "Failed to get user info", "user_id", userID, "error", err)
+				// Continue without user info
+				return nil
+			}
+			in.userInfo = userInfo
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return &in, nil
+}
+
+// stageError reports a failed stage, as a timeout when it ran out of time
+func stageError(err error, message string) *Error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fail(http.StatusGatewayTimeout, "Feed request timed out")
+	}
+	return fail(http.StatusInternalServerError, message)
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestStage(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	staged, cancelStage := stage(ctx, 0.4)
+	defer cancelStage()
+	deadline, ok := staged.Deadline()
+	if !ok {
+		t.Fatal("stage has no deadline")
+	}
+	if left := time.Until(deadline); left > 400*time.Millisecond || left < 300*time.Millisecond {
+		t.Errorf("stage has %v left, want 40%% of a second", left)
+	}
+
+	unbounded, cancelUnbounded := stage(context.Background(), 0.4)
+	defer cancelUnbounded()
+	if _, ok := unbounded.Deadline(); ok {
+		t.Error("stage of a context without a deadline has one")
+	}
+}
+
+func TestStageError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{name: "timeout", err: context.DeadlineExceeded, wantStatus: http.StatusGatewayTimeout},
+		{name: "wrapped timeout", err: errors.Join(errors.New("get"), context.DeadlineExceeded), wantStatus: http.StatusGatewayTimeout},
+		{name: "failure", err: errors.New("status 503"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stageError(tt.err, "Failed"); got.Status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", got.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestLoadInputs(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	prefDoc := mtest.CreateCursorResponse(0, "feed.preferences", mtest.FirstBatch,
+		bson.D{{Key: "user_id", Value: 1}, {Key: "prefered_tags", Value: bson.A{"go"}}})
+	noPrefs := mtest.CreateCursorResponse(0, "feed.preferences", mtest.FirstBatch)
+	prefsDown := mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "unavailable"})
+
+	ok := func(w http.ResponseWriter, r *http.Request, body interface{}) { writeJSON(w, body) }
+	down := func(w http.ResponseWriter, r *http.Request, _ interface{}) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}
+	hang := func(w http.ResponseWriter, r *http.Request, _ interface{}) { <-r.Context().Done() }
+
+	tests := []struct {
+		name            string
+		want            inputs
+		prefs           bson.D
+		communities     func(http.ResponseWriter, *http.Request, interface{})
+		userInfo        func(http.ResponseWriter, *http.Request, interface{})
+		wantStatus      int
+		wantPref        bool
+		wantCommunities []int
+		wantUserInfo    bool
+	}{
+		{name: "everything loads", want: inputs{communities: true, userInfo: true}, prefs: prefDoc, communities: ok, userInfo: ok, wantPref: true, wantCommunities: []int{3, 4}, wantUserInfo: true},
+		{name: "no preferences saved", want: inputs{communities: true}, prefs: noPrefs, communities: ok, wantCommunities: []int{3, 4}},
+		{name: "preferences fail", want: inputs{communities: true}, prefs: prefsDown, communities: ok, wantStatus: http.StatusInternalServerError},
+		{name: "communities fail", want: inputs{communities: true}, prefs: prefDoc, communities: down, wantStatus: http.StatusInternalServerError},
+		{name: "optional communities fail", want: inputs{communities: true, optionalCommunities: true}, prefs: prefDoc, communities: down, wantPref: true},
+		{name: "communities time out", want: inputs{communities: true}, prefs: prefDoc, communities: hang, wantStatus: http.StatusGatewayTimeout},
+		{name: "user info fails", want: inputs{userInfo: true}, prefs: prefDoc, userInfo: down, wantPref: true},
+		// User info only adjusts ranking, so the feed goes ahead without it
+		{name: "user info times out", want: inputs{userInfo: true}, prefs: prefDoc, userInfo: hang, wantPref: true},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			upstream := http.NewServeMux()
+			if tt.communities != nil {
+				upstream.HandleFunc("/user/1/communities", func(w http.ResponseWriter, r *http.Request) {
+					tt.communities(w, r, map[string]interface{}{"communities": []map[string]int{{"id": 3}, {"id": 4}}})
+				})
+			}
+			if tt.userInfo != nil {
+				upstream.HandleFunc("/users/1", func(w http.ResponseWriter, r *http.Request) {
+					tt.userInfo(w, r, map[string]interface{}{"age": 30})
+				})
+			}
+			fc := testController(mt, upstream, nil)
+			mt.AddMockResponses(tt.prefs)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+			defer cancel()
+			in, err := fc.loadInputs(ctx, 1, tt.want)
+
+			if tt.wantStatus != 0 {
+				var feedErr *Error
+				if !errors.As(err, &feedErr) || feedErr.Status != tt.wantStatus {
+					mt.Fatalf("err = %v, want status %d", err, tt.wantStatus)
+				}
+				return
+			}
+			if err != nil {
+				mt.Fatal(err)
+			}
+			if (in.pref != nil) != tt.wantPref {
+				mt.Errorf("preferences = %+v, want loaded %v", in.pref, tt.wantPref)
+			}
+			if len(in.communities) != len(tt.wantCommunities) {
+				mt.Errorf("communities = %v, want %v", in.communities, tt.wantCommunities)
+			}
+			if (in.userInfo != nil) != tt.wantUserInfo {
+				mt.Errorf("user info = %v, want loaded %v", in.userInfo, tt.wantUserInfo)
+			}
+		})
+	}
+}
@@ -5,43 +5,136 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/CircleConnectApp/feed-service/candidates"
 	"github.com/CircleConnectApp/feed-service/database"
+	"github.com/CircleConnectApp/feed-service/diversity"
+	"github.com/CircleConnectApp/feed-service/experiments"
+	"github.com/CircleConnectApp/feed-service/interactions"
+	"github.com/CircleConnectApp/feed-service/logger"
+	"github.com/CircleConnectApp/feed-service/memberships"
+	"github.com/CircleConnectApp/feed-service/metrics"
 	"github.com/CircleConnectApp/feed-service/models"
+	"github.com/CircleConnectApp/feed-service/pins"
+	"github.com/CircleConnectApp/feed-service/ranking"
+	"github.com/CircleConnectApp/feed-service/seen"
+	"github.com/CircleConnectApp/feed-service/settings"
+	"github.com/CircleConnectApp/feed-service/tracing"
+	"github.com/CircleConnectApp/feed-service/trending"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
 )
 
+// upstreamPost is a post as returned by the post service
+type upstreamPost struct {
+	ID          string    `json:"id"`
+	UserID      int       `json:"user_id"`
+	CommunityID int       `json:"community_id"`
+	Title       string    `json:"title"`
+	Content     string    `json:"content"`
+	MediaURLs   []string  `json:"media_urls"`
+	Tags        []string  `json:"tags"`
+	CreatedAt   time.Time `json:"created_at"`
+	LikeCount   int       `json:"like_count"`
+	UserName    string    `json:"user_name"`
+	UserPic     string    `json:"user_pic"`
+}
+
+// toFeedItem converts a post to a feed item; ranking fields are left to the caller
+func (p upstreamPost) toFeedItem(postID primitive.ObjectID) models.FeedItem {
+	return models.FeedItem{
+		ID:           primitive.NewObjectID(),
+		PostID:       postID,
+		UserID:       p.UserID,
+		CommunityID:  p.CommunityID,
+		Title:        p.Title,
+		Content:      p.Content,
+		LikeCount:    p.LikeCount,
+		MediaURLs:    p.MediaURLs,
+		Tags:         p.Tags,
+		CreatedAt:    p.CreatedAt,
+		AuthorName:   p.UserName,
+		AuthorAvatar: p.UserPic,
+	}
+}
+
+// postsResponse is a page of posts from the post service
+type postsResponse struct {
+	Posts []upstreamPost `json:"posts"`
+	Total int            `json:"total"`
+}
+
 type FeedController struct {
-	mongoDB *mongo.Database
-	pgDB    *sql.DB
-	config  struct {
+	mongoDB    *mongo.Database
+	pgDB       *sql.DB
+	httpClient *http.Client
+	settings   *settings.Store
+	recorder   *interactions.Recorder
+	seen       *seen.Store
+	// seenRecorder counts the recommendations served to each user
+	seenRecorder *seen.Recorder
+	// memberships snapshots joined communities for community suggestions,
+	// written through membershipRecorder
+	memberships        *memberships.Store
+	membershipRecorder *memberships.Recorder
+	// trending ranks the posts the home feed mixes in as trending
+	trending *trending.Store
+	// pins holds the posts communities pin to the top of their feeds
+	pins *pins.Store
+	// authors and communities cache details for hydrating feed items
+	authors     *batchCache[author]
+	communities *batchCache[community]
+	config      struct {
 		UserServiceURL      string
 		PostServiceURL      string
 		CommunityServiceURL string
+		WebAppURL           string
+		RequestTimeout      time.Duration
 	}
 }
 
 // NewFeedController creates a new instance of FeedController
-func NewFeedController(mongoDB *mongo.Database, pgDB *sql.DB, userServiceURL, postServiceURL, communityServiceURL string) *FeedController {
+func NewFeedController(mongoDB *mongo.Database, pgDB *sql.DB, userServiceURL, postServiceURL, communityServiceURL, webAppURL string, requestTimeout time.Duration, settingsStore *settings.Store, recorder *interactions.Recorder, seenStore *seen.Store, seenRecorder *seen.Recorder, membershipStore *memberships.Store, membershipRecorder *memberships.Recorder, trendingStore *trending.Store, pinStore *pins.Store) *FeedController {
 	return &FeedController{
-		mongoDB: mongoDB,
-		pgDB:    pgDB,
+		mongoDB:            mongoDB,
+		pgDB:               pgDB,
+		settings:           settingsStore,
+		recorder:           recorder,
+		seen:               seenStore,
+		seenRecorder:       seenRecorder,
+		memberships:        membershipStore,
+		membershipRecorder: membershipRecorder,
+		trending:           trendingStore,
+		pins:               pinStore,
+		authors:            newBatchCache[author]("authors"),
+		communities:        newBatchCache[community]("communities"),
+		// The instrumented transport creates client spans and injects traceparent headers
+		httpClient: &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)},
 		config: struct {
 			UserServiceURL      string
 			PostServiceURL      string
 			CommunityServiceURL string
+			WebAppURL           string
+			RequestTimeout      time.Duration
 		}{
 			UserServiceURL:      userServiceURL,
 			PostServiceURL:      postServiceURL,
 			CommunityServiceURL: communityServiceURL,
+			WebAppURL:           strings.TrimSuffix(webAppURL, "/"),
+			RequestTimeout:      requestTimeout,
 		},
 	}
 }
@@ -59,47 +152,46 @@ func (fc *FeedController) GetFeed(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	// Set default values
-	if query.Page <= 0 {
-		query.Page = 1
-	}
-	if query.Limit <= 0 {
-		query.Limit = 20
+	view, ok := bindView(c)
+	if !ok {
+		return
 	}
 
-	// Get user preferences
-	pref, err := fc.getUserPreferences(userID.(int))
-	if err != nil && err != mongo.ErrNoDocuments {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user preferences"})
+	feed, err := fc.PersonalFeed(c.Request.Context(), userID.(int), query)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
+	fc.recordImpressions(c.Request.Context(), userID.(int), "personal", feed)
 
-	// If no sort method specified, use the one from user preferences
-	if query.SortBy == "" && pref != nil {
-		query.SortBy = pref.FeedSortMethod
-	}
+	respondFeed(c, feed, view)
+}
 
-	// Default to date sorting if no preference is set
-	if query.SortBy == "" {
-		query.SortBy = "date"
-	}
+// PersonalFeed builds the personalized feed for a user
+func (fc *FeedController) PersonalFeed(ctx context.Context, userID int, query models.FeedQuery) (*models.Feed, error) {
+	ctx, cancel := fc.withBudget(ctx)
+	defer cancel()
+
+	// Set default values
+	fc.applyPageLimits(&query.Page, &query.Limit)
 
-	// Get joined communities for the user
-	joinedCommunities, err := fc.getJoinedCommunities(userID.(int))
+	// Get user preferences and joined communities
+	in, err := fc.loadInputs(ctx, userID, inputs{communities: true})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get joined communities"})
-		return
+		return nil, err
 	}
 
+	// If no sort method specified, use the one from user preferences
+	applySortPreference(&query, in.pref)
+
 	// Build the feed items based on user preferences and joined communities
-	feed, err := fc.buildFeed(userID.(int), joinedCommunities, pref, query)
+	feed, err := fc.buildFeed(ctx, userID, in.communities, in.pref, query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build feed"})
-		return
+		return nil, stageError(err, "Failed to build feed")
 	}
 
-	c.JSON(http.StatusOK, feed)
+	fc.hydrate(ctx, feed.Items)
+	return feed, nil
 }
 
 // GetRecommendedPosts retrieves recommended posts for the user
@@ -115,40 +207,250 @@ func (fc *FeedController) GetRecommendedPosts(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	view, ok := bindView(c)
+	if !ok {
+		return
+	}
+
+	feed, err := fc.RecommendedFeed(c.Request.Context(), userID.(int), query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	fc.recordImpressions(c.Request.Context(), userID.(int), "recommended", feed)
+
+	respondFeed(c, feed, view)
+}
+
+// RecommendedFeed builds a user's recommended posts
+func (fc *FeedController) RecommendedFeed(ctx context.Context, userID int, query models.FeedQuery) (*models.Feed, error) {
+	ctx, cancel := fc.withBudget(ctx)
+	defer cancel()
 
 	// Set default values
-	if query.Page <= 0 {
-		query.Page = 1
+	fc.applyPageLimits(&query.Page, &query.Limit)
+
+	// Get user preferences and demographics from the profile service, and
+	// the joined communities when recommendations leave them out. Leaving
+	// them out is best effort, so recommendations are built without it if
+	// they cannot be loaded.
+	excludeJoined := fc.settings.Current().Enabled("exclude_joined_communities")
+	in, err := fc.loadInputs(ctx, userID, inputs{communities: excludeJoined, optionalCommunities: true, userInfo: true})
+	if err != nil {
+		return nil, err
 	}
-	if query.Limit <= 0 {
-		query.Limit = 20
+
+	// Default to relevance for recommendations
+	query.SortBy = "relevance"
+
+	// Build recommended feed based on user preferences, demographics, and post popularity
+	feed, err := fc.buildRecommendedFeed(ctx, userID, in.pref, in.userInfo, in.communities, query)
+	if err != nil {
+		return nil, stageError(err, "Failed to build recommended feed")
 	}
 
-	// Get user preferences and demographic info
-	pref, err := fc.getUserPreferences(userID.(int))
-	if err != nil && err != mongo.ErrNoDocuments {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user preferences"})
+	fc.hydrate(ctx, feed.Items)
+	return feed, nil
+}
+
+// GetHomeFeed returns the home feed, interleaving posts from the user's
+// communities with recommendations and labelling each item's source
+func (fc *FeedController) GetHomeFeed(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
-	// Default to relevance for recommendations
-	query.SortBy = "relevance"
+	var query models.FeedQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	view, ok := bindView(c)
+	if !ok {
+		return
+	}
 
-	// Get user's demographics from profile service
-	userInfo, err := fc.getUserInfo(userID.(int))
+	feed, err := fc.HomeFeed(c.Request.Context(), userID.(int), query)
 	if err != nil {
-		log.Printf("Warning: Failed to get user info: %v", err)
-		// Continue without user info
+		respondError(c, err)
+		return
 	}
+	fc.recordImpressions(c.Request.Context(), userID.(int), "home", feed)
 
-	// Build recommended feed based on user preferences, demographics, and post popularity
-	feed, err := fc.buildRecommendedFeed(userID.(int), pref, userInfo, query)
+	respondFeed(c, feed, view)
+}
+
+// HomeFeed builds a user's home feed
+func (fc *FeedController) HomeFeed(ctx context.Context, userID int, query models.FeedQuery) (*models.Feed, error) {
+	ctx, cancel := fc.withBudget(ctx)
+	defer cancel()
+
+	// Set default values
+	fc.applyPageLimits(&query.Page, &query.Limit)
+
+	in, err := fc.loadInputs(ctx, userID, inputs{communities: true, userInfo: true})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build recommended feed"})
+		return nil, err
+	}
+
+	// Each source is paged separately with its share of the page, so pages
+	// of the home feed line up with pages of the sources. The sources are
+	// built concurrently.
+	snap := fc.settings.Current()
+	rules := snap.Home
+	recommendedSlots, trendingSlots := homeSlots(query.Limit, rules)
+
+	var joined, recommended, trendingPosts *models.Feed
+	g, gctx := errgroup.WithContext(ctx)
+	if joinedSlots := query.Limit - recommendedSlots - trendingSlots; joinedSlots > 0 {
+		g.Go(func() error {
+			joinedQuery := query
+			joinedQuery.Limit = joinedSlots
+			applySortPreference(&joinedQuery, in.pref)
+			var err error
+			joined, err = fc.buildFeed(gctx, userID, in.communities, in.pref, joinedQuery)
+			if err != nil {
+				return stageError(err, "Failed to build feed")
+			}
+			return nil
+		})
+	}
+	if recommendedSlots > 0 {
+		g.Go(func() error {
+			recommendedQuery := query
+			recommendedQuery.Limit = recommendedSlots
+			recommendedQuery.SortBy = "relevance"
+			var err error
+			recommended, err = fc.buildRecommendedFeed(gctx, userID, in.pref, in.userInfo, in.communities, recommendedQuery)
+			if err != nil {
+				// The home feed degrades to the other sources
+				slog.WarnContext(ctx, "Failed to build recommendations for home feed", "user_id", userID, "error", err)
+				recommended = nil
+			}
+			return nil
+		})
+	}
+	if trendingSlots > 0 {
+		g.Go(func() error {
+			trendingQuery := query
+			trendingQuery.Limit = trendingSlots
+			var err error
+			trendingPosts, err = fc.buildTrendingFeed(gctx, userID, in.communities, trendingQuery)
+			if err != nil {
+				// The home feed degrades to the other sources
+				slog.WarnContext(ctx, "Failed to build trending posts for home feed", "user_id", userID, "error", err)
+				trendingPosts = nil
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	feed := &models.Feed{Page: query.Page, Limit: query.Limit}
+	var joinedItems, recommendedItems, trendingItems []models.FeedItem
+	for _, source := range []struct {
+		feed  *models.Feed
+		name  string
+		items *[]models.FeedItem
+	}{
+		{joined, models.SourceJoined, &joinedItems},
+		{recommended, models.SourceRecommended, &recommendedItems},
+		{trendingPosts, models.SourceTrending, &trendingItems},
+	} {
+		if source.feed == nil {
+			continue
+		}
+		for _, item := range source.feed.Items {
+			item.Source = source.name
+			*source.items = append(*source.items, item)
+		}
+		feed.Total += source.feed.Total
+		for experiment, variant := range source.feed.Experiments {
+			if feed.Experiments == nil {
+				feed.Experiments = make(map[string]string)
+			}
+			feed.Experiments[experiment] = variant
+		}
+	}
+	// Each source was diversified on its own; this catches runs and near
+	// duplicates across them
+	feed.Items = diversify("home", blendFeeds(joinedItems, recommendedItems, trendingItems, query.Limit, rules), snap.Diversity)
+	metrics.FeedItems.WithLabelValues("home").Observe(float64(len(feed.Items)))
+
+	fc.hydrate(ctx, feed.Items)
+	return feed, nil
+}
+
+// RecordInteractions logs the user's interactions with feed items, tagged
+// with the experiment variants they were assigned
+func (fc *FeedController) RecordInteractions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.InteractionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, feed)
+	accepted, err := fc.LogInteractions(c.Request.Context(), userID.(int), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"accepted": accepted})
+}
+
+// LogInteractions validates a user's interactions and queues them for the
+// interaction log, returning how many were accepted
+func (fc *FeedController) LogInteractions(ctx context.Context, userID int, req models.InteractionRequest) (int, error) {
+	snap := fc.settings.Current()
+	requestID := logger.RequestID(ctx)
+	now := time.Now().UTC()
+
+	events := make([]interactions.Event, 0, len(req.Events))
+	for i, e := range req.Events {
+		if !interactions.ClientEventTypes[e.Type] {
+			return 0, fail(http.StatusBadRequest, fmt.Sprintf("events[%d]: unsupported type %q", i, e.Type))
+		}
+		if _, err := primitive.ObjectIDFromHex(e.PostID); err != nil {
+			return 0, fail(http.StatusBadRequest, fmt.Sprintf("events[%d]: invalid post_id", i))
+		}
+		if e.Feed != "" && e.Feed != "personal" && e.Feed != "recommended" && e.Feed != "home" && e.Feed != "community" {
+			return 0, fail(http.StatusBadRequest, fmt.Sprintf("events[%d]: unknown feed %q", i, e.Feed))
+		}
+
+		// Client clocks are not trusted beyond a small window
+		occurredAt := now
+		if e.OccurredAt != nil && e.OccurredAt.After(now.Add(-24*time.Hour)) && e.OccurredAt.Before(now.Add(5*time.Minute)) {
+			occurredAt = e.OccurredAt.UTC()
+		}
+
+		event := interactions.Event{
+			UserID:     userID,
+			PostID:     e.PostID,
+			Type:       e.Type,
+			Feed:       e.Feed,
+			Position:   e.Position,
+			RequestID:  requestID,
+			OccurredAt: occurredAt,
+		}
+		if e.Feed != "" {
+			event.Experiments = feedExperiments(snap, userID, e.Feed)
+		}
+		events = append(events, event)
+	}
+
+	fc.recorder.Record(events...)
+	return len(events), nil
 }
 
 // GetUserPreferences retrieves the feed preferences for the authenticated user
@@ -159,22 +461,32 @@ func (fc *FeedController) GetUserPreferences(c *gin.Context) {
 		return
 	}
 
-	pref, err := fc.getUserPreferences(userID.(int))
+	pref, err := fc.Preferences(c.Request.Context(), userID.(int))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pref)
+}
+
+// Preferences returns a user's feed preferences, or the defaults if they have
+// not saved any
+func (fc *FeedController) Preferences(ctx context.Context, userID int) (*models.UserPreference, error) {
+	pref, err := fc.getUserPreferences(ctx, userID)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			// Return default preferences if not found
-			pref = &models.UserPreference{
-				UserID:         userID.(int),
+			return &models.UserPreference{
+				UserID:         userID,
 				FeedSortMethod: "date",
 				UpdatedAt:      time.Now(),
-			}
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user preferences"})
-			return
+			}, nil
 		}
+		return nil, fail(http.StatusInternalServerError, "Failed to get user preferences")
 	}
 
-	c.JSON(http.StatusOK, pref)
+	return pref, nil
 }
 
 // UpdateUserPreferences updates the feed preferences for the authenticated user
@@ -191,17 +503,28 @@ func (fc *FeedController) UpdateUserPreferences(c *gin.Context) {
 		return
 	}
 
+	pref, err := fc.UpdatePreferences(c.Request.Context(), userID.(int), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pref)
+}
+
+// UpdatePreferences applies the fields set in req to a user's preferences
+// and saves them
+func (fc *FeedController) UpdatePreferences(ctx context.Context, userID int, req models.UpdatePreferenceRequest) (*models.UserPreference, error) {
 	// Get current preferences
-	pref, err := fc.getUserPreferences(userID.(int))
+	pref, err := fc.getUserPreferences(ctx, userID)
 	if err != nil && err != mongo.ErrNoDocuments {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user preferences"})
-		return
+		return nil, fail(http.StatusInternalServerError, "Failed to get user preferences")
 	}
 
 	// Create new preferences if not found
 	if err == mongo.ErrNoDocuments || pref == nil {
 		pref = &models.UserPreference{
-			UserID:         userID.(int),
+			UserID:         userID,
 			FeedSortMethod: "date",
 			UpdatedAt:      time.Now(),
 		}
@@ -211,6 +534,9 @@ func (fc *FeedController) UpdateUserPreferences(c *gin.Context) {
 	if req.FeedSortMethod != "" {
 		pref.FeedSortMethod = req.FeedSortMethod
 	}
+	if req.FeedSortPeriod != "" {
+		pref.FeedSortPeriod = req.FeedSortPeriod
+	}
 	if req.PreferedTags != nil {
 		pref.PreferedTags = req.PreferedTags
 	}
@@ -223,26 +549,159 @@ func (fc *FeedController) UpdateUserPreferences(c *gin.Context) {
 	pref.UpdatedAt = time.Now()
 
 	// Save preferences
-	err = fc.saveUserPreferences(pref)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save user preferences"})
-		return
+	if err := fc.saveUserPreferences(ctx, pref); err != nil {
+		return nil, fail(http.StatusInternalServerError, "Failed to save user preferences")
 	}
 
-	c.JSON(http.StatusOK, pref)
+	return pref, nil
 }
 
 // Helper functions
 
+// applySortPreference fills in the sort method and top period from the user's
+// preferences when the query does not set them, defaulting to date and day
+func applySortPreference(query *models.FeedQuery, pref *models.UserPreference) {
+	if query.SortBy == "" && pref != nil {
+		query.SortBy = pref.FeedSortMethod
+	}
+	if query.Period == "" && pref != nil && pref.FeedSortMethod == query.SortBy {
+		query.Period = pref.FeedSortPeriod
+	}
+
+	// Default to date sorting if no preference is set
+	if query.SortBy == "" {
+		query.SortBy = "date"
+	}
+	if query.SortBy == "top" && query.Period == "" {
+		query.Period = "day"
+	}
+}
+
+// applyPageLimits fills in the default page and page size and caps the page
+// size at the configured maximum
+func (fc *FeedController) applyPageLimits(page, limit *int) {
+	limits := fc.settings.Current().Limits
+	if *page <= 0 {
+		*page = 1
+	}
+	if *limit <= 0 {
+		*limit = limits.DefaultPageSize
+	}
+	if *limit > limits.MaxPageSize {
+		*limit = limits.MaxPageSize
+	}
+}
+
+// recordImpressions records the impressions of a whole page of a feed
+func (fc *FeedController) recordImpressions(ctx context.Context, userID int, feedName string, feed *models.Feed) {
+	fc.RecordImpressions(ctx, userID, feedName, feed.Items, (feed.Page-1)*feed.Limit, feed.Experiments)
+}
+
+// RecordImpressions logs an impression for every item served from a feed,
+// starting at position offset, with the features it was ranked on, and the
+// user's exposure to the feed's experiments. The feed builders leave this to
+// their callers so only the items actually served are recorded.
+func (fc *FeedController) RecordImpressions(ctx context.Context, userID int, feedName string, items []models.FeedItem, offset int, experiments map[string]string) {
+	requestID := logger.RequestID(ctx)
+	now := time.Now().UTC()
+
+	events := make([]interactions.Event, 0, len(items))
+	var seenPosts []string
+	for i, item := range items {
+		if feedName == "recommended" || item.Source == models.SourceRecommended {
+			// Only recommendations are suppressed once seen, so only they
+			// are counted
+			seenPosts = append(seenPosts, item.PostID.Hex())
+		}
+		events = append(events, interactions.Event{
+			UserID:      userID,
+			PostID:      item.PostID.Hex(),
+			Type:        interactions.Impression,
+			Feed:        feedName,
+			Position:    offset + i,
+			RequestID:   requestID,
+			Experiments: experiments,
+			Features: map[string]interface{}{
+				"like_count":            item.Signals.LikeCount,
+				"created_at":            item.Signals.CreatedAt,
+				"preferred_tag_matches": item.Signals.PreferredTagMatches,
+				"preferred_community":   item.Signals.PreferredCommunity,
+				"demographic_matches":   item.Signals.DemographicMatches,
+				"candidate_sources":     item.Signals.Sources,
+				"community_id":          item.CommunityID,
+				"author_id":             item.UserID,
+				"tags":                  item.Tags,
+				"relevance":             item.Relevance,
+				"source":                item.Source,
+			},
+			OccurredAt: now,
+		})
+	}
+
+	fc.recorder.Record(events...)
+	fc.recorder.Expose(userID, experiments)
+	fc.seenRecorder.Record(userID, seenPosts, now)
+}
+
+// feedExperiments returns the experiment variants a user is assigned on a
+// feed; the home feed blends the personal and recommended feeds and so
+// carries the assignments of both
+func feedExperiments(snap *settings.Snapshot, userID int, feed string) map[string]string {
+	if feed != "home" {
+		return experiments.Assign(snap, userID, feed).Variants
+	}
+
+	var variants map[string]string
+	for _, f := range []string{"personal", "recommended"} {
+		for experiment, variant := range experiments.Assign(snap, userID, f).Variants {
+			if variants == nil {
+				variants = make(map[string]string)
+			}
+			variants[experiment] = variant
+		}
+	}
+	return variants
+}
+
+// getUpstream performs a GET request against an upstream service, recording
+// its latency and any transport or non-200 failure
+func (fc *FeedController) getUpstream(ctx context.Context, service, operation, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if requestID := logger.RequestID(ctx); requestID != "" {
+		req.Header.Set(logger.RequestIDHeader, requestID)
+	}
+
+	start := time.Now()
+	resp, err := fc.httpClient.Do(req)
+	metrics.ObserveUpstream(service, operation, start)
+	if err != nil {
+		metrics.UpstreamErrors.WithLabelValues(service, operation, "transport").Inc()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		metrics.UpstreamErrors.WithLabelValues(service, operation, fmt.Sprintf("status_%dxx", resp.StatusCode/100)).Inc()
+	}
+	return resp, nil
+}
+
 // getUserPreferences retrieves a user's feed preferences
-func (fc *FeedController) getUserPreferences(userID int) (*models.UserPreference, error) {
+func (fc *FeedController) getUserPreferences(ctx context.Context, userID int) (*models.UserPreference, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "getUserPreferences")
+	defer span.End()
+
 	collection := fc.mongoDB.Collection(database.PreferencesCollection)
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var pref models.UserPreference
 	err := collection.FindOne(ctx, bson.M{"user_id": userID}).Decode(&pref)
 	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			tracing.RecordError(span, err)
+		}
 		return nil, err
 	}
 
@@ -250,9 +709,9 @@ func (fc *FeedController) getUserPreferences(userID int) (*models.UserPreference
 }
 
 // saveUserPreferences saves a user's feed preferences
-func (fc *FeedController) saveUserPreferences(pref *models.UserPreference) error {
+func (fc *FeedController) saveUserPreferences(ctx context.Context, pref *models.UserPreference) error {
 	collection := fc.mongoDB.Collection(database.PreferencesCollection)
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	if pref.ID.IsZero() {
@@ -266,11 +725,18 @@ func (fc *FeedController) saveUserPreferences(pref *models.UserPreference) error
 }
 
 // getJoinedCommunities retrieves the communities joined by a user
-func (fc *FeedController) getJoinedCommunities(userID int) ([]int, error) {
+func (fc *FeedController) getJoinedCommunities(ctx context.Context, userID int) (communityIDs []int, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "getJoinedCommunities")
+	defer func() {
+		span.SetAttributes(attribute.Int("feed.communities", len(communityIDs)))
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
 	url := fmt.Sprintf("%s/user/%d/communities", fc.config.CommunityServiceURL, userID)
 
 	// Make HTTP request to community service
-	resp, err := http.Get(url)
+	resp, err := fc.getUpstream(ctx, "community", "joined_communities", url)
 	if err != nil {
 		return nil, err
 	}
@@ -290,20 +756,29 @@ func (fc *FeedController) getJoinedCommunities(userID int) ([]int, error) {
 		return nil, err
 	}
 
-	var communityIDs []int
 	for _, community := range result.Communities {
 		communityIDs = append(communityIDs, community.ID)
 	}
 
+	// Keep the snapshot community suggestions compare users by up to date,
+	// off the request path
+	fc.membershipRecorder.Record(userID, communityIDs, time.Now().UTC())
+
 	return communityIDs, nil
 }
 
 // getUserInfo retrieves user demographic information
-func (fc *FeedController) getUserInfo(userID int) (map[string]interface{}, error) {
+func (fc *FeedController) getUserInfo(ctx context.Context, userID int) (userInfo map[string]interface{}, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "getUserInfo")
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
 	url := fmt.Sprintf("%s/users/%d", fc.config.UserServiceURL, userID)
 
 	// Make HTTP request to user service
-	resp, err := http.Get(url)
+	resp, err := fc.getUpstream(ctx, "user", "user_info", url)
 	if err != nil {
 		return nil, err
 	}
@@ -322,222 +797,271 @@ func (fc *FeedController) getUserInfo(userID int) (map[string]interface{}, error
 }
 
 // buildFeed constructs a personalized feed for the user
-func (fc *FeedController) buildFeed(userID int, communities []int, pref *models.UserPreference, query models.FeedQuery) (*models.Feed, error) {
+func (fc *FeedController) buildFeed(ctx context.Context, userID int, communities []int, pref *models.UserPreference, query models.FeedQuery) (feed *models.Feed, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "buildFeed")
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
 	// Get posts from the post service
 	postsURL := fmt.Sprintf("%s/posts", fc.config.PostServiceURL)
+	filter := url.Values{}
 
 	// Add query parameters
 	if query.CommunityID > 0 {
 		postsURL += fmt.Sprintf("?community_id=%d", query.CommunityID)
+		filter.Set("community_id", strconv.Itoa(query.CommunityID))
 	} else if len(communities) > 0 {
 		// If community ID not specified, use all user's joined communities
 		postsURL += "?community_id=" + strconv.Itoa(communities[0])
 		for i := 1; i < len(communities); i++ {
 			postsURL += "," + strconv.Itoa(communities[i])
 		}
+		filter.Set("community_id", joinIDs(communities))
 	}
 
-	// Add pagination
-	if query.Page > 0 && query.Limit > 0 {
-		if postsURL == fc.config.PostServiceURL+"/posts" {
-			postsURL += "?"
-		} else {
-			postsURL += "&"
+	var postsResp postsResponse
+	if rankedInService(query.SortBy) {
+		// Ranked below over a window of posts, which the page is cut from
+		postsResp.Posts, err = fc.windowPosts(ctx, "list_posts", filter)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// Add pagination
+		if query.Page > 0 && query.Limit > 0 {
+			if postsURL == fc.config.PostServiceURL+"/posts" {
+				postsURL += "?"
+			} else {
+				postsURL += "&"
+			}
+			postsURL += fmt.Sprintf("page=%d&limit=%d", query.Page, query.Limit)
 		}
-		postsURL += fmt.Sprintf("page=%d&limit=%d", query.Page, query.Limit)
-	}
 
-	// Make HTTP request to post service
-	resp, err := http.Get(postsURL)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+		// Popular pages are drawn from the most liked posts
+		if query.SortBy == "popular" {
+			if strings.Contains(postsURL, "?") {
+				postsURL += "&sort=popular"
+			} else {
+				postsURL += "?sort=popular"
+			}
+		}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get posts: status %d", resp.StatusCode)
-	}
-
-	// Parse response
-	var postsResp struct {
-		Posts []struct {
-			ID          string    `json:"id"`
-			UserID      int       `json:"user_id"`
-			CommunityID int       `json:"community_id"`
-			Title       string    `json:"title"`
-			Content     string    `json:"content"`
-			MediaURLs   []string  `json:"media_urls"`
-			Tags        []string  `json:"tags"`
-			CreatedAt   time.Time `json:"created_at"`
-			LikeCount   int       `json:"like_count"`
-			UserName    string    `json:"user_name"`
-			UserPic     string    `json:"user_pic"`
-		} `json:"posts"`
-		Total int `json:"total"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&postsResp); err != nil {
-		return nil, err
+		// Make HTTP request to post service
+		resp, err := fc.getUpstream(ctx, "post", "list_posts", postsURL)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to get posts: status %d", resp.StatusCode)
+		}
+
+		// Parse response
+		if err := json.NewDecoder(resp.Body).Decode(&postsResp); err != nil {
+			return nil, err
+		}
 	}
 
-	// Convert to feed items
+	// Convert to feed items, scoring and sorting them
+	snap := fc.settings.Current()
+	assignment := experiments.Assign(snap, userID, "personal")
+	_, rankSpan := tracing.Tracer().Start(ctx, "rankFeed", trace.WithAttributes(
+		attribute.Int("feed.candidates", len(postsResp.Posts)),
+		attribute.String("feed.sort_by", query.SortBy),
+	))
+	now := time.Now()
 	feedItems := make([]models.FeedItem, 0, len(postsResp.Posts))
 	for _, post := range postsResp.Posts {
 		postID, err := primitive.ObjectIDFromHex(post.ID)
 		if err != nil {
 			// Skip invalid post IDs
+			metrics.FeedItemsFiltered.WithLabelValues("personal", "invalid_post_id").Inc()
+			continue
+		}
+		if !inPeriod(post.CreatedAt, query.SortBy, query.Period, now) {
+			metrics.FeedItemsFiltered.WithLabelValues("personal", "outside_period").Inc()
 			continue
 		}
 
 		// Calculate relevance score
-		relevance := calculateRelevance(post.LikeCount, post.CreatedAt, pref)
-
-		feedItem := models.FeedItem{
-			ID:           primitive.NewObjectID(),
-			PostID:       postID,
-			UserID:       post.UserID,
-			CommunityID:  post.CommunityID,
-			Title:        post.Title,
-			Content:      post.Content,
-			LikeCount:    post.LikeCount,
-			MediaURLs:    post.MediaURLs,
-			Tags:         post.Tags,
-			CreatedAt:    post.CreatedAt,
-			Relevance:    relevance,
-			AuthorName:   post.UserName,
-			AuthorAvatar: post.UserPic,
-		}
+		signals := ranking.Signals{LikeCount: post.LikeCount, CreatedAt: post.CreatedAt}
+		relevance := ranking.Score(signals, assignment.Ranking, now)
+
+		feedItem := post.toFeedItem(postID)
+		feedItem.Relevance = relevance
+		feedItem.Signals = signals
 		feedItems = append(feedItems, feedItem)
 	}
 
-	// Sort feed items based on query
-	sortFeedItems(feedItems, query.SortBy)
+	// Sort feed items based on query, then spread out authors and communities
+	sortFeedItems(feedItems, query.SortBy, now)
+	total := postsResp.Total
+	if rankedInService(query.SortBy) {
+		total = len(feedItems)
+		feedItems = pageOf(feedItems, query.Page, query.Limit)
+	}
+	feedItems = diversify("personal", feedItems, snap.Diversity)
+	rankSpan.End()
+	metrics.FeedItems.WithLabelValues("personal").Observe(float64(len(feedItems)))
 
 	return &models.Feed{
-		Items: feedItems,
-		Total: postsResp.Total,
-		Page:  query.Page,
-		Limit: query.Limit,
+		Items:       feedItems,
+		Total:       total,
+		Page:        query.Page,
+		Limit:       query.Limit,
+		Experiments: assignment.Variants,
 	}, nil
 }
 
-// buildRecommendedFeed constructs a recommended feed for the user
-func (fc *FeedController) buildRecommendedFeed(userID int, pref *models.UserPreference, userInfo map[string]interface{}, query models.FeedQuery) (*models.Feed, error) {
-	// Get popular posts
-	postsURL := fmt.Sprintf("%s/posts?sort=popular&page=%d&limit=%d", fc.config.PostServiceURL, query.Page, query.Limit)
-
-	// Make HTTP request to post service
-	resp, err := http.Get(postsURL)
+// buildRecommendedFeed constructs a recommended feed for the user, leaving
+// out the user's own posts and, if enabled, posts from joinedCommunities
+func (fc *FeedController) buildRecommendedFeed(ctx context.Context, userID int, pref *models.UserPreference, userInfo map[string]interface{}, joinedCommunities []int, query models.FeedQuery) (feed *models.Feed, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "buildRecommendedFeed")
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	// Gather candidates from every enabled source while looking up what the
+	// user has already seen
+	snap := fc.settings.Current()
+	joined := make(map[int]bool)
+	if snap.Enabled("exclude_joined_communities") {
+		for _, communityID := range joinedCommunities {
+			joined[communityID] = true
+		}
+	}
+	seenCounts := make(chan map[string]int, 1)
+	go func() { seenCounts <- fc.seenCounts(ctx, userID, snap.SeenPosts) }()
+	offset := (query.Page - 1) * query.Limit
+	depth := min(offset+query.Limit, maxRecommendedCandidates)
+	generated, err := candidates.Generate(ctx, "recommended", fc.recommendationSources(userID, pref, joined, depth, snap),
+		func(post upstreamPost) string { return post.ID })
+	seenPosts := <-seenCounts
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get posts: status %d", resp.StatusCode)
-	}
-
-	// Parse response
-	var postsResp struct {
-		Posts []struct {
-			ID          string    `json:"id"`
-			UserID      int       `json:"user_id"`
-			CommunityID int       `json:"community_id"`
-			Title       string    `json:"title"`
-			Content     string    `json:"content"`
-			MediaURLs   []string  `json:"media_urls"`
-			Tags        []string  `json:"tags"`
-			CreatedAt   time.Time `json:"created_at"`
-			LikeCount   int       `json:"like_count"`
-			UserName    string    `json:"user_name"`
-			UserPic     string    `json:"user_pic"`
-		} `json:"posts"`
-		Total int `json:"total"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&postsResp); err != nil {
-		return nil, err
+	// Score and sort the candidates, spread out authors and communities, then
+	// cut the page from the merged ranking. Filtered candidates leave the
+	// ranking short, so while the page is not full and the sources have more
+	// posts, they are asked for more.
+	assignment := experiments.Assign(snap, userID, "recommended")
+	_, rankSpan := tracing.Tracer().Start(ctx, "rankFeed", trace.WithAttributes(
+		attribute.String("feed.sort_by", query.SortBy),
+	))
+	feedItems, filtered := rankRecommendations(generated.Candidates, userID, pref, userInfo, joined, seenPosts, assignment, snap)
+	for round := 0; round < maxBackfillRounds && len(feedItems) < offset+query.Limit && generated.Remaining() > 0 && depth < maxRecommendedCandidates; round++ {
+		depth = min(depth*2, maxRecommendedCandidates)
+		more, err := candidates.Generate(ctx, "recommended", fc.recommendationSources(userID, pref, joined, depth, snap),
+			func(post upstreamPost) string { return post.ID })
+		if err != nil {
+			// Serve the short page rather than nothing
+			slog.WarnContext(ctx, "Failed to backfill recommendations", "user_id", userID, "error", err)
+			break
+		}
+		generated = more
+		feedItems, filtered = rankRecommendations(generated.Candidates, userID, pref, userInfo, joined, seenPosts, assignment, snap)
 	}
+	for reason, count := range filtered {
+		metrics.FeedItemsFiltered.WithLabelValues("recommended", reason).Add(float64(count))
+	}
+	rankSpan.SetAttributes(attribute.Int("feed.candidates", len(generated.Candidates)))
+	ranked := len(feedItems)
+	feedItems = feedItems[min(offset, ranked):min(offset+query.Limit, ranked)]
+	rankSpan.End()
+	metrics.FeedItems.WithLabelValues("recommended").Observe(float64(len(feedItems)))
 
-	// Convert to feed items
-	feedItems := make([]models.FeedItem, 0, len(postsResp.Posts))
-	for _, post := range postsResp.Posts {
+	// The sources' unreturned posts may still be recommended, up to the
+	// depth recommendations page to
+	total := min(ranked+generated.Remaining(), maxRecommendedCandidates)
+
+	return &models.Feed{
+		Items:       feedItems,
+		Total:       total,
+		Page:        query.Page,
+		Limit:       query.Limit,
+		Experiments: assignment.Variants,
+	}, nil
+}
+
+// rankRecommendations scores and sorts recommendation candidates, leaving
+// out invalid posts, the user's own posts, posts in the joined communities
+// and posts seen too often, and applies the diversity rules. It returns the
+// ranking and how many candidates were removed for each reason.
+func rankRecommendations(generated []candidates.Candidate[upstreamPost], userID int, pref *models.UserPreference, userInfo map[string]interface{}, joined map[int]bool, seenPosts map[string]int, assignment experiments.Assignment, snap *settings.Snapshot) ([]models.FeedItem, map[string]int) {
+	now := time.Now()
+	filtered := make(map[string]int)
+	feedItems := make([]models.FeedItem, 0, len(generated))
+	for _, candidate := range generated {
+		post := candidate.Post
 		postID, err := primitive.ObjectIDFromHex(post.ID)
 		if err != nil {
-			// Skip invalid post IDs
+			filtered["invalid_post_id"]++
+			continue
+		}
+		if post.UserID == userID {
+			filtered["own_post"]++
+			continue
+		}
+		if joined[post.CommunityID] {
+			// The user already sees these in their personal feed
+			filtered["joined_community"]++
+			continue
+		}
+		if snap.SeenPosts.MaxImpressions > 0 && seenPosts[post.ID] >= snap.SeenPosts.MaxImpressions {
+			filtered["seen"]++
 			continue
 		}
 
 		// Calculate relevance score with user demographics factored in
-		relevance := calculateRecommendationScore(post, userInfo, pref)
-
-		feedItem := models.FeedItem{
-			ID:           primitive.NewObjectID(),
-			PostID:       postID,
-			UserID:       post.UserID,
-			CommunityID:  post.CommunityID,
-			Title:        post.Title,
-			Content:      post.Content,
-			LikeCount:    post.LikeCount,
-			MediaURLs:    post.MediaURLs,
-			Tags:         post.Tags,
-			CreatedAt:    post.CreatedAt,
-			Relevance:    relevance,
-			AuthorName:   post.UserName,
-			AuthorAvatar: post.UserPic,
-		}
+		signals := recommendationSignals(post, userInfo, pref, snap)
+		signals.Sources = candidate.Sources
+		relevance := ranking.Score(signals, assignment.Ranking, now)
+
+		feedItem := post.toFeedItem(postID)
+		feedItem.Relevance = relevance
+		feedItem.Signals = signals
 		feedItems = append(feedItems, feedItem)
 	}
 
-	// Sort by relevance
-	sortFeedItems(feedItems, "relevance")
-
-	return &models.Feed{
-		Items: feedItems,
-		Total: postsResp.Total,
-		Page:  query.Page,
-		Limit: query.Limit,
-	}, nil
+	sortFeedItems(feedItems, "relevance", now)
+	feedItems, dropped := diversity.Rerank(feedItems, snap.Diversity)
+	for reason, count := range dropped {
+		filtered[reason] += count
+	}
+	return feedItems, filtered
 }
 
-// calculateRelevance calculates a post's relevance score
-func calculateRelevance(likeCount int, createdAt time.Time, pref *models.UserPreference) float64 {
-	// Simple algorithm based on popularity and recency
-	recencyFactor := 1.0 / (1.0 + float64(time.Since(createdAt).Hours()/24.0)) // Higher value for more recent posts
-	popularityFactor := float64(likeCount) / 100.0                             // Normalize like count
-
-	// Combine factors with weights
-	relevanceScore := (0.7 * recencyFactor) + (0.3 * popularityFactor)
-
-	return relevanceScore
+// seenCounts returns how often the user was shown each post within the
+// cooldown. Suppression is best effort, so a failed lookup returns nothing.
+func (fc *FeedController) seenCounts(ctx context.Context, userID int, rules settings.SeenPosts) map[string]int {
+	if rules.MaxImpressions == 0 {
+		return nil
+	}
+	since := time.Now().AddDate(0, 0, -rules.CooldownDays+1)
+	counts, err := fc.seen.Counts(ctx, userID, since)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to load seen posts", "user_id", userID, "error", err)
+		return nil
+	}
+	return counts
 }
 
-// calculateRecommendationScore calculates a recommendation score for a post
-func calculateRecommendationScore(post struct {
-	ID          string    `json:"id"`
-	UserID      int       `json:"user_id"`
-	CommunityID int       `json:"community_id"`
-	Title       string    `json:"title"`
-	Content     string    `json:"content"`
-	MediaURLs   []string  `json:"media_urls"`
-	Tags        []string  `json:"tags"`
-	CreatedAt   time.Time `json:"created_at"`
-	LikeCount   int       `json:"like_count"`
-	UserName    string    `json:"user_name"`
-	UserPic     string    `json:"user_pic"`
-}, userInfo map[string]interface{}, pref *models.UserPreference) float64 {
-	// Calculate base relevance
-	baseRelevance := calculateRelevance(post.LikeCount, post.CreatedAt, pref)
-
-	// Add additional factors based on user preferences
-	additionalScore := 0.0
+// recommendationSignals gathers the ranking inputs for a recommended post,
+// including how well it matches the user's preferences and demographics
+func recommendationSignals(post upstreamPost, userInfo map[string]interface{}, pref *models.UserPreference, snap *settings.Snapshot) ranking.Signals {
+	signals := ranking.Signals{LikeCount: post.LikeCount, CreatedAt: post.CreatedAt}
 
 	// Preferred tags match
 	if pref != nil && len(pref.PreferedTags) > 0 {
 		for _, tag := range post.Tags {
 			for _, prefTag := range pref.PreferedTags {
 				if tag == prefTag {
-					additionalScore += 0.2 // Boost for each matching preferred tag
+					signals.PreferredTagMatches++ // Boost for each matching preferred tag
 					break
 				}
 			}
@@ -548,14 +1072,14 @@ func calculateRecommendationScore(post struct {
 	if pref != nil && len(pref.PreferedCommunities) > 0 {
 		for _, prefCommunity := range pref.PreferedCommunities {
 			if post.CommunityID == prefCommunity {
-				additionalScore += 0.3 // Boost for preferred community
+				signals.PreferredCommunity = true
 				break
 			}
 		}
 	}
 
 	// User demographics (gender, interests, etc.)
-	if userInfo != nil {
+	if userInfo != nil && snap.Enabled("demographic_boost") {
 		// Here you would implement logic to boost posts based on demographic matching
 		// This is a simplified placeholder - in a real system, this would be more sophisticated
 		if gender, ok := userInfo["gender"].(string); ok {
@@ -564,29 +1088,119 @@ func calculateRecommendationScore(post struct {
 			for _, tag := range post.Tags {
 				if (gender == "male" && (tag == "sports" || tag == "gaming")) ||
 					(gender == "female" && (tag == "fashion" || tag == "beauty")) {
-					additionalScore += 0.1
+					signals.DemographicMatches++
 				}
 			}
 		}
 	}
 
-	return baseRelevance + additionalScore
+	return signals
+}
+
+// diversify applies the diversity rules to a ranked page and counts the
+// items it removes
+func diversify(feedName string, items []models.FeedItem, rules settings.DiversityRules) []models.FeedItem {
+	items, dropped := diversity.Rerank(items, rules)
+	for reason, count := range dropped {
+		metrics.FeedItemsFiltered.WithLabelValues(feedName, reason).Add(float64(count))
+	}
+	return items
+}
+
+// sortWindow is how many of the newest and of the most liked posts the hot,
+// rising and top sorts rank. The post service only sorts by date and likes,
+// so these sorts are ranked here and page over those posts.
+const sortWindow = 200
+
+// rankedInService reports whether feeds sorted by sortBy are ranked by this
+// service over a window of posts rather than paged by the post service
+func rankedInService(sortBy string) bool {
+	switch sortBy {
+	case "hot", "rising", "top":
+		return true
+	}
+	return false
+}
+
+// windowPosts retrieves the newest and the most liked sortWindow posts
+// matching filter, each post once
+func (fc *FeedController) windowPosts(ctx context.Context, operation string, filter url.Values) ([]upstreamPost, error) {
+	lists := make([][]upstreamPost, 2)
+	g, gctx := errgroup.WithContext(ctx)
+	for i, sortBy := range []string{"", "popular"} {
+		params := url.Values{"page": {"1"}, "limit": {strconv.Itoa(sortWindow)}}
+		for key, values := range filter {
+			params[key] = values
+		}
+		if sortBy != "" {
+			params.Set("sort", sortBy)
+		}
+		i := i
+		g.Go(func() error {
+			posts, _, err := fc.listPosts(gctx, operation, params)
+			lists[i] = posts
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(lists[0])+len(lists[1]))
+	posts := make([]upstreamPost, 0, len(lists[0])+len(lists[1]))
+	for _, list := range lists {
+		for _, post := range list {
+			if !seen[post.ID] {
+				seen[post.ID] = true
+				posts = append(posts, post)
+			}
+		}
+	}
+	return posts, nil
+}
+
+// pageOf returns the given page of items
+func pageOf(items []models.FeedItem, page, limit int) []models.FeedItem {
+	offset := min(max((page-1)*limit, 0), len(items))
+	return items[offset:min(offset+limit, len(items))]
+}
+
+// inPeriod reports whether a post created at createdAt belongs in a feed
+// sorted by sortBy; only top feeds are limited to a period
+func inPeriod(createdAt time.Time, sortBy, period string, now time.Time) bool {
+	window := ranking.Periods[period]
+	return sortBy != "top" || window == 0 || !createdAt.Before(now.Add(-window))
 }
 
-// sortFeedItems sorts feed items based on the specified method
-func sortFeedItems(items []models.FeedItem, sortBy string) {
+// sortFeedItems sorts feed items based on the specified method. Ties go to
+// the newer post, then the lower post ID, so equal scores keep one order.
+func sortFeedItems(items []models.FeedItem, sortBy string, now time.Time) {
+	var score func(item models.FeedItem) float64
 	switch sortBy {
-	case "date":
-		// Sort by creation date (newest first)
-		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
-	case "popular":
+	case "popular", "top":
 		// Sort by like count (highest first)
-		options.Find().SetSort(bson.D{{Key: "like_count", Value: -1}})
+		score = func(item models.FeedItem) float64 { return float64(item.LikeCount) }
+	case "hot":
+		// Sort by log-scaled likes traded against age (highest first)
+		score = func(item models.FeedItem) float64 { return ranking.Hot(item.LikeCount, item.CreatedAt) }
+	case "rising":
+		// Sort by likes per hour of age (highest first)
+		score = func(item models.FeedItem) float64 { return ranking.Rising(item.LikeCount, item.CreatedAt, now) }
 	case "relevance":
 		// Sort by relevance score (highest first)
-		options.Find().SetSort(bson.D{{Key: "relevance", Value: -1}})
-	default:
-		// Default to date sorting
-		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+		score = func(item models.FeedItem) float64 { return item.Relevance }
 	}
+
+	// Without a score, sort by creation date (newest first)
+	sort.SliceStable(items, func(i, j int) bool {
+		if score != nil {
+			if si, sj := score(items[i]), score(items[j]); si != sj {
+				return si > sj
+			}
+		}
+		if !items[i].CreatedAt.Equal(items[j].CreatedAt) {
+			return items[i].CreatedAt.After(items[j].CreatedAt)
+		}
+		return items[i].PostID.Hex() < items[j].PostID.Hex()
+	})
 }
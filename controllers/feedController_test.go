@@ -0,0 +1,391 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/models"
+	"github.com/CircleConnectApp/feed-service/seen"
+	"github.com/CircleConnectApp/feed-service/settings"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestBuildFeed(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	// The post service lists posts newest first: the second is the most
+	// liked recent post and the fourth the most liked but ten days old. The
+	// fifth is in a community the user has not joined.
+	now := time.Now()
+	ps := &postService{posts: []upstreamPost{
+		{ID: testPostID(1), UserID: 1, CommunityID: 10, LikeCount: 1, CreatedAt: now.Add(-time.Hour)},
+		{ID: testPostID(2), UserID: 2, CommunityID: 20, LikeCount: 500, CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: testPostID(3), UserID: 3, CommunityID: 10, LikeCount: 0, CreatedAt: now.Add(-3 * time.Hour)},
+		{ID: testPostID(4), UserID: 4, CommunityID: 20, LikeCount: 1000, CreatedAt: now.Add(-10 * 24 * time.Hour)},
+		{ID: testPostID(5), UserID: 5, CommunityID: 30, LikeCount: 50, CreatedAt: now.Add(-time.Hour)},
+	}}
+	// window is the pair of requests hot, rising and top rank their posts from
+	window := func(communities string) []string {
+		return []string{
+			url.Values{"community_id": {communities}, "page": {"1"}, "limit": {"200"}}.Encode(),
+			url.Values{"community_id": {communities}, "page": {"1"}, "limit": {"200"}, "sort": {"popular"}}.Encode(),
+		}
+	}
+
+	tests := []struct {
+		name        string
+		query       models.FeedQuery
+		wantQueries []string
+		wantPosts   []string
+		wantTotal   int
+	}{
+		{
+			name:        "date",
+			query:       models.FeedQuery{SortBy: "date", Page: 1, Limit: 2},
+			wantQueries: []string{"community_id=10%2C20&limit=2&page=1"},
+			wantPosts:   []string{testPostID(1), testPostID(2)}, wantTotal: 4,
+		},
+		{
+			name:        "relevance",
+			query:       models.FeedQuery{SortBy: "relevance", Page: 1, Limit: 4},
+			wantQueries: []string{"community_id=10%2C20&limit=4&page=1"},
+			wantPosts:   []string{testPostID(4), testPostID(2), testPostID(1), testPostID(3)}, wantTotal: 4,
+		},
+		{
+			name:        "popular",
+			query:       models.FeedQuery{SortBy: "popular", Page: 1, Limit: 2},
+			wantQueries: []string{"community_id=10%2C20&limit=2&page=1&sort=popular"},
+			wantPosts:   []string{testPostID(4), testPostID(2)}, wantTotal: 4,
+		},
+		{
+			name:        "hot",
+			query:       models.FeedQuery{SortBy: "hot", Page: 1, Limit: 2},
+			wantQueries: window("10,20"),
+			wantPosts:   []string{testPostID(2), testPostID(1)}, wantTotal: 4,
+		},
+		{
+			name:        "hot second page",
+			query:       models.FeedQuery{SortBy: "hot", Page: 2, Limit: 2},
+			wantQueries: window("10,20"),
+			wantPosts:   []string{testPostID(3), testPostID(4)}, wantTotal: 4,
+		},
+		{
+			name:        "rising",
+			query:       models.FeedQuery{SortBy: "rising", Page: 1, Limit: 4},
+			wantQueries: window("10,20"),
+			wantPosts:   []string{testPostID(2), testPostID(4), testPostID(1), testPostID(3)}, wantTotal: 4,
+		},
+		{
+			name:        "top of the day",
+			query:       models.FeedQuery{SortBy: "top", Period: "day", Page: 1, Limit: 4},
+			wantQueries: window("10,20"),
+			wantPosts:   []string{testPostID(2), testPostID(1), testPostID(3)}, wantTotal: 3,
+		},
+		{
+			name:        "top of all time",
+			query:       models.FeedQuery{SortBy: "top", Period: "all", Page: 1, Limit: 4},
+			wantQueries: window("10,20"),
+			wantPosts:   []string{testPostID(4), testPostID(2), testPostID(1), testPostID(3)}, wantTotal: 4,
+		},
+		{
+			name:        "one community",
+			query:       models.FeedQuery{SortBy: "hot", CommunityID: 30, Page: 1, Limit: 4},
+			wantQueries: window("30"),
+			wantPosts:   []string{testPostID(5)}, wantTotal: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			upstream := http.NewServeMux()
+			upstream.Handle("/posts", ps)
+			fc := testController(mt, upstream, nil)
+			ps.queries = nil
+
+			feed, err := fc.buildFeed(context.Background(), 1, []int{10, 20}, nil, tt.query)
+			if err != nil {
+				mt.Fatal(err)
+			}
+			var queries []string
+			for _, q := range ps.queries {
+				queries = append(queries, q.Encode())
+			}
+			sort.Strings(queries)
+			if !reflect.DeepEqual(queries, tt.wantQueries) {
+				mt.Errorf("queries = %q, want %q", queries, tt.wantQueries)
+			}
+			if got := postIDs(feed.Items); !reflect.DeepEqual(got, tt.wantPosts) {
+				mt.Errorf("posts = %v, want %v", got, tt.wantPosts)
+			}
+			if feed.Total != tt.wantTotal {
+				mt.Errorf("total = %d, want %d", feed.Total, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestSortFeedItems(t *testing.T) {
+	createdAt := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	item := func(n, likes int, relevance float64, age time.Duration) models.FeedItem {
+		postID, _ := primitive.ObjectIDFromHex(testPostID(n))
+		return models.FeedItem{PostID: postID, LikeCount: likes, Relevance: relevance, CreatedAt: createdAt.Add(-age)}
+	}
+	// Posts 1 and 2 tie on everything but their IDs, post 3 ties with them
+	// on likes and relevance but is older, and post 4 is the oldest
+	items := []models.FeedItem{
+		item(2, 5, 0.5, time.Hour),
+		item(4, 9, 0.2, 3*time.Hour),
+		item(3, 5, 0.5, 2*time.Hour),
+		item(1, 5, 0.5, time.Hour),
+	}
+
+	tests := []struct {
+		sortBy string
+		want   []string
+	}{
+		{sortBy: "date", want: []string{testPostID(1), testPostID(2), testPostID(3), testPostID(4)}},
+		{sortBy: "popular", want: []string{testPostID(4), testPostID(1), testPostID(2), testPostID(3)}},
+		{sortBy: "relevance", want: []string{testPostID(1), testPostID(2), testPostID(3), testPostID(4)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			sorted := append([]models.FeedItem(nil), items...)
+			sortFeedItems(sorted, tt.sortBy, createdAt)
+			if got := postIDs(sorted); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sortFeedItems(%q) = %v, want %v", tt.sortBy, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildRecommendedFeed(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	// Eight posts by different authors in different communities. The popular
+	// source proposes them by likes and the preferred tags source proposes
+	// the last two, which have few likes. Matching both preferred tags ranks
+	// post 7 first; post 8 matches one and ranks last.
+	now := time.Now()
+	ps := &postService{}
+	for n := 1; n <= 6; n++ {
+		ps.posts = append(ps.posts, upstreamPost{ID: testPostID(n), UserID: 10 + n, CommunityID: 100 + n, LikeCount: 100 - n, CreatedAt: now.Add(-time.Hour)})
+	}
+	ps.posts = append(ps.posts,
+		upstreamPost{ID: testPostID(7), UserID: 17, CommunityID: 107, LikeCount: 10, Tags: []string{"go", "rust"}, CreatedAt: now.Add(-30 * time.Minute)},
+		upstreamPost{ID: testPostID(8), UserID: 18, CommunityID: 108, LikeCount: 5, Tags: []string{"go"}, CreatedAt: now.Add(-10 * time.Minute)},
+	)
+	pref := &models.UserPreference{PreferedTags: []string{"go", "rust"}}
+	seenCounts := func(counts map[int]int) bson.D {
+		posts := bson.D{}
+		for n, count := range counts {
+			posts = append(posts, bson.E{Key: testPostID(n), Value: count})
+		}
+		return mtest.CreateCursorResponse(0, "feed.seen_posts", mtest.FirstBatch, bson.D{{Key: "posts", Value: posts}})
+	}
+
+	tests := []struct {
+		name       string
+		page       int
+		limit      int
+		joined     []int
+		seen       bson.D // response to the seen posts lookup; nil disables suppression
+		wantPosts  []string
+		wantTotal  int
+		wantLimits []string // of the popular source's requests, each from its first page
+	}{
+		{name: "first page", page: 1, limit: 3, wantPosts: []string{testPostID(7), testPostID(1), testPostID(2)}, wantTotal: 10, wantLimits: []string{"3"}},
+		{name: "second page", page: 2, limit: 3, wantPosts: []string{testPostID(3), testPostID(4), testPostID(5)}, wantTotal: 10, wantLimits: []string{"6"}},
+		{name: "last page", page: 3, limit: 3, wantPosts: []string{testPostID(6), testPostID(8)}, wantTotal: 8, wantLimits: []string{"9"}},
+		{name: "past the end", page: 4, limit: 3, wantPosts: []string{}, wantTotal: 8, wantLimits: []string{"12"}},
+		{
+			name: "seen posts are backfilled", page: 1, limit: 3,
+			seen:      seenCounts(map[int]int{1: 4, 2: 3, 7: 3}),
+			wantPosts: []string{testPostID(3), testPostID(4), testPostID(5)}, wantTotal: 7, wantLimits: []string{"3", "6"},
+		},
+		{
+			name: "backfill stops after a few rounds", page: 1, limit: 1,
+			seen:      seenCounts(map[int]int{1: 3, 2: 3, 3: 3, 4: 3, 5: 3, 6: 3, 7: 3, 8: 3}),
+			wantPosts: []string{}, wantTotal: 0, wantLimits: []string{"1", "2", "4", "8"},
+		},
+		{
+			name: "joined communities are left out", page: 1, limit: 3,
+			joined:    []int{101, 102},
+			wantPosts: []string{testPostID(7), testPostID(3), testPostID(8)}, wantTotal: 8, wantLimits: []string{"3"},
+		},
+		{
+			name: "failed seen lookup suppresses nothing", page: 1, limit: 3,
+			seen:      mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "unavailable"}),
+			wantPosts: []string{testPostID(7), testPostID(1), testPostID(2)}, wantTotal: 10, wantLimits: []string{"3"},
+		},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			upstream := http.NewServeMux()
+			upstream.Handle("/posts", ps)
+			fc := testController(mt, upstream, func(s *settings.Settings) {
+				s.Candidates.Fresh = 0
+				s.Candidates.EngagedAuthors = 0
+				if tt.seen == nil {
+					s.SeenPosts.MaxImpressions = 0
+				}
+			})
+			if tt.seen != nil {
+				mt.AddMockResponses(mtest.CreateSuccessResponse()) // seen posts indexes
+				seenStore, err := seen.NewStore(context.Background(), mt.DB.Collection("seen_posts"))
+				if err != nil {
+					mt.Fatal(err)
+				}
+				fc.seen = seenStore
+				mt.AddMockResponses(tt.seen)
+			}
+			ps.queries = nil
+
+			feed, err := fc.buildRecommendedFeed(context.Background(), 1, pref, nil, tt.joined, models.FeedQuery{SortBy: "relevance", Page: tt.page, Limit: tt.limit})
+			if err != nil {
+				mt.Fatal(err)
+			}
+			if got := postIDs(feed.Items); !reflect.DeepEqual(got, tt.wantPosts) {
+				mt.Errorf("posts = %v, want %v", got, tt.wantPosts)
+			}
+			if feed.Total != tt.wantTotal {
+				mt.Errorf("total = %d, want %d", feed.Total, tt.wantTotal)
+			}
+			var limits []string
+			for _, q := range ps.requests("sort", "popular") {
+				if q.Get("page") != "1" {
+					mt.Errorf("query %v, want the first page", q)
+				}
+				if !q.Has("tags") {
+					limits = append(limits, q.Get("limit"))
+				}
+			}
+			if !reflect.DeepEqual(limits, tt.wantLimits) {
+				mt.Errorf("limits = %v, want %v", limits, tt.wantLimits)
+			}
+			if tagged := ps.requests("tags", "go,rust"); len(tagged) != len(limits) {
+				mt.Errorf("preferred tags source asked %d times, want %d", len(tagged), len(limits))
+			}
+		})
+	}
+}
+
+func TestRecordImpressionsCountsSeenRecommendations(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	item := func(n int, source string) models.FeedItem {
+		postID, _ := primitive.ObjectIDFromHex(testPostID(n))
+		return models.FeedItem{PostID: postID, Source: source}
+	}
+
+	tests := []struct {
+		name     string
+		feedName string
+		items    []models.FeedItem
+		wantSeen []string
+	}{
+		{name: "recommended feed", feedName: "recommended", items: []models.FeedItem{item(1, ""), item(2, "")}, wantSeen: []string{testPostID(1), testPostID(2)}},
+		{name: "home feed recommendations only", feedName: "home", items: []models.FeedItem{item(1, models.SourceJoined), item(2, models.SourceRecommended), item(3, models.SourceTrending)}, wantSeen: []string{testPostID(2)}},
+		{name: "personal feed", feedName: "personal", items: []models.FeedItem{item(1, "")}},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			fc := testController(mt, http.NotFoundHandler(), nil)
+			mt.AddMockResponses(mtest.CreateSuccessResponse()) // seen posts indexes
+			seenStore, err := seen.NewStore(context.Background(), mt.DB.Collection("seen_posts"))
+			if err != nil {
+				mt.Fatal(err)
+			}
+			fc.seenRecorder = seen.NewRecorder(seenStore, 4)
+			mt.AddMockResponses(mtest.CreateSuccessResponse())
+			mt.ClearEvents()
+
+			fc.RecordImpressions(context.Background(), 1, tt.feedName, tt.items, 0, nil)
+			if err := fc.seenRecorder.Close(context.Background()); err != nil {
+				mt.Fatal(err)
+			}
+
+			var seenPosts []string
+			for _, event := range mt.GetAllStartedEvents() {
+				if event.CommandName != "update" {
+					continue
+				}
+				elems, err := event.Command.Lookup("updates", "0", "u", "$inc").Document().Elements()
+				if err != nil {
+					mt.Fatal(err)
+				}
+				for _, elem := range elems {
+					if postID, ok := strings.CutPrefix(elem.Key(), "posts."); ok {
+						seenPosts = append(seenPosts, postID)
+					}
+				}
+			}
+			sort.Strings(seenPosts)
+			if !reflect.DeepEqual(seenPosts, tt.wantSeen) {
+				mt.Errorf("seen = %v, want %v", seenPosts, tt.wantSeen)
+			}
+		})
+	}
+}
+
+func TestRecommendedFeedJoinedCommunities(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	createdAt := time.Now().Add(-time.Hour)
+	ps := &postService{posts: []upstreamPost{
+		{ID: testPostID(1), UserID: 11, CommunityID: 101, LikeCount: 3, CreatedAt: createdAt},
+		{ID: testPostID(2), UserID: 12, CommunityID: 102, LikeCount: 2, CreatedAt: createdAt},
+		{ID: testPostID(3), UserID: 13, CommunityID: 103, LikeCount: 1, CreatedAt: createdAt},
+	}}
+
+	tests := []struct {
+		name        string
+		exclude     bool
+		wantLookups int
+		wantPosts   []string
+	}{
+		// Leaving out joined communities is best effort
+		{name: "failed lookup excludes nothing", exclude: true, wantLookups: 1, wantPosts: []string{testPostID(1), testPostID(2), testPostID(3)}},
+		{name: "exclusion disabled", exclude: false, wantLookups: 0, wantPosts: []string{testPostID(1), testPostID(2), testPostID(3)}},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			var lookups int
+			upstream := http.NewServeMux()
+			upstream.Handle("/posts", ps)
+			upstream.HandleFunc("/user/1/communities", func(w http.ResponseWriter, r *http.Request) {
+				lookups++
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			})
+			fc := testController(mt, upstream, func(s *settings.Settings) {
+				s.Features["exclude_joined_communities"] = tt.exclude
+				s.Candidates.Fresh = 0
+				s.Candidates.EngagedAuthors = 0
+				s.SeenPosts.MaxImpressions = 0
+			})
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, "feed.preferences", mtest.FirstBatch))
+
+			feed, err := fc.RecommendedFeed(context.Background(), 1, models.FeedQuery{Page: 1, Limit: 3})
+			if err != nil {
+				mt.Fatal(err)
+			}
+			if lookups != tt.wantLookups {
+				mt.Errorf("%d community lookups, want %d", lookups, tt.wantLookups)
+			}
+			if got := postIDs(feed.Items); !reflect.DeepEqual(got, tt.wantPosts) {
+				mt.Errorf("posts = %v, want %v", got, tt.wantPosts)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/CircleConnectApp/feed-service/feedtokens"
+	"github.com/CircleConnectApp/feed-service/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type FeedTokenController struct {
+	store *feedtokens.Store
+}
+
+// NewFeedTokenController creates a new instance of FeedTokenController
+func NewFeedTokenController(store *feedtokens.Store) *FeedTokenController {
+	return &FeedTokenController{store: store}
+}
+
+// CreateFeedToken issues a feed token for the authenticated user. The token is
+// only ever returned in this response.
+func (tc *FeedTokenController) CreateFeedToken(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.CreateFeedTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret, token, err := tc.store.Create(c.Request.Context(), userID.(int), req.Name)
+	if err != nil {
+		if errors.Is(err, feedtokens.ErrTooMany) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Too many feed tokens; revoke one first"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create feed token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"id":         token.ID,
+		"name":       token.Name,
+		"created_at": token.CreatedAt,
+		"token":      secret,
+	})
+}
+
+// GetFeedTokens lists the authenticated user's feed tokens, without their secrets
+func (tc *FeedTokenController) GetFeedTokens(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	tokens, err := tc.store.List(c.Request.Context(), userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get feed tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tokens": tokens})
+}
+
+// RevokeFeedToken revokes one of the authenticated user's feed tokens
+func (tc *FeedTokenController) RevokeFeedToken(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid feed token ID"})
+		return
+	}
+
+	if err := tc.store.Revoke(c.Request.Context(), userID.(int), id); err != nil {
+		if errors.Is(err, feedtokens.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Feed token not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke feed token"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
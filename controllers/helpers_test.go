@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/interactions"
+	"github.com/CircleConnectApp/feed-service/memberships"
+	"github.com/CircleConnectApp/feed-service/models"
+	"github.com/CircleConnectApp/feed-service/pins"
+	"github.com/CircleConnectApp/feed-service/seen"
+	"github.com/CircleConnectApp/feed-service/settings"
+	"github.com/CircleConnectApp/feed-service/trending"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// testController returns a FeedController on the mocked database whose
+// user, post and community services are all served by upstream. configure
+// may adjust the runtime settings.
+func testController(mt *mtest.T, upstream http.Handler, configure func(*settings.Settings)) *FeedController {
+	srv := httptest.NewServer(upstream)
+	mt.Cleanup(srv.Close)
+
+	s := settings.Default()
+	if configure != nil {
+		configure(&s)
+	}
+	mt.AddMockResponses(
+		mtest.CreateSuccessResponse(),
+		mtest.CreateCursorResponse(0, "feed.settings", mtest.FirstBatch),
+		mtest.CreateSuccessResponse(), // trending indexes
+		mtest.CreateSuccessResponse(), // pins indexes
+	)
+	settingsStore, err := settings.NewStore(context.Background(), s, "", mt.DB.Collection("settings"), mt.DB.Collection("settings_history"))
+	if err != nil {
+		mt.Fatal(err)
+	}
+	trendingStore, err := trending.NewStore(context.Background(), mt.DB.Collection("trending"))
+	if err != nil {
+		mt.Fatal(err)
+	}
+	pinStore, err := pins.NewStore(context.Background(), mt.DB.Collection("pins"))
+	if err != nil {
+		mt.Fatal(err)
+	}
+
+	// Closed recorders drop what they are given instead of writing it
+	recorder := interactions.NewRecorder(nil, 1)
+	if err := recorder.Close(context.Background()); err != nil {
+		mt.Fatal(err)
+	}
+	seenRecorder := seen.NewRecorder(nil, 1)
+	if err := seenRecorder.Close(context.Background()); err != nil {
+		mt.Fatal(err)
+	}
+	membershipRecorder := memberships.NewRecorder(nil, 1)
+	if err := membershipRecorder.Close(context.Background()); err != nil {
+		mt.Fatal(err)
+	}
+
+	return NewFeedController(mt.DB, nil, srv.URL, srv.URL, srv.URL, "https://circle.example", 5*time.Second, settingsStore, recorder, nil, seenRecorder, nil, membershipRecorder, trendingStore, pinStore)
+}
+
+// writeJSON responds to an upstream request with v
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// testPostID returns a stable post ID for n
+func testPostID(n int) string {
+	id := primitive.NilObjectID
+	id[len(id)-1] = byte(n)
+	id[len(id)-2] = byte(n >> 8)
+	return id.Hex()
+}
+
+// postIDs returns the post IDs of items in order
+func postIDs(items []models.FeedItem) []string {
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.PostID.Hex()
+	}
+	return ids
+}
+
+// postService serves GET /posts over posts the way the post service does:
+// community_id, tags and author_id take comma-separated lists, a post
+// matching any tag; sort=popular orders by likes, otherwise newest first.
+// It logs the query of every request.
+type postService struct {
+	posts []upstreamPost
+
+	mu      sync.Mutex
+	queries []url.Values
+}
+
+func (ps *postService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	ps.mu.Lock()
+	ps.queries = append(ps.queries, query)
+	ps.mu.Unlock()
+
+	in := func(param string, values ...string) bool {
+		if !query.Has(param) {
+			return true
+		}
+		for _, want := range strings.Split(query.Get(param), ",") {
+			for _, v := range values {
+				if v == want {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	var matched []upstreamPost
+	for _, post := range ps.posts {
+		if in("community_id", strconv.Itoa(post.CommunityID)) && in("author_id", strconv.Itoa(post.UserID)) && in("tags", post.Tags...) {
+			matched = append(matched, post)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		if query.Get("sort") == "popular" {
+			return matched[i].LikeCount > matched[j].LikeCount
+		}
+		return matched[i].CreatedAt.After(matched[j].CreatedAt)
+	})
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	start := min((page-1)*limit, len(matched))
+	writeJSON(w, postsResponse{Posts: matched[start:min(start+limit, len(matched))], Total: len(matched)})
+}
+
+// requests returns the logged queries that have param set to value
+func (ps *postService) requests(param, value string) []url.Values {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	var out []url.Values
+	for _, q := range ps.queries {
+		if q.Get(param) == value {
+			out = append(out, q)
+		}
+	}
+	return out
+}
@@ -0,0 +1,314 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/metrics"
+	"github.com/CircleConnectApp/feed-service/models"
+	"github.com/CircleConnectApp/feed-service/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// hydrationCacheTTL bounds how stale author and community details may be
+	hydrationCacheTTL = time.Minute
+	// maxHydrationCacheEntries bounds each hydration cache's memory
+	maxHydrationCacheEntries = 50000
+	// maxBatchIDs bounds the IDs requested from an upstream service in one call
+	maxBatchIDs = 100
+)
+
+// author is a user as returned by the user service's batch endpoint
+type author struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Avatar string `json:"avatar"`
+}
+
+// batchCache caches upstream records by ID for a short time, so that
+// consecutive pages and users seeing the same authors share lookups.
+// Concurrent misses for the same ID share one fetch.
+type batchCache[V any] struct {
+	name     string
+	mu       sync.Mutex
+	entries  map[int]batchEntry[V]
+	inflight map[int]*batchFetch
+}
+
+type batchEntry[V any] struct {
+	value   V
+	found   bool // false for IDs the upstream did not return
+	expires time.Time
+}
+
+// batchFetch is a fetch in progress; done is closed once its records are
+// stored or it failed with err
+type batchFetch struct {
+	done chan struct{}
+	err  error
+}
+
+func newBatchCache[V any](name string) *batchCache[V] {
+	return &batchCache[V]{name: name, entries: make(map[int]batchEntry[V]), inflight: make(map[int]*batchFetch)}
+}
+
+// load returns the records for ids, fetching those not cached in batches of
+// maxBatchIDs and waiting for those another caller is already fetching.
+// Records the upstream does not return are left out, and cached as missing
+// so they are not requested again at once. On error the records loaded so
+// far are returned with it.
+func (bc *batchCache[V]) load(ctx context.Context, ids []int, fetch func(context.Context, []int) (map[int]V, error)) (map[int]V, error) {
+	found := make(map[int]V, len(ids))
+	var missing []int
+	waiting := make(map[*batchFetch][]int)
+	requested := make(map[int]bool, len(ids))
+
+	bc.mu.Lock()
+	now := time.Now()
+	for _, id := range ids {
+		if requested[id] {
+			continue
+		}
+		requested[id] = true
+		entry, ok := bc.entries[id]
+		hit := ok && now.Before(entry.expires)
+		metrics.CacheLookup(bc.name, hit)
+		switch {
+		case hit && entry.found:
+			found[id] = entry.value
+		case hit:
+		case bc.inflight[id] != nil:
+			waiting[bc.inflight[id]] = append(waiting[bc.inflight[id]], id)
+		default:
+			missing = append(missing, id)
+		}
+	}
+	batches := make([]*batchFetch, 0, (len(missing)+maxBatchIDs-1)/maxBatchIDs)
+	for start := 0; start < len(missing); start += maxBatchIDs {
+		f := &batchFetch{done: make(chan struct{})}
+		for _, id := range missing[start:min(start+maxBatchIDs, len(missing))] {
+			bc.inflight[id] = f
+		}
+		batches = append(batches, f)
+	}
+	bc.mu.Unlock()
+
+	var err error
+	for i, f := range batches {
+		batch := missing[i*maxBatchIDs : min((i+1)*maxBatchIDs, len(missing))]
+		var fetched map[int]V
+		if err == nil {
+			fetched, f.err = fetch(ctx, batch)
+			err = f.err
+		} else {
+			f.err = err
+		}
+		for id, value := range fetched {
+			found[id] = value
+		}
+		bc.finish(batch, fetched, f)
+	}
+	if err != nil {
+		return found, err
+	}
+
+	for f, waited := range waiting {
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return found, ctx.Err()
+		}
+		if f.err != nil {
+			return found, f.err
+		}
+		bc.mu.Lock()
+		for _, id := range waited {
+			if entry := bc.entries[id]; entry.found {
+				found[id] = entry.value
+			}
+		}
+		bc.mu.Unlock()
+	}
+	return found, nil
+}
+
+// finish caches the records fetched for ids, unless the fetch failed, and
+// releases the callers waiting for them
+func (bc *batchCache[V]) finish(ids []int, values map[int]V, f *batchFetch) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if f.err == nil {
+		bc.store(ids, values)
+	}
+	for _, id := range ids {
+		delete(bc.inflight, id)
+	}
+	close(f.done)
+}
+
+// store caches the records fetched for ids; the caller must hold mu. When
+// the cache is full, expired entries are dropped first and then arbitrary
+// ones, as few as make room.
+func (bc *batchCache[V]) store(ids []int, values map[int]V) {
+	now := time.Now()
+	if len(bc.entries)+len(ids) > maxHydrationCacheEntries {
+		for id, entry := range bc.entries {
+			if now.After(entry.expires) {
+				delete(bc.entries, id)
+			}
+		}
+		for id := range bc.entries {
+			if len(bc.entries)+len(ids) <= maxHydrationCacheEntries {
+				break
+			}
+			delete(bc.entries, id)
+		}
+	}
+	for _, id := range ids {
+		value, found := values[id]
+		bc.entries[id] = batchEntry[V]{value: value, found: found, expires: now.Add(hydrationCacheTTL)}
+	}
+}
+
+type skipHydrationKey struct{}
+
+// WithoutHydration marks a feed request whose caller does not show authors
+// or communities, so the feeds it builds keep what the post service sent
+func WithoutHydration(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipHydrationKey{}, true)
+}
+
+// hydrate fills in the authors and communities of a page of feed items from
+// the user and community services, with one batched lookup per service.
+// Details that cannot be loaded keep what the post service sent.
+func (fc *FeedController) hydrate(ctx context.Context, items []models.FeedItem) {
+	if len(items) == 0 || ctx.Value(skipHydrationKey{}) != nil {
+		return
+	}
+	ctx, span := tracing.Tracer().Start(ctx, "hydrateFeed")
+	defer span.End()
+
+	userIDs, communityIDs := uniqueIDs(items)
+	span.SetAttributes(attribute.Int("feed.authors", len(userIDs)), attribute.Int("feed.communities", len(communityIDs)))
+
+	var authors map[int]author
+	var communities map[int]community
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		var err error
+		authors, err = fc.authors.load(ctx, userIDs, fc.getAuthors)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to load post authors", "error", err)
+			tracing.RecordError(span, err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		var err error
+		communities, err = fc.communities.load(ctx, communityIDs, fc.getCommunities)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to load post communities", "error", err)
+			tracing.RecordError(span, err)
+		}
+	}()
+	wg.Wait()
+
+	for i := range items {
+		if a, ok := authors[items[i].UserID]; ok {
+			if a.Name != "" {
+				items[i].AuthorName = a.Name
+			}
+			if a.Avatar != "" {
+				items[i].AuthorAvatar = a.Avatar
+			}
+		}
+		if c, ok := communities[items[i].CommunityID]; ok {
+			items[i].CommunityName = c.Name
+			items[i].CommunityIcon = c.Icon
+		}
+	}
+}
+
+// uniqueIDs returns the distinct author and community IDs of items
+func uniqueIDs(items []models.FeedItem) (userIDs, communityIDs []int) {
+	seenUsers := make(map[int]bool, len(items))
+	seenCommunities := make(map[int]bool, len(items))
+	for _, item := range items {
+		if item.UserID > 0 && !seenUsers[item.UserID] {
+			seenUsers[item.UserID] = true
+			userIDs = append(userIDs, item.UserID)
+		}
+		if item.CommunityID > 0 && !seenCommunities[item.CommunityID] {
+			seenCommunities[item.CommunityID] = true
+			communityIDs = append(communityIDs, item.CommunityID)
+		}
+	}
+	return userIDs, communityIDs
+}
+
+// getAuthors retrieves users by ID from the user service
+func (fc *FeedController) getAuthors(ctx context.Context, ids []int) (map[int]author, error) {
+	var result struct {
+		Users []author `json:"users"`
+	}
+	url := fmt.Sprintf("%s/users?ids=%s", fc.config.UserServiceURL, joinIDs(ids))
+	if err := fc.getBatch(ctx, "user", "batch_users", url, &result); err != nil {
+		return nil, err
+	}
+
+	authors := make(map[int]author, len(result.Users))
+	for _, a := range result.Users {
+		authors[a.ID] = a
+	}
+	return authors, nil
+}
+
+// getCommunities retrieves communities by ID from the community service
+func (fc *FeedController) getCommunities(ctx context.Context, ids []int) (map[int]community, error) {
+	var result struct {
+		Communities []community `json:"communities"`
+	}
+	url := fmt.Sprintf("%s/communities?ids=%s", fc.config.CommunityServiceURL, joinIDs(ids))
+	if err := fc.getBatch(ctx, "community", "batch_communities", url, &result); err != nil {
+		return nil, err
+	}
+
+	communities := make(map[int]community, len(result.Communities))
+	for _, c := range result.Communities {
+		communities[c.ID] = c
+	}
+	return communities, nil
+}
+
+// getBatch performs a batch lookup against an upstream service and decodes
+// the response into out
+func (fc *FeedController) getBatch(ctx context.Context, service, operation, url string, out interface{}) error {
+	resp, err := fc.getUpstream(ctx, service, operation, url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to %s: status %d", strings.ReplaceAll(operation, "_", " "), resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func joinIDs(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/models"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// fetchRecorder is a batch fetch returning "name-<id>" for IDs below 1000
+// and logging the batches it was asked for
+type fetchRecorder struct {
+	mu      sync.Mutex
+	batches [][]int
+	err     error
+}
+
+func (f *fetchRecorder) fetch(ctx context.Context, ids []int) (map[int]string, error) {
+	f.mu.Lock()
+	f.batches = append(f.batches, append([]int(nil), ids...))
+	f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	values := make(map[int]string, len(ids))
+	for _, id := range ids {
+		if id < 1000 {
+			values[id] = "name-" + strconv.Itoa(id)
+		}
+	}
+	return values, nil
+}
+
+func TestBatchCacheLoad(t *testing.T) {
+	manyIDs := make([]int, maxBatchIDs+1)
+	for i := range manyIDs {
+		manyIDs[i] = i + 1
+	}
+
+	tests := []struct {
+		name        string
+		cached      []int // IDs loaded beforehand
+		ids         []int
+		err         error
+		want        []int
+		wantBatches [][]int
+		wantErr     bool
+	}{
+		{name: "misses are fetched together", ids: []int{1, 2, 3}, want: []int{1, 2, 3}, wantBatches: [][]int{{1, 2, 3}}},
+		{name: "hits are not fetched", cached: []int{1, 2}, ids: []int{1, 2, 3}, want: []int{1, 2, 3}, wantBatches: [][]int{{3}}},
+		{name: "duplicates are fetched once", ids: []int{1, 1, 2}, want: []int{1, 2}, wantBatches: [][]int{{1, 2}}},
+		{name: "unknown IDs are cached as missing", cached: []int{1000}, ids: []int{1000, 1}, want: []int{1}, wantBatches: [][]int{{1}}},
+		{name: "large lookups are batched", ids: manyIDs, want: manyIDs, wantBatches: [][]int{manyIDs[:maxBatchIDs], manyIDs[maxBatchIDs:]}},
+		{name: "failed fetches return the hits", cached: []int{1}, ids: []int{1, 2}, err: errors.New("unavailable"), want: []int{1}, wantBatches: [][]int{{2}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := newBatchCache[string]("test")
+			if len(tt.cached) > 0 {
+				if _, err := bc.load(context.Background(), tt.cached, (&fetchRecorder{}).fetch); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			f := &fetchRecorder{err: tt.err}
+			got, err := bc.load(context.Background(), tt.ids, f.fetch)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(f.batches, tt.wantBatches) {
+				t.Errorf("batches = %v, want %v", f.batches, tt.wantBatches)
+			}
+			ids := make([]int, 0, len(got))
+			for id, value := range got {
+				if value != "name-"+strconv.Itoa(id) {
+					t.Errorf("record %d = %q", id, value)
+				}
+				ids = append(ids, id)
+			}
+			sort.Ints(ids)
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("loaded %v, want %v", ids, tt.want)
+			}
+			if len(bc.inflight) != 0 {
+				t.Errorf("%d fetches left in flight", len(bc.inflight))
+			}
+		})
+	}
+}
+
+func TestBatchCacheSharesConcurrentMisses(t *testing.T) {
+	bc := newBatchCache[string]("test")
+	release := make(chan struct{})
+	var calls atomic.Int32
+	fetch := func(ctx context.Context, ids []int) (map[int]string, error) {
+		calls.Add(1)
+		<-release
+		return map[int]string{1: "one"}, nil
+	}
+
+	const callers = 5
+	results := make(chan map[int]string, callers)
+	started := make(chan struct{}, callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			started <- struct{}{}
+			got, _ := bc.load(context.Background(), []int{1}, fetch)
+			results <- got
+		}()
+	}
+	for i := 0; i < callers; i++ {
+		<-started
+	}
+	// Give every caller time to find the fetch in flight
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	for i := 0; i < callers; i++ {
+		if got := <-results; got[1] != "one" {
+			t.Errorf("caller got %v", got)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("fetched %d times, want once", n)
+	}
+}
+
+func TestBatchCacheEvictsWhenFull(t *testing.T) {
+	bc := newBatchCache[string]("test")
+	now := time.Now()
+	for id := 0; id < maxHydrationCacheEntries; id++ {
+		expires := now.Add(hydrationCacheTTL)
+		if id < 10 {
+			expires = now.Add(-time.Second)
+		}
+		bc.entries[id] = batchEntry[string]{found: true, expires: expires}
+	}
+
+	newIDs := make([]int, 25)
+	for i := range newIDs {
+		newIDs[i] = maxHydrationCacheEntries + i
+	}
+	bc.store(newIDs, nil)
+
+	if len(bc.entries) != maxHydrationCacheEntries {
+		t.Errorf("cache holds %d entries, want it full at %d", len(bc.entries), maxHydrationCacheEntries)
+	}
+	for id := 0; id < 10; id++ {
+		if _, ok := bc.entries[id]; ok {
+			t.Errorf("expired entry %d was kept", id)
+		}
+	}
+	for _, id := range newIDs {
+		if _, ok := bc.entries[id]; !ok {
+			t.Errorf("new entry %d was not stored", id)
+		}
+	}
+}
+
+func TestPosts(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("hydrates the posts together", func(mt *mtest.T) {
+		var userLookups, communityLookups atomic.Int32
+		upstream := http.NewServeMux()
+		upstream.HandleFunc("/posts/", func(w http.ResponseWriter, r *http.Request) {
+			id := strings.TrimPrefix(r.URL.Path, "/posts/")
+			if id == testPostID(3) {
+				http.NotFound(w, r)
+				return
+			}
+			writeJSON(w, upstreamPost{ID: id, UserID: 7, CommunityID: 10, UserName: "stale"})
+		})
+		upstream.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+			userLookups.Add(1)
+			writeJSON(w, map[string]interface{}{"users": []author{{ID: 7, Name: "Ada"}}})
+		})
+		upstream.HandleFunc("/communities", func(w http.ResponseWriter, r *http.Request) {
+			communityLookups.Add(1)
+			writeJSON(w, map[string]interface{}{"communities": []community{{ID: 10, Name: "Go"}}})
+		})
+		fc := testController(mt, upstream, nil)
+
+		posts := fc.Posts(context.Background(), []string{testPostID(1), testPostID(2), testPostID(1), testPostID(3), "invalid"})
+
+		ids := make([]string, 0, len(posts))
+		for id, post := range posts {
+			ids = append(ids, id)
+			if post.PostID.Hex() != id || post.AuthorName != "Ada" || post.CommunityName != "Go" {
+				mt.Errorf("post %s = %+v, want it hydrated", id, post)
+			}
+		}
+		sort.Strings(ids)
+		if want := []string{testPostID(1), testPostID(2)}; !reflect.DeepEqual(ids, want) {
+			mt.Errorf("posts = %v, want %v", ids, want)
+		}
+		if userLookups.Load() != 1 || communityLookups.Load() != 1 {
+			mt.Errorf("%d user and %d community lookups, want one of each", userLookups.Load(), communityLookups.Load())
+		}
+	})
+}
+
+func TestHydrateWithoutHydration(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("keeps what the post service sent", func(mt *mtest.T) {
+		var lookups atomic.Int32
+		upstream := http.NewServeMux()
+		upstream.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+			lookups.Add(1)
+			writeJSON(w, map[string]interface{}{"users": []author{{ID: 7, Name: "Ada"}}})
+		})
+		upstream.HandleFunc("/communities", func(w http.ResponseWriter, r *http.Request) {
+			lookups.Add(1)
+			writeJSON(w, map[string]interface{}{"communities": []community{{ID: 10, Name: "Go"}}})
+		})
+		fc := testController(mt, upstream, nil)
+
+		items := []models.FeedItem{{UserID: 7, CommunityID: 10, AuthorName: "stale"}}
+		fc.hydrate(WithoutHydration(context.Background()), items)
+
+		if lookups.Load() != 0 {
+			mt.Errorf("%d lookups, want none", lookups.Load())
+		}
+		if items[0].AuthorName != "stale" || items[0].CommunityName != "" {
+			mt.Errorf("item = %+v, want it as sent", items[0])
+		}
+
+		fc.hydrate(context.Background(), items)
+		if items[0].AuthorName != "Ada" || items[0].CommunityName != "Go" {
+			mt.Errorf("item = %+v, want it hydrated", items[0])
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/CircleConnectApp/feed-service/feedview"
+	"github.com/CircleConnectApp/feed-service/models"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/ugorji/go/codec"
+)
+
+// feedPage is a feed page with items reduced to the requested view
+type feedPage struct {
+	Items       []map[string]interface{} `json:"items"`
+	Total       int                      `json:"total"`
+	Page        int                      `json:"page"`
+	Limit       int                      `json:"limit"`
+	Experiments map[string]string        `json:"experiments,omitempty"`
+}
+
+// msgpackHandle encodes with the current MessagePack spec: str and bin are
+// distinct and times use the timestamp extension
+var msgpackHandle = &codec.MsgpackHandle{WriteExt: true}
+
+// msgpackRender writes MessagePack. gin's own renderer uses the old spec,
+// which most clients decode strings and times from poorly.
+type msgpackRender struct {
+	data interface{}
+}
+
+func (r msgpackRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return codec.NewEncoder(w, msgpackHandle).Encode(r.data)
+}
+
+func (r msgpackRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", binding.MIMEMSGPACK2)
+}
+
+// bindView reads the fields and content_preview parameters, writing a 400
+// response when they are invalid
+func bindView(c *gin.Context) (*feedview.View, bool) {
+	var query models.FeedViewQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	view, err := feedview.New(query.Fields, query.ContentPreview)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "fields": feedview.Fields()})
+		return nil, false
+	}
+	return view, true
+}
+
+// respondFeed writes a feed page in the view, as MessagePack when the client
+// accepts it and JSON otherwise
+func respondFeed(c *gin.Context, feed *models.Feed, view *feedview.View) {
+	format := c.NegotiateFormat(binding.MIMEJSON, binding.MIMEMSGPACK2, binding.MIMEMSGPACK)
+	msgpack := format == binding.MIMEMSGPACK2 || format == binding.MIMEMSGPACK
+	c.Writer.Header().Add("Vary", "Accept")
+
+	if !msgpack && view.Full() {
+		c.JSON(http.StatusOK, feed)
+		return
+	}
+
+	page := feedPage{
+		Items:       view.Items(feed.Items),
+		Total:       feed.Total,
+		Page:        feed.Page,
+		Limit:       feed.Limit,
+		Experiments: feed.Experiments,
+	}
+	if msgpack {
+		c.Render(http.StatusOK, msgpackRender{page})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/CircleConnectApp/feed-service/settings"
+	"github.com/gin-gonic/gin"
+)
+
+type SettingsController struct {
+	store *settings.Store
+}
+
+// NewSettingsController creates a new instance of SettingsController
+func NewSettingsController(store *settings.Store) *SettingsController {
+	return &SettingsController{store: store}
+}
+
+// GetSettings returns the effective runtime settings and the overrides saved through the API
+func (sc *SettingsController) GetSettings(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"effective": sc.store.Current(),
+		"overrides": sc.store.Overrides(),
+	})
+}
+
+// UpdateSettings applies a JSON merge patch to the runtime setting overrides
+func (sc *SettingsController) UpdateSettings(c *gin.Context) {
+	var patch map[string]interface{}
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	actor := fmt.Sprintf("user:%v", userID)
+
+	snap, err := sc.store.Update(c.Request.Context(), patch, actor)
+	if err != nil {
+		if errors.Is(err, settings.ErrConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Settings were modified concurrently, please retry"})
+			return
+		}
+		var validationErr *settings.ValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"effective": snap,
+		"overrides": sc.store.Overrides(),
+	})
+}
+
+// GetSettingsHistory returns the audit history of settings changes, newest first
+func (sc *SettingsController) GetSettingsHistory(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+		return
+	}
+
+	changes, err := sc.store.History(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get settings history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"changes": changes})
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/models"
+	"github.com/CircleConnectApp/feed-service/syndication"
+	"github.com/gin-gonic/gin"
+)
+
+// syndicationFormat renders a feed in one export format
+type syndicationFormat struct {
+	contentType string
+	render      func(syndication.Channel, []models.FeedItem) ([]byte, error)
+}
+
+// GetRSSFeed exports the user's feed, or a community's, as RSS 2.0
+func (fc *FeedController) GetRSSFeed(c *gin.Context) {
+	fc.exportFeed(c, syndicationFormat{syndication.RSSContentType, syndication.RSS})
+}
+
+// GetAtomFeed exports the user's feed, or a community's, as Atom
+func (fc *FeedController) GetAtomFeed(c *gin.Context) {
+	fc.exportFeed(c, syndicationFormat{syndication.AtomContentType, syndication.Atom})
+}
+
+// GetJSONFeed exports the user's feed, or a community's, as JSON Feed 1.1
+func (fc *FeedController) GetJSONFeed(c *gin.Context) {
+	fc.exportFeed(c, syndicationFormat{syndication.JSONFeedContentType, syndication.JSONFeed})
+}
+
+// exportFeed builds the newest page of a feed and renders it. Exports are
+// polled by feed readers rather than viewed, so they record no impressions.
+func (fc *FeedController) exportFeed(c *gin.Context, format syndicationFormat) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var query models.SyndicationQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page := 1
+	fc.applyPageLimits(&page, &query.Limit)
+
+	ctx, cancel := fc.withBudget(c.Request.Context())
+	defer cancel()
+	channel := syndication.Channel{
+		FeedURL: requestURL(c),
+		PostURL: func(item models.FeedItem) string {
+			return fmt.Sprintf("%s/posts/%s", fc.config.WebAppURL, item.PostID.Hex())
+		},
+	}
+
+	var feed *models.Feed
+	if query.CommunityID > 0 {
+		info, err := fc.viewableCommunity(ctx, userID.(int), query.CommunityID)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+
+		feed, err = fc.buildCommunityFeed(ctx, query.CommunityID, models.CommunityFeedQuery{Sort: "new", Page: page, Limit: query.Limit})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build community feed"})
+			return
+		}
+		channel.Title = info.Name
+		channel.Description = fmt.Sprintf("Newest posts in %s", info.Name)
+		channel.Link = fmt.Sprintf("%s/communities/%d", fc.config.WebAppURL, query.CommunityID)
+	} else {
+		in, err := fc.loadInputs(ctx, userID.(int), inputs{communities: true})
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+
+		// Readers order entries by date, so export the newest posts
+		feed, err = fc.buildFeed(ctx, userID.(int), in.communities, in.pref, models.FeedQuery{SortBy: "date", Page: page, Limit: query.Limit})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build feed"})
+			return
+		}
+		channel.Title = "CircleConnect feed"
+		channel.Description = "Newest posts from your communities"
+		channel.Link = fc.config.WebAppURL + "/feed"
+	}
+
+	fc.hydrate(ctx, feed.Items)
+
+	// The feed was last updated when its newest post was created
+	for _, item := range feed.Items {
+		if item.CreatedAt.After(channel.Updated) {
+			channel.Updated = item.CreatedAt
+		}
+	}
+	if channel.Updated.IsZero() {
+		channel.Updated = time.Now()
+	}
+
+	body, err := format.render(channel, feed.Items)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render feed"})
+		return
+	}
+
+	// Feed URLs carry credentials, so keep responses out of shared caches
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Data(http.StatusOK, format.contentType, body)
+}
+
+// requestURL reconstructs the URL a request was made to, for feeds' self
+// links. The feed token is left out so it is not published in the feed.
+func requestURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	u := *c.Request.URL
+	query := u.Query()
+	if query.Has("token") {
+		query.Del("token")
+		u.RawQuery = query.Encode()
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, c.Request.Host, u.RequestURI())
+}
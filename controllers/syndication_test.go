@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestURL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		target string
+		proto  string
+		want   string
+	}{
+		{name: "no query", target: "/api/feed.rss", want: "http://feed.example/api/feed.rss"},
+		{name: "keeps other parameters", target: "/api/feed.atom?community_id=3&limit=10", want: "http://feed.example/api/feed.atom?community_id=3&limit=10"},
+		{name: "drops the token", target: "/api/feed.json?token=secret&limit=10", want: "http://feed.example/api/feed.json?limit=10"},
+		{name: "drops only the token", target: "/api/feed.rss?token=secret", want: "http://feed.example/api/feed.rss"},
+		{name: "https behind a proxy", target: "/api/feed.rss?token=secret", proto: "https", want: "https://feed.example/api/feed.rss"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "http://feed.example"+tt.target, nil)
+			if tt.proto != "" {
+				c.Request.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+			if got := requestURL(c); got != tt.want {
+				t.Errorf("requestURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
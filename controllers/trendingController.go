@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/models"
+	"github.com/CircleConnectApp/feed-service/settings"
+	"github.com/CircleConnectApp/feed-service/trending"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultTrendingWindow is used when a request names no window
+const defaultTrendingWindow = "24h"
+
+type TrendingController struct {
+	store    *trending.Store
+	settings *settings.Store
+}
+
+// trendingQuery represents query parameters for the trending endpoints
+type trendingQuery struct {
+	Window      string `form:"window"` // 1h, 24h or 7d
+	CommunityID int    `form:"community_id"`
+	Limit       int    `form:"limit"`
+}
+
+// NewTrendingController creates a new instance of TrendingController
+func NewTrendingController(store *trending.Store, settingsStore *settings.Store) *TrendingController {
+	return &TrendingController{store: store, settings: settingsStore}
+}
+
+// GetTrendingPosts returns the posts gaining likes fastest, globally or in one community
+func (tc *TrendingController) GetTrendingPosts(c *gin.Context) {
+	var query trendingQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if query.Window == "" {
+		query.Window = defaultTrendingWindow
+	}
+
+	posts, err := tc.TrendingPosts(c.Request.Context(), query.Window, query.CommunityID, query.Limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"window": query.Window, "posts": posts})
+}
+
+// GetTrendingTags returns the tags whose posts are gaining likes fastest
+func (tc *TrendingController) GetTrendingTags(c *gin.Context) {
+	var query trendingQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if query.Window == "" {
+		query.Window = defaultTrendingWindow
+	}
+
+	tags, err := tc.TrendingTags(c.Request.Context(), query.Window, query.CommunityID, query.Limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"window": query.Window, "tags": tags})
+}
+
+// TrendingPosts returns the top trending posts in a window, globally or in
+// one community
+func (tc *TrendingController) TrendingPosts(ctx context.Context, window string, communityID, limit int) ([]trending.Post, error) {
+	duration, limit, err := tc.resolveQuery(window, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	posts, err := tc.store.Posts(ctx, duration, communityID, limit)
+	if err != nil {
+		return nil, fail(http.StatusInternalServerError, "Failed to get trending posts")
+	}
+	return posts, nil
+}
+
+// TrendingTags returns the top trending tags in a window, globally or in one
+// community
+func (tc *TrendingController) TrendingTags(ctx context.Context, window string, communityID, limit int) ([]trending.Tag, error) {
+	duration, limit, err := tc.resolveQuery(window, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := tc.store.Tags(ctx, duration, communityID, limit)
+	if err != nil {
+		return nil, fail(http.StatusInternalServerError, "Failed to get trending tags")
+	}
+	return tags, nil
+}
+
+// IngestPostEvents records likes and unlikes reported by the post service
+func (tc *TrendingController) IngestPostEvents(c *gin.Context) {
+	var req models.PostEventsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	accepted, err := tc.RecordPostEvents(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"accepted": accepted})
+}
+
+// RecordPostEvents adds post events to the trending counters, returning how
+// many were accepted
+func (tc *TrendingController) RecordPostEvents(ctx context.Context, req models.PostEventsRequest) (int, error) {
+	events := make([]trending.Event, 0, len(req.Events))
+	for _, e := range req.Events {
+		events = append(events, trending.Event{
+			Type:        e.Type,
+			PostID:      e.PostID,
+			CommunityID: e.CommunityID,
+			AuthorID:    e.AuthorID,
+			Tags:        e.Tags,
+			OccurredAt:  e.OccurredAt,
+		})
+	}
+
+	if err := tc.store.Record(ctx, events); err != nil {
+		return 0, fail(http.StatusInternalServerError, "Failed to record post events")
+	}
+	return len(events), nil
+}
+
+// resolveQuery defaults and validates the trending window and limit
+func (tc *TrendingController) resolveQuery(window string, limit int) (time.Duration, int, error) {
+	if window == "" {
+		window = defaultTrendingWindow
+	}
+	duration, ok := trending.Windows[window]
+	if !ok {
+		return 0, 0, fail(http.StatusBadRequest, "window must be one of 1h, 24h or 7d")
+	}
+
+	limits := tc.settings.Current().Limits
+	if limit <= 0 {
+		limit = limits.DefaultPageSize
+	}
+	if limit > limits.MaxPageSize {
+		limit = limits.MaxPageSize
+	}
+
+	return duration, limit, nil
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/CircleConnectApp/feed-service/metrics"
+	"github.com/CircleConnectApp/feed-service/models"
+	"github.com/CircleConnectApp/feed-service/ranking"
+	"github.com/CircleConnectApp/feed-service/tracing"
+	"github.com/CircleConnectApp/feed-service/trending"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// maxHomeTrending bounds the trending posts the home feed pages through;
+	// later pages get none
+	maxHomeTrending = 100
+	// maxPostFetches bounds concurrent single-post lookups
+	maxPostFetches = 8
+)
+
+// buildTrendingFeed constructs a page of the posts trending in the last
+// day, in trending order, for the home feed. The user's own posts and posts
+// in private communities the user has not joined are left out.
+func (fc *FeedController) buildTrendingFeed(ctx context.Context, userID int, joinedCommunities []int, query models.FeedQuery) (feed *models.Feed, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "buildTrendingFeed")
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	feed = &models.Feed{Items: []models.FeedItem{}, Page: query.Page, Limit: query.Limit}
+	offset := (query.Page - 1) * query.Limit
+	if offset >= maxHomeTrending {
+		return feed, nil
+	}
+
+	posts, err := fc.trending.Posts(ctx, trending.Windows[defaultTrendingWindow], 0, maxHomeTrending)
+	if err != nil {
+		return nil, err
+	}
+
+	communityIDs := make([]int, 0, len(posts))
+	for _, post := range posts {
+		if post.CommunityID > 0 {
+			communityIDs = append(communityIDs, post.CommunityID)
+		}
+	}
+	communities, err := fc.communities.load(ctx, communityIDs, fc.getCommunities)
+	if err != nil {
+		return nil, err
+	}
+	joined := make(map[int]bool, len(joinedCommunities))
+	for _, communityID := range joinedCommunities {
+		joined[communityID] = true
+	}
+
+	visible := make([]trending.Post, 0, len(posts))
+	for _, post := range posts {
+		if post.AuthorID == userID {
+			metrics.FeedItemsFiltered.WithLabelValues("trending", "own_post").Inc()
+			continue
+		}
+		if c, ok := communities[post.CommunityID]; post.CommunityID > 0 && (!ok || c.IsPrivate && !joined[post.CommunityID]) {
+			metrics.FeedItemsFiltered.WithLabelValues("trending", "private_community").Inc()
+			continue
+		}
+		visible = append(visible, post)
+	}
+	feed.Total = len(visible)
+	if offset >= len(visible) {
+		return feed, nil
+	}
+	visible = visible[offset:min(offset+query.Limit, len(visible))]
+
+	ids := make([]string, len(visible))
+	for i, post := range visible {
+		ids[i] = post.PostID
+	}
+	fetched := fc.getPosts(ctx, ids)
+	span.SetAttributes(attribute.Int("feed.trending", len(fetched)))
+	for _, post := range visible {
+		upstream, ok := fetched[post.PostID]
+		if !ok {
+			continue
+		}
+		postID, err := primitive.ObjectIDFromHex(post.PostID)
+		if err != nil {
+			metrics.FeedItemsFiltered.WithLabelValues("trending", "invalid_post_id").Inc()
+			continue
+		}
+		item := upstream.toFeedItem(postID)
+		item.Signals = ranking.Signals{LikeCount: upstream.LikeCount, CreatedAt: upstream.CreatedAt}
+		feed.Items = append(feed.Items, item)
+	}
+	return feed, nil
+}
+
+// getPosts fetches posts from the post service concurrently. Posts that
+// cannot be fetched are left out.
+func (fc *FeedController) getPosts(ctx context.Context, ids []string) map[string]*upstreamPost {
+	posts := make(map[string]*upstreamPost, len(ids))
+	var mu sync.Mutex
+	sem := make(chan struct{}, maxPostFetches)
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(id string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			post, err := fc.getPost(ctx, id)
+			if err != nil {
+				slog.WarnContext(ctx, "Failed to get post", "post_id", id, "error", err)
+				return
+			}
+			mu.Lock()
+			posts[id] = post
+			mu.Unlock()
+		}(id)
+	}
+	wg.Wait()
+	return posts
+}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/CircleConnectApp/feed-service/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestBuildTrendingFeed(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	// Posts 1-6 trend in this order: 2 is the user's own, 3 is in a private
+	// community the user has not joined, 4 in one the user has, 5 in one the
+	// community service does not know and 6 fails to load
+	trendingPosts := []bson.D{
+		{{Key: "_id", Value: testPostID(1)}, {Key: "community_id", Value: 10}, {Key: "author_id", Value: 2}, {Key: "likes", Value: 9}},
+		{{Key: "_id", Value: testPostID(2)}, {Key: "community_id", Value: 10}, {Key: "author_id", Value: 1}, {Key: "likes", Value: 8}},
+		{{Key: "_id", Value: testPostID(3)}, {Key: "community_id", Value: 20}, {Key: "author_id", Value: 2}, {Key: "likes", Value: 7}},
+		{{Key: "_id", Value: testPostID(4)}, {Key: "community_id", Value: 30}, {Key: "author_id", Value: 2}, {Key: "likes", Value: 6}},
+		{{Key: "_id", Value: testPostID(5)}, {Key: "community_id", Value: 40}, {Key: "author_id", Value: 2}, {Key: "likes", Value: 5}},
+		{{Key: "_id", Value: testPostID(6)}, {Key: "community_id", Value: 10}, {Key: "author_id", Value: 2}, {Key: "likes", Value: 4}},
+	}
+
+	upstream := http.NewServeMux()
+	upstream.HandleFunc("/communities", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{"communities": []community{
+			{ID: 10, Name: "public"},
+			{ID: 20, Name: "private", IsPrivate: true},
+			{ID: 30, Name: "joined", IsPrivate: true},
+		}})
+	})
+	upstream.HandleFunc("/posts/", func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/posts/")
+		if id == testPostID(6) {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		writeJSON(w, upstreamPost{ID: id, UserID: 2, Title: "post " + id, LikeCount: 3})
+	})
+
+	tests := []struct {
+		name      string
+		page      int
+		limit     int
+		wantPosts []string
+	}{
+		{name: "first page", page: 1, limit: 2, wantPosts: []string{testPostID(1), testPostID(4)}},
+		{name: "posts that fail to load are left out", page: 2, limit: 2, wantPosts: []string{}},
+		{name: "past the trending posts", page: 3, limit: 2, wantPosts: []string{}},
+		{name: "past the trending limit", page: 51, limit: 2, wantPosts: []string{}},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			fc := testController(mt, upstream, nil)
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, "feed.trending", mtest.FirstBatch, trendingPosts...))
+
+			feed, err := fc.buildTrendingFeed(context.Background(), 1, []int{30}, models.FeedQuery{Page: tt.page, Limit: tt.limit})
+			if err != nil {
+				mt.Fatal(err)
+			}
+			if got := postIDs(feed.Items); !reflect.DeepEqual(got, tt.wantPosts) {
+				mt.Errorf("posts = %v, want %v", got, tt.wantPosts)
+			}
+			if tt.page < 51 && feed.Total != 3 {
+				mt.Errorf("total = %d, want the 3 visible trending posts", feed.Total)
+			}
+		})
+	}
+}
@@ -5,22 +5,36 @@ import (
 	"database/sql"
 	"time"
 
+	"github.com/CircleConnectApp/feed-service/metrics"
 	_ "github.com/lib/pq"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
 const (
 	FeedCollection        = "feeds"
 	PreferencesCollection = "preferences"
+	RateLimitCollection   = "rate_limits"
+	SettingsCollection    = "settings"
+	SettingsHistory       = "settings_history"
+	TrendingCollection    = "trending_buckets"
+	PinsCollection        = "community_pins"
+	FeedTokensCollection  = "feed_tokens"
+	SeenPostsCollection   = "seen_posts"
+	MembershipsCollection = "community_memberships"
 )
 
 func ConnectMongoDB(mongoURI string) (*mongo.Client, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI).SetMonitor(chainMonitors(
+		metrics.MongoMonitor(),
+		otelmongo.NewMonitor(),
+	)))
 	if err != nil {
 		return nil, err
 	}
@@ -38,9 +52,78 @@ func ConnectPostgres(postgresURI string) (*sql.DB, error) {
 		return nil, err
 	}
 
-	if err = db.Ping(); err != nil {
+	start := time.Now()
+	err = db.Ping()
+	metrics.ObserveDB("postgres", "ping", start, err)
+	if err != nil {
 		return nil, err
 	}
 
 	return db, nil
 }
+
+// chainMonitors fans command events out to several monitors, since the driver
+// accepts only one
+func chainMonitors(monitors ...*event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			for _, m := range monitors {
+				if m.Started != nil {
+					m.Started(ctx, e)
+				}
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			for _, m := range monitors {
+				if m.Succeeded != nil {
+					m.Succeeded(ctx, e)
+				}
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			for _, m := range monitors {
+				if m.Failed != nil {
+					m.Failed(ctx, e)
+				}
+			}
+		},
+	}
+}
+
+// postgresSchema creates the tables owned by the feed service. Statements are
+// idempotent so they run on every start.
+var postgresSchema = []string{
+	`CREATE TABLE IF NOT EXISTS feed_interactions (
+		id BIGSERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL,
+		post_id TEXT NOT NULL,
+		event_type TEXT NOT NULL,
+		feed TEXT NOT NULL DEFAULT '',
+		position INTEGER,
+		request_id TEXT NOT NULL DEFAULT '',
+		experiments JSONB NOT NULL DEFAULT '{}',
+		features JSONB,
+		occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS feed_interactions_user_time_idx ON feed_interactions (user_id, occurred_at)`,
+	`CREATE INDEX IF NOT EXISTS feed_interactions_type_time_idx ON feed_interactions (event_type, occurred_at)`,
+	`CREATE TABLE IF NOT EXISTS experiment_exposures (
+		experiment TEXT NOT NULL,
+		variant TEXT NOT NULL,
+		user_id INTEGER NOT NULL,
+		exposures BIGINT NOT NULL DEFAULT 0,
+		first_exposed_at TIMESTAMPTZ NOT NULL,
+		last_exposed_at TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (experiment, variant, user_id)
+	)`,
+}
+
+// MigratePostgres creates the service's PostgreSQL tables if they do not exist
+func MigratePostgres(ctx context.Context, db *sql.DB) error {
+	for _, stmt := range postgresSchema {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package diversity
+
+import (
+	"hash/fnv"
+	"strings"
+	"unicode"
+
+	"github.com/CircleConnectApp/feed-service/models"
+	"github.com/CircleConnectApp/feed-service/settings"
+)
+
+// Reasons items are removed, used as metric labels
+const (
+	Duplicate     = "duplicate"
+	NearDuplicate = "near_duplicate"
+	AuthorCap     = "author_cap"
+	CommunityCap  = "community_cap"
+)
+
+// Rerank applies the diversity rules to a ranked page. Items keep their
+// ranked order except where that would put more than the allowed number of
+// items from one author or community in a row, in which case the best-ranked
+// item that breaks the run moves up. Exact and near duplicates and items over
+// the per-page caps are removed; the returned counts say how many and why.
+func Rerank(items []models.FeedItem, rules settings.DiversityRules) ([]models.FeedItem, map[string]int) {
+	dropped := map[string]int{}
+	candidates := make([]models.FeedItem, 0, len(items))
+
+	seen := make(map[string]bool, len(items))
+	var shingleSets []map[uint64]struct{}
+	perAuthor := map[int]int{}
+	perCommunity := map[int]int{}
+
+	for _, item := range items {
+		key := item.PostID.Hex()
+		if seen[key] {
+			dropped[Duplicate]++
+			continue
+		}
+		seen[key] = true
+
+		if rules.NearDuplicateThreshold > 0 {
+			shingles := Shingles(item.Title+" "+item.Content, rules.ShingleSize)
+			duplicate := false
+			for _, other := range shingleSets {
+				if Jaccard(shingles, other) >= rules.NearDuplicateThreshold {
+					duplicate = true
+					break
+				}
+			}
+			if duplicate {
+				dropped[NearDuplicate]++
+				continue
+			}
+			shingleSets = append(shingleSets, shingles)
+		}
+
+		if rules.MaxPerAuthor > 0 && perAuthor[item.UserID] >= rules.MaxPerAuthor {
+			dropped[AuthorCap]++
+			continue
+		}
+		if rules.MaxPerCommunity > 0 && perCommunity[item.CommunityID] >= rules.MaxPerCommunity {
+			dropped[CommunityCap]++
+			continue
+		}
+		perAuthor[item.UserID]++
+		perCommunity[item.CommunityID]++
+
+		candidates = append(candidates, item)
+	}
+
+	return spread(candidates, rules), dropped
+}
+
+// spread reorders items so runs from one author or community respect the
+// consecutive limits where the remaining items allow it
+func spread(items []models.FeedItem, rules settings.DiversityRules) []models.FeedItem {
+	if rules.MaxConsecutiveAuthor <= 0 && rules.MaxConsecutiveCommunity <= 0 {
+		return items
+	}
+
+	out := make([]models.FeedItem, 0, len(items))
+	remaining := items
+	for len(remaining) > 0 {
+		pick := 0
+		for i, item := range remaining {
+			if !extendsRun(out, item, rules) {
+				pick = i
+				break
+			}
+		}
+		out = append(out, remaining[pick])
+		remaining = append(remaining[:pick:pick], remaining[pick+1:]...)
+	}
+	return out
+}
+
+// extendsRun reports whether appending item would exceed a consecutive limit
+func extendsRun(out []models.FeedItem, item models.FeedItem, rules settings.DiversityRules) bool {
+	authorRun, communityRun := 0, 0
+	for i := len(out) - 1; i >= 0 && out[i].UserID == item.UserID; i-- {
+		authorRun++
+	}
+	for i := len(out) - 1; i >= 0 && out[i].CommunityID == item.CommunityID; i-- {
+		communityRun++
+	}
+	return (rules.MaxConsecutiveAuthor > 0 && authorRun >= rules.MaxConsecutiveAuthor) ||
+		(rules.MaxConsecutiveCommunity > 0 && communityRun >= rules.MaxConsecutiveCommunity)
+}
+
+// Shingles returns the hashed word n-grams of text, ignoring case and
+// punctuation. Text shorter than size yields a single shingle of all its words.
+func Shingles(text string, size int) map[uint64]struct{} {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if size < 1 {
+		size = 1
+	}
+	if len(words) < size {
+		size = len(words)
+	}
+
+	shingles := make(map[uint64]struct{})
+	for i := 0; size > 0 && i+size <= len(words); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:i+size], " ")))
+		shingles[h.Sum64()] = struct{}{}
+	}
+	return shingles
+}
+
+// Jaccard returns the similarity of two shingle sets, from 0 to 1
+func Jaccard(a, b map[uint64]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	shared := 0
+	for s := range a {
+		if _, ok := b[s]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
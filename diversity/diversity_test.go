@@ -0,0 +1,162 @@
+package diversity
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/CircleConnectApp/feed-service/models"
+	"github.com/CircleConnectApp/feed-service/settings"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// item is a post written as "post/author/community", with optional title text
+type item struct {
+	post, author, community int
+	title                   string
+}
+
+func feedItems(specs ...item) []models.FeedItem {
+	items := make([]models.FeedItem, len(specs))
+	for i, s := range specs {
+		var id primitive.ObjectID
+		id[len(id)-1] = byte(s.post)
+		title := s.title
+		if title == "" {
+			title = fmt.Sprintf("unique title number %d", s.post)
+		}
+		items[i] = models.FeedItem{PostID: id, UserID: s.author, CommunityID: s.community, Title: title}
+	}
+	return items
+}
+
+func posts(items []models.FeedItem) []int {
+	out := make([]int, len(items))
+	for i, item := range items {
+		out[i] = int(item.PostID[len(item.PostID)-1])
+	}
+	return out
+}
+
+func TestRerank(t *testing.T) {
+	tests := []struct {
+		name        string
+		items       []models.FeedItem
+		rules       settings.DiversityRules
+		want        []int
+		wantDropped map[string]int
+	}{
+		{
+			name:        "no rules keep the order",
+			items:       feedItems(item{1, 1, 1, ""}, item{2, 1, 1, ""}, item{3, 1, 1, ""}),
+			want:        []int{1, 2, 3},
+			wantDropped: map[string]int{},
+		},
+		{
+			name:        "exact duplicates",
+			items:       feedItems(item{1, 1, 1, ""}, item{2, 2, 2, ""}, item{1, 1, 1, ""}),
+			want:        []int{1, 2},
+			wantDropped: map[string]int{Duplicate: 1},
+		},
+		{
+			name: "near duplicates",
+			items: feedItems(
+				item{1, 1, 1, "Big news: the river festival is back this summer!"},
+				item{2, 2, 2, "big news the river festival is back this summer"},
+				item{3, 3, 3, "A completely different story about gardening"},
+			),
+			rules:       settings.DiversityRules{NearDuplicateThreshold: 0.8, ShingleSize: 3},
+			want:        []int{1, 3},
+			wantDropped: map[string]int{NearDuplicate: 1},
+		},
+		{
+			name:        "per page caps",
+			items:       feedItems(item{1, 1, 1, ""}, item{2, 1, 2, ""}, item{3, 2, 1, ""}, item{4, 3, 1, ""}),
+			rules:       settings.DiversityRules{MaxPerAuthor: 1, MaxPerCommunity: 2},
+			want:        []int{1, 3},
+			wantDropped: map[string]int{AuthorCap: 1, CommunityCap: 1},
+		},
+		{
+			name:        "consecutive authors are spread out",
+			items:       feedItems(item{1, 1, 1, ""}, item{2, 1, 2, ""}, item{3, 1, 3, ""}, item{4, 2, 4, ""}, item{5, 3, 5, ""}),
+			rules:       settings.DiversityRules{MaxConsecutiveAuthor: 1},
+			want:        []int{1, 4, 2, 5, 3},
+			wantDropped: map[string]int{},
+		},
+		{
+			name:        "consecutive communities are spread out",
+			items:       feedItems(item{1, 1, 1, ""}, item{2, 2, 1, ""}, item{3, 3, 1, ""}, item{4, 4, 2, ""}),
+			rules:       settings.DiversityRules{MaxConsecutiveCommunity: 2},
+			want:        []int{1, 2, 4, 3},
+			wantDropped: map[string]int{},
+		},
+		{
+			name:        "runs stay when nothing can break them",
+			items:       feedItems(item{1, 1, 1, ""}, item{2, 1, 1, ""}, item{3, 1, 1, ""}),
+			rules:       settings.DiversityRules{MaxConsecutiveAuthor: 1},
+			want:        []int{1, 2, 3},
+			wantDropped: map[string]int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, dropped := Rerank(tt.items, tt.rules)
+			if !reflect.DeepEqual(posts(got), tt.want) {
+				t.Errorf("Rerank() = %v, want %v", posts(got), tt.want)
+			}
+			if !reflect.DeepEqual(dropped, tt.wantDropped) {
+				t.Errorf("dropped = %v, want %v", dropped, tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestRerankKeepsInput(t *testing.T) {
+	items := feedItems(item{1, 1, 1, ""}, item{2, 1, 2, ""}, item{3, 2, 3, ""})
+	Rerank(items, settings.DiversityRules{MaxConsecutiveAuthor: 1})
+	if got := posts(items); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("input reordered to %v", got)
+	}
+}
+
+func TestJaccard(t *testing.T) {
+	tests := []struct {
+		a, b string
+		size int
+		want float64
+	}{
+		{a: "one two three", b: "One, two; three!", size: 2, want: 1},
+		{a: "one two three", b: "four five six", size: 1, want: 0},
+		{a: "one two three four", b: "one two three five", size: 1, want: 3.0 / 5},
+		{a: "one two", b: "one two three", size: 3, want: 0},
+		{a: "", b: "one", size: 1, want: 0},
+	}
+	for _, tt := range tests {
+		got := Jaccard(Shingles(tt.a, tt.size), Shingles(tt.b, tt.size))
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Jaccard(%q, %q, %d) = %v, want %v", tt.a, tt.b, tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestShingles(t *testing.T) {
+	tests := []struct {
+		text string
+		size int
+		want int
+	}{
+		{text: "a b c d", size: 2, want: 3},
+		{text: "a b", size: 3, want: 1},
+		{text: "a a a a", size: 1, want: 1},
+		{text: "  ...  ", size: 2, want: 0},
+		{text: strings.Repeat("w ", 5), size: 0, want: 1},
+	}
+	for _, tt := range tests {
+		if got := len(Shingles(tt.text, tt.size)); got != tt.want {
+			t.Errorf("len(Shingles(%q, %d)) = %d, want %d", tt.text, tt.size, got, tt.want)
+		}
+	}
+}
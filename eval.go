@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/config"
+	"github.com/CircleConnectApp/feed-service/database"
+	"github.com/CircleConnectApp/feed-service/evaluation"
+	"github.com/CircleConnectApp/feed-service/settings"
+)
+
+// runEval implements the eval command, which replays logged impressions and
+// interactions against ranker configurations and prints ranking metrics
+func runEval(args []string) int {
+	fs := flag.NewFlagSet("feed-service eval", flag.ContinueOnError)
+	fixture := fs.String("fixture", "", "read records from this JSONL file instead of PostgreSQL")
+	postgresURI := fs.String("postgres-uri", "", "PostgreSQL URI (defaults to the service configuration)")
+	since := fs.Duration("since", 7*24*time.Hour, "replay impressions logged this long before --until")
+	untilFlag := fs.String("until", "", "end of the replay period, RFC 3339 (default now)")
+	feed := fs.String("feed", "", "only replay this feed (personal or recommended)")
+	k := fs.Int("k", 10, "cutoff for NDCG@k and precision@k")
+	window := fs.Duration("window", 24*time.Hour, "how long after an impression an interaction counts")
+	format := fs.String("format", "text", "output format: text or json")
+	var rankerSpecs []string
+	fs.Func("ranker", `ranker to evaluate, as "name", "name:key=value,..." or "name:@file"; repeatable`, func(spec string) error {
+		rankerSpecs = append(rankerSpecs, spec)
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *k < 1 || *window <= 0 || (*format != "text" && *format != "json") {
+		fmt.Fprintln(os.Stderr, "eval: --k and --window must be positive and --format text or json")
+		return 2
+	}
+
+	base := settings.Default().Ranking
+	if len(rankerSpecs) == 0 {
+		rankerSpecs = []string{"default"}
+	}
+	rankers := make([]evaluation.Ranker, 0, len(rankerSpecs))
+	for _, spec := range rankerSpecs {
+		r, err := evaluation.ParseRanker(spec, base)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "eval: %v\n", err)
+			return 2
+		}
+		rankers = append(rankers, r)
+	}
+
+	until := time.Now()
+	if *untilFlag != "" {
+		t, err := time.Parse(time.RFC3339, *untilFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "eval: invalid --until: %v\n", err)
+			return 2
+		}
+		until = t
+	}
+
+	records, err := loadEvalRecords(*fixture, *postgresURI, until.Add(-*since), until.Add(*window))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "eval: failed to load records: %v\n", err)
+		return 1
+	}
+
+	sessions, err := evaluation.BuildSessions(records, evaluation.Options{Feed: *feed, Window: *window})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "eval: %v\n", err)
+		return 1
+	}
+	reports := evaluation.Evaluate(sessions, rankers, *k)
+
+	if *format == "json" {
+		out, _ := json.MarshalIndent(map[string]interface{}{"k": *k, "reports": reports}, "", "  ")
+		fmt.Println(string(out))
+		return 0
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "RANKER\tSESSIONS\tLABELED\tNDCG@%d\tMRR\tP@%d\tCOVERAGE\n", *k, *k)
+	for _, r := range reports {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.4f\t%.4f\t%.4f\t%.4f\n",
+			r.Ranker, r.Sessions, r.LabeledSessions, r.NDCG, r.MRR, r.Precision, r.Coverage)
+	}
+	w.Flush()
+	return 0
+}
+
+// loadEvalRecords reads the fixture if one is given, and otherwise the
+// interaction log between since and until
+func loadEvalRecords(fixture, postgresURI string, since, until time.Time) ([]evaluation.Record, error) {
+	if fixture != "" {
+		f, err := os.Open(fixture)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return evaluation.ReadJSONL(f)
+	}
+
+	if postgresURI == "" {
+		cfg, err := config.Load(nil)
+		if err != nil {
+			return nil, err
+		}
+		postgresURI = cfg.PostgresURI
+	}
+	db, err := database.ConnectPostgres(postgresURI)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	return evaluation.LoadPostgres(ctx, db, since, until)
+}
@@ -0,0 +1,285 @@
+package evaluation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/interactions"
+	"github.com/CircleConnectApp/feed-service/ranking"
+	"github.com/CircleConnectApp/feed-service/settings"
+	"gopkg.in/yaml.v3"
+)
+
+// LoggedRanker names the baseline that keeps the order the feed was served in
+const LoggedRanker = "logged"
+
+// gains grade interactions; a post's label is the best grade it received
+var gains = map[string]float64{
+	interactions.Click:   1,
+	interactions.Comment: 2,
+	interactions.Like:    2,
+	interactions.Share:   3,
+}
+
+// Ranker is a ranking configuration to evaluate
+type Ranker struct {
+	Name    string
+	Weights settings.RankingWeights
+}
+
+// ParseRanker parses a ranker flag written as "name", "name:key=value,..."
+// or "name:@file" where the file holds overrides in YAML or JSON. Overrides
+// are applied over base.
+func ParseRanker(spec string, base settings.RankingWeights) (Ranker, error) {
+	name, overridesSpec, _ := strings.Cut(spec, ":")
+	if name == "" || name == LoggedRanker {
+		return Ranker{}, fmt.Errorf("ranker %q: invalid name", spec)
+	}
+
+	overrides := map[string]float64{}
+	switch {
+	case strings.HasPrefix(overridesSpec, "@"):
+		data, err := os.ReadFile(overridesSpec[1:])
+		if err != nil {
+			return Ranker{}, fmt.Errorf("ranker %q: %w", name, err)
+		}
+		if err := yaml.Unmarshal(data, &overrides); err != nil {
+			return Ranker{}, fmt.Errorf("ranker %q: %w", name, err)
+		}
+	case overridesSpec != "":
+		for _, pair := range strings.Split(overridesSpec, ",") {
+			key, raw, ok := strings.Cut(pair, "=")
+			value, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+			if !ok || err != nil {
+				return Ranker{}, fmt.Errorf("ranker %q: invalid override %q", name, pair)
+			}
+			overrides[strings.TrimSpace(key)] = value
+		}
+	}
+
+	weights, err := base.WithOverrides(overrides)
+	if err != nil {
+		return Ranker{}, fmt.Errorf("ranker %q: %w", name, err)
+	}
+	return Ranker{Name: name, Weights: weights}, nil
+}
+
+// Options control how records are replayed
+type Options struct {
+	Feed   string        // only replay this feed; empty replays all
+	Window time.Duration // how long after an impression an interaction counts
+}
+
+// Session is one served feed page with the interactions that followed it
+type Session struct {
+	UserID   int
+	Feed     string
+	ServedAt time.Time
+	Items    []Item
+}
+
+// Item is a post shown in a session
+type Item struct {
+	PostID   string
+	Position int
+	Signals  ranking.Signals
+	Gain     float64
+}
+
+// Report holds a ranker's metrics averaged over the labeled sessions
+type Report struct {
+	Ranker          string  `json:"ranker"`
+	Sessions        int     `json:"sessions"`
+	LabeledSessions int     `json:"labeled_sessions"`
+	NDCG            float64 `json:"ndcg"`
+	MRR             float64 `json:"mrr"`
+	Precision       float64 `json:"precision"`
+	Coverage        float64 `json:"coverage"` // share of candidate posts that reach any top k
+}
+
+// BuildSessions groups impressions into served pages and labels each item
+// with the interactions the user had with it within the window
+func BuildSessions(records []Record, opts Options) ([]Session, error) {
+	type interactionKey struct {
+		userID int
+		postID string
+	}
+	actions := map[interactionKey][]Record{}
+	sessions := map[string]*Session{}
+	var order []string
+
+	for _, rec := range records {
+		if rec.Type != interactions.Impression {
+			actions[interactionKey{rec.UserID, rec.PostID}] = append(actions[interactionKey{rec.UserID, rec.PostID}], rec)
+			continue
+		}
+		if opts.Feed != "" && rec.Feed != opts.Feed {
+			continue
+		}
+
+		var signals ranking.Signals
+		if len(rec.Features) > 0 {
+			if err := json.Unmarshal(rec.Features, &signals); err != nil {
+				return nil, fmt.Errorf("impression of post %s: %w", rec.PostID, err)
+			}
+		}
+
+		// Impressions of one page share a request ID and serve time
+		key := fmt.Sprintf("%s/%d/%s/%d", rec.RequestID, rec.UserID, rec.Feed, rec.OccurredAt.UnixNano())
+		session, ok := sessions[key]
+		if !ok {
+			session = &Session{UserID: rec.UserID, Feed: rec.Feed, ServedAt: rec.OccurredAt}
+			sessions[key] = session
+			order = append(order, key)
+		}
+		duplicate := false
+		for _, item := range session.Items {
+			if item.PostID == rec.PostID {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			session.Items = append(session.Items, Item{PostID: rec.PostID, Position: rec.Position, Signals: signals})
+		}
+	}
+
+	out := make([]Session, 0, len(order))
+	for _, key := range order {
+		session := sessions[key]
+		for i := range session.Items {
+			item := &session.Items[i]
+			for _, action := range actions[interactionKey{session.UserID, item.PostID}] {
+				if action.Feed != "" && action.Feed != session.Feed {
+					continue
+				}
+				if action.OccurredAt.Before(session.ServedAt) || action.OccurredAt.After(session.ServedAt.Add(opts.Window)) {
+					continue
+				}
+				if action.Type == interactions.Hide {
+					// Hiding a post outweighs any other interaction with it
+					item.Gain = 0
+					break
+				}
+				item.Gain = math.Max(item.Gain, gains[action.Type])
+			}
+		}
+		sort.SliceStable(session.Items, func(i, j int) bool { return session.Items[i].Position < session.Items[j].Position })
+		out = append(out, *session)
+	}
+	return out, nil
+}
+
+// Evaluate replays the sessions through the logged order and each ranker
+func Evaluate(sessions []Session, rankers []Ranker, k int) []Report {
+	reports := []Report{evaluate(LoggedRanker, sessions, k, func(s Session) []Item { return s.Items })}
+	for _, r := range rankers {
+		weights := r.Weights
+		reports = append(reports, evaluate(r.Name, sessions, k, func(s Session) []Item {
+			items := append([]Item(nil), s.Items...)
+			scores := make(map[string]float64, len(items))
+			for _, item := range items {
+				// Score as of serve time so recency matches what the user saw
+				scores[item.PostID] = ranking.Score(item.Signals, weights, s.ServedAt)
+			}
+			sort.SliceStable(items, func(i, j int) bool { return scores[items[i].PostID] > scores[items[j].PostID] })
+			return items
+		}))
+	}
+	return reports
+}
+
+func evaluate(name string, sessions []Session, k int, rank func(Session) []Item) Report {
+	report := Report{Ranker: name, Sessions: len(sessions)}
+	candidates := map[string]bool{}
+	shown := map[string]bool{}
+
+	for _, session := range sessions {
+		ranked := rank(session)
+		for i, item := range ranked {
+			candidates[item.PostID] = true
+			if i < k {
+				shown[item.PostID] = true
+			}
+		}
+
+		if !hasPositive(ranked) {
+			continue
+		}
+		report.LabeledSessions++
+		report.NDCG += ndcg(ranked, k)
+		report.MRR += reciprocalRank(ranked)
+		report.Precision += precision(ranked, k)
+	}
+
+	if report.LabeledSessions > 0 {
+		n := float64(report.LabeledSessions)
+		report.NDCG /= n
+		report.MRR /= n
+		report.Precision /= n
+	}
+	if len(candidates) > 0 {
+		report.Coverage = float64(len(shown)) / float64(len(candidates))
+	}
+	return report
+}
+
+func hasPositive(items []Item) bool {
+	for _, item := range items {
+		if item.Gain > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func dcg(gains []float64, k int) float64 {
+	total := 0.0
+	for i := 0; i < len(gains) && i < k; i++ {
+		total += (math.Pow(2, gains[i]) - 1) / math.Log2(float64(i+2))
+	}
+	return total
+}
+
+func ndcg(items []Item, k int) float64 {
+	gains := make([]float64, len(items))
+	for i, item := range items {
+		gains[i] = item.Gain
+	}
+	actual := dcg(gains, k)
+	sort.Sort(sort.Reverse(sort.Float64Slice(gains)))
+	ideal := dcg(gains, k)
+	if ideal == 0 {
+		return 0
+	}
+	return actual / ideal
+}
+
+func reciprocalRank(items []Item) float64 {
+	for i, item := range items {
+		if item.Gain > 0 {
+			return 1 / float64(i+1)
+		}
+	}
+	return 0
+}
+
+func precision(items []Item, k int) float64 {
+	n := k
+	if len(items) < n {
+		n = len(items)
+	}
+	relevant := 0
+	for _, item := range items[:n] {
+		if item.Gain > 0 {
+			relevant++
+		}
+	}
+	return float64(relevant) / float64(n)
+}
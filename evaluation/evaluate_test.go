@@ -0,0 +1,302 @@
+package evaluation
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/interactions"
+	"github.com/CircleConnectApp/feed-service/ranking"
+	"github.com/CircleConnectApp/feed-service/settings"
+)
+
+func items(gains ...float64) []Item {
+	out := make([]Item, len(gains))
+	for i, gain := range gains {
+		out[i] = Item{PostID: string(rune('a' + i)), Position: i, Gain: gain}
+	}
+	return out
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestMetrics(t *testing.T) {
+	tests := []struct {
+		name          string
+		items         []Item
+		k             int
+		wantNDCG      float64
+		wantMRR       float64
+		wantPrecision float64
+	}{
+		{
+			name:          "ideal order",
+			items:         items(3, 2, 0),
+			k:             3,
+			wantNDCG:      1,
+			wantMRR:       1,
+			wantPrecision: 2.0 / 3,
+		},
+		{
+			name:  "relevant item second",
+			items: items(0, 1),
+			k:     2,
+			// (2^1-1)/log2(3) over the ideal (2^1-1)/log2(2)
+			wantNDCG:      1 / math.Log2(3),
+			wantMRR:       0.5,
+			wantPrecision: 0.5,
+		},
+		{
+			name:  "reversed grades",
+			items: items(1, 3),
+			k:     2,
+			wantNDCG: (1 + 7/math.Log2(3)) /
+				(7 + 1/math.Log2(3)),
+			wantMRR:       1,
+			wantPrecision: 1,
+		},
+		{
+			name:          "relevant item beyond k",
+			items:         items(0, 0, 2),
+			k:             2,
+			wantNDCG:      0,
+			wantMRR:       1.0 / 3,
+			wantPrecision: 0,
+		},
+		{
+			name:          "fewer items than k",
+			items:         items(1),
+			k:             10,
+			wantNDCG:      1,
+			wantMRR:       1,
+			wantPrecision: 1,
+		},
+		{
+			name:  "no relevant items",
+			items: items(0, 0),
+			k:     2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ndcg(tt.items, tt.k); !almostEqual(got, tt.wantNDCG) {
+				t.Errorf("ndcg() = %v, want %v", got, tt.wantNDCG)
+			}
+			if got := reciprocalRank(tt.items); !almostEqual(got, tt.wantMRR) {
+				t.Errorf("reciprocalRank() = %v, want %v", got, tt.wantMRR)
+			}
+			if got := precision(tt.items, tt.k); !almostEqual(got, tt.wantPrecision) {
+				t.Errorf("precision() = %v, want %v", got, tt.wantPrecision)
+			}
+		})
+	}
+}
+
+func TestBuildSessions(t *testing.T) {
+	served := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	impression := func(postID string, position int) Record {
+		return Record{UserID: 1, PostID: postID, Type: interactions.Impression, Feed: "personal", Position: position, RequestID: "r1", OccurredAt: served}
+	}
+	action := func(postID, kind, feed string, after time.Duration) Record {
+		return Record{UserID: 1, PostID: postID, Type: kind, Feed: feed, OccurredAt: served.Add(after)}
+	}
+
+	tests := []struct {
+		name      string
+		records   []Record
+		opts      Options
+		wantGains map[string]float64
+	}{
+		{
+			name:      "best grade wins",
+			records:   []Record{impression("a", 0), action("a", interactions.Click, "personal", time.Minute), action("a", interactions.Share, "personal", 2*time.Minute)},
+			opts:      Options{Window: time.Hour},
+			wantGains: map[string]float64{"a": 3},
+		},
+		{
+			name:      "hide outweighs other interactions",
+			records:   []Record{impression("a", 0), action("a", interactions.Like, "personal", time.Minute), action("a", interactions.Hide, "personal", 2*time.Minute)},
+			opts:      Options{Window: time.Hour},
+			wantGains: map[string]float64{"a": 0},
+		},
+		{
+			name:      "interactions outside the window are ignored",
+			records:   []Record{impression("a", 0), impression("b", 1), action("a", interactions.Like, "personal", 2*time.Hour), action("b", interactions.Like, "personal", -time.Minute)},
+			opts:      Options{Window: time.Hour},
+			wantGains: map[string]float64{"a": 0, "b": 0},
+		},
+		{
+			name:      "interactions on another feed are ignored",
+			records:   []Record{impression("a", 0), action("a", interactions.Like, "recommended", time.Minute)},
+			opts:      Options{Window: time.Hour},
+			wantGains: map[string]float64{"a": 0},
+		},
+		{
+			name:      "interactions without a feed count",
+			records:   []Record{impression("a", 0), action("a", interactions.Comment, "", time.Minute)},
+			opts:      Options{Window: time.Hour},
+			wantGains: map[string]float64{"a": 2},
+		},
+		{
+			name:    "other feeds are skipped when filtering",
+			records: []Record{impression("a", 0)},
+			opts:    Options{Feed: "recommended", Window: time.Hour},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessions, err := BuildSessions(tt.records, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tt.wantGains) == 0 {
+				if len(sessions) != 0 {
+					t.Fatalf("got %d sessions, want none", len(sessions))
+				}
+				return
+			}
+			if len(sessions) != 1 {
+				t.Fatalf("got %d sessions, want 1", len(sessions))
+			}
+			got := map[string]float64{}
+			for _, item := range sessions[0].Items {
+				got[item.PostID] = item.Gain
+			}
+			for postID, want := range tt.wantGains {
+				if got[postID] != want {
+					t.Errorf("gain of %s = %v, want %v", postID, got[postID], want)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildSessionsGroupsPages(t *testing.T) {
+	served := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	records := []Record{
+		{UserID: 1, PostID: "b", Type: interactions.Impression, Feed: "personal", Position: 1, RequestID: "r1", OccurredAt: served},
+		{UserID: 1, PostID: "a", Type: interactions.Impression, Feed: "personal", Position: 0, RequestID: "r1", OccurredAt: served},
+		{UserID: 1, PostID: "a", Type: interactions.Impression, Feed: "personal", Position: 0, RequestID: "r1", OccurredAt: served},
+		{UserID: 1, PostID: "c", Type: interactions.Impression, Feed: "personal", Position: 0, RequestID: "r2", OccurredAt: served},
+	}
+
+	sessions, err := BuildSessions(records, Options{Window: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("got %d sessions, want one per request", len(sessions))
+	}
+	if first := sessions[0].Items; len(first) != 2 || first[0].PostID != "a" || first[1].PostID != "b" {
+		t.Errorf("first session = %+v, want a then b without duplicates", first)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	served := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	// The logged order served the stale post first, but the user liked the
+	// fresh one
+	sessions := []Session{{
+		UserID:   1,
+		Feed:     "personal",
+		ServedAt: served,
+		Items: []Item{
+			{PostID: "stale", Position: 0, Signals: ranking.Signals{LikeCount: 50, CreatedAt: served.Add(-30 * 24 * time.Hour)}},
+			{PostID: "fresh", Position: 1, Signals: ranking.Signals{LikeCount: 0, CreatedAt: served}, Gain: 2},
+		},
+	}, {
+		UserID:   2,
+		Feed:     "personal",
+		ServedAt: served,
+		Items:    []Item{{PostID: "unlabeled", Position: 0}},
+	}}
+	recency, err := ParseRanker("recency:recency_weight=1,popularity_weight=0", settings.Default().Ranking)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reports := Evaluate(sessions, []Ranker{recency}, 1)
+	if len(reports) != 2 || reports[0].Ranker != LoggedRanker || reports[1].Ranker != "recency" {
+		t.Fatalf("reports = %+v, want logged then recency", reports)
+	}
+	logged, ranked := reports[0], reports[1]
+	if logged.Sessions != 2 || logged.LabeledSessions != 1 {
+		t.Errorf("logged sessions = %d/%d labeled, want 2/1", logged.Sessions, logged.LabeledSessions)
+	}
+	if logged.NDCG != 0 || logged.MRR != 0.5 || logged.Precision != 0 {
+		t.Errorf("logged = %+v, want the liked post missed at k=1", logged)
+	}
+	if ranked.NDCG != 1 || ranked.MRR != 1 || ranked.Precision != 1 {
+		t.Errorf("recency = %+v, want the liked post first", ranked)
+	}
+	if !almostEqual(logged.Coverage, 2.0/3) {
+		t.Errorf("coverage = %v, want 2/3 of the candidates in a top 1", logged.Coverage)
+	}
+}
+
+func TestParseRanker(t *testing.T) {
+	base := settings.Default().Ranking
+	file := filepath.Join(t.TempDir(), "ranker.yaml")
+	if err := os.WriteFile(file, []byte("popularity_weight: 0.9\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		spec           string
+		wantRecency    float64
+		wantPopularity float64
+		wantErr        string
+	}{
+		{spec: "baseline", wantRecency: base.RecencyWeight, wantPopularity: base.PopularityWeight},
+		{spec: "r:recency_weight=0.1, popularity_weight = 0.2", wantRecency: 0.1, wantPopularity: 0.2},
+		{spec: "f:@" + file, wantRecency: base.RecencyWeight, wantPopularity: 0.9},
+		{spec: "", wantErr: "invalid name"},
+		{spec: LoggedRanker, wantErr: "invalid name"},
+		{spec: "r:recency_weight", wantErr: "invalid override"},
+		{spec: "r:recency=1", wantErr: "invalid ranking override"},
+		{spec: "r:@missing.yaml", wantErr: "no such file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseRanker(tt.spec, base)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseRanker() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Weights.RecencyWeight != tt.wantRecency || got.Weights.PopularityWeight != tt.wantPopularity {
+				t.Errorf("weights = %+v", got.Weights)
+			}
+		})
+	}
+}
+
+func TestReadJSONL(t *testing.T) {
+	input := `{"user_id": 1, "post_id": "a", "type": "impression", "feed": "personal"}
+
+{"user_id": 1, "post_id": "a", "type": "like"}
+`
+	records, err := ReadJSONL(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[1].Type != interactions.Like {
+		t.Errorf("records = %+v", records)
+	}
+
+	if _, err := ReadJSONL(strings.NewReader("{}\nnot json\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("ReadJSONL() error = %v, want the failing line", err)
+	}
+}
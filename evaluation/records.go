@@ -0,0 +1,75 @@
+package evaluation
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Record is one row of the interaction log. JSONL fixtures use the same
+// shape, one record per line.
+type Record struct {
+	UserID      int               `json:"user_id"`
+	PostID      string            `json:"post_id"`
+	Type        string            `json:"type"`
+	Feed        string            `json:"feed"`
+	Position    int               `json:"position"`
+	RequestID   string            `json:"request_id"`
+	Experiments map[string]string `json:"experiments,omitempty"`
+	Features    json.RawMessage   `json:"features,omitempty"`
+	OccurredAt  time.Time         `json:"occurred_at"`
+}
+
+// ReadJSONL reads records from a JSONL fixture; blank lines are skipped
+func ReadJSONL(r io.Reader) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
+
+// LoadPostgres reads the records logged between since and until
+func LoadPostgres(ctx context.Context, db *sql.DB, since, until time.Time) ([]Record, error) {
+	rows, err := db.QueryContext(ctx, `SELECT user_id, post_id, event_type, feed, COALESCE(position, 0),
+			request_id, experiments, features, occurred_at
+		FROM feed_interactions
+		WHERE occurred_at >= $1 AND occurred_at < $2
+		ORDER BY occurred_at`, since, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		var rec Record
+		var experiments []byte
+		var features []byte
+		if err := rows.Scan(&rec.UserID, &rec.PostID, &rec.Type, &rec.Feed, &rec.Position,
+			&rec.RequestID, &experiments, &features, &rec.OccurredAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(experiments, &rec.Experiments); err != nil {
+			return nil, err
+		}
+		rec.Features = features
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
@@ -0,0 +1,92 @@
+package experiments
+
+import (
+	"hash/fnv"
+	"log/slog"
+	"sort"
+	"strconv"
+
+	"github.com/CircleConnectApp/feed-service/settings"
+)
+
+// buckets is the resolution of the traffic split; variant weights are
+// proportions of it
+const buckets = 10000
+
+// Assignment is the ranker a user receives on one feed, together with the
+// experiment variants that produced it
+type Assignment struct {
+	Ranking  settings.RankingWeights
+	Variants map[string]string // experiment name -> variant name
+}
+
+// Bucket deterministically maps a user to [0, 10000) for an experiment.
+// Hashing the experiment name with the user ID keeps assignments stable
+// across requests and replicas while making experiments independent.
+func Bucket(experiment string, userID int) int {
+	h := fnv.New32a()
+	h.Write([]byte(experiment))
+	h.Write([]byte{':'})
+	h.Write([]byte(strconv.Itoa(userID)))
+	return int(h.Sum32() % buckets)
+}
+
+// Assign resolves the enabled experiments that apply to feed for a user.
+// Variant ranking overrides are layered over the snapshot's weights in
+// experiment name order, so overlapping experiments resolve predictably.
+func Assign(snap *settings.Snapshot, userID int, feed string) Assignment {
+	assignment := Assignment{Ranking: snap.Ranking}
+
+	exps := make([]settings.Experiment, 0, len(snap.Experiments))
+	for _, exp := range snap.Experiments {
+		if exp.Enabled && appliesTo(exp, feed) {
+			exps = append(exps, exp)
+		}
+	}
+	sort.Slice(exps, func(i, j int) bool { return exps[i].Name < exps[j].Name })
+
+	for _, exp := range exps {
+		variant := pickVariant(exp, Bucket(exp.Name, userID))
+
+		ranking, err := assignment.Ranking.WithOverrides(variant.Ranking)
+		if err != nil {
+			// Validation rejects bad overrides before they are published
+			slog.Warn("Ignoring invalid experiment variant", "experiment", exp.Name, "variant", variant.Name, "error", err)
+			continue
+		}
+
+		if assignment.Variants == nil {
+			assignment.Variants = make(map[string]string)
+		}
+		assignment.Ranking = ranking
+		assignment.Variants[exp.Name] = variant.Name
+	}
+
+	return assignment
+}
+
+func appliesTo(exp settings.Experiment, feed string) bool {
+	for _, f := range exp.Feeds {
+		if f == feed {
+			return true
+		}
+	}
+	return false
+}
+
+// pickVariant maps a bucket onto the variants' cumulative weights
+func pickVariant(exp settings.Experiment, bucket int) settings.Variant {
+	total := 0
+	for _, v := range exp.Variants {
+		total += v.Weight
+	}
+
+	point := bucket * total / buckets
+	for _, v := range exp.Variants {
+		if point < v.Weight {
+			return v
+		}
+		point -= v.Weight
+	}
+	return exp.Variants[len(exp.Variants)-1]
+}
@@ -0,0 +1,149 @@
+package experiments
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/CircleConnectApp/feed-service/settings"
+)
+
+func TestBucket(t *testing.T) {
+	for userID := 0; userID < 1000; userID++ {
+		b := Bucket("exp", userID)
+		if b < 0 || b >= buckets {
+			t.Fatalf("Bucket(exp, %d) = %d, want [0, %d)", userID, b, buckets)
+		}
+		if Bucket("exp", userID) != b {
+			t.Fatalf("Bucket(exp, %d) is not deterministic", userID)
+		}
+	}
+
+	// Experiments are independent, so a user's bucket differs between them
+	same := 0
+	for userID := 0; userID < 1000; userID++ {
+		if Bucket("a", userID) == Bucket("b", userID) {
+			same++
+		}
+	}
+	if same > 10 {
+		t.Errorf("%d of 1000 users share a bucket across experiments", same)
+	}
+}
+
+func TestPickVariant(t *testing.T) {
+	exp := settings.Experiment{Variants: []settings.Variant{
+		{Name: "control", Weight: 1},
+		{Name: "off", Weight: 0},
+		{Name: "treatment", Weight: 3},
+	}}
+
+	tests := []struct {
+		bucket int
+		want   string
+	}{
+		{bucket: 0, want: "control"},
+		{bucket: 2499, want: "control"},
+		{bucket: 2500, want: "treatment"},
+		{bucket: buckets - 1, want: "treatment"},
+	}
+	for _, tt := range tests {
+		if got := pickVariant(exp, tt.bucket).Name; got != tt.want {
+			t.Errorf("pickVariant(%d) = %s, want %s", tt.bucket, got, tt.want)
+		}
+	}
+}
+
+func TestAssign(t *testing.T) {
+	split := func(name string, feeds []string, enabled bool, treatment map[string]float64) settings.Experiment {
+		return settings.Experiment{
+			Name:    name,
+			Enabled: enabled,
+			Feeds:   feeds,
+			Variants: []settings.Variant{
+				// Every user lands in the treatment
+				{Name: "control", Weight: 0},
+				{Name: "treatment", Weight: 1, Ranking: treatment},
+			},
+		}
+	}
+	defaults := settings.Default().Ranking
+
+	tests := []struct {
+		name         string
+		experiments  []settings.Experiment
+		feed         string
+		wantVariants map[string]string
+		wantRecency  float64
+	}{
+		{name: "no experiments", feed: "personal", wantRecency: defaults.RecencyWeight},
+		{
+			name:         "applies the variant overrides",
+			experiments:  []settings.Experiment{split("a", []string{"personal"}, true, map[string]float64{"recency_weight": 0.1})},
+			feed:         "personal",
+			wantVariants: map[string]string{"a": "treatment"},
+			wantRecency:  0.1,
+		},
+		{
+			name:        "skips disabled experiments",
+			experiments: []settings.Experiment{split("a", []string{"personal"}, false, map[string]float64{"recency_weight": 0.1})},
+			feed:        "personal",
+			wantRecency: defaults.RecencyWeight,
+		},
+		{
+			name:        "skips experiments on other feeds",
+			experiments: []settings.Experiment{split("a", []string{"recommended"}, true, map[string]float64{"recency_weight": 0.1})},
+			feed:        "personal",
+			wantRecency: defaults.RecencyWeight,
+		},
+		{
+			name: "later names override earlier ones",
+			experiments: []settings.Experiment{
+				split("b", []string{"personal"}, true, map[string]float64{"recency_weight": 0.2}),
+				split("a", []string{"personal"}, true, map[string]float64{"recency_weight": 0.1}),
+			},
+			feed:         "personal",
+			wantVariants: map[string]string{"a": "treatment", "b": "treatment"},
+			wantRecency:  0.2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := settings.Default()
+			s.Experiments = tt.experiments
+			got := Assign(&settings.Snapshot{Settings: s}, 42, tt.feed)
+			if !reflect.DeepEqual(got.Variants, tt.wantVariants) {
+				t.Errorf("variants = %v, want %v", got.Variants, tt.wantVariants)
+			}
+			if got.Ranking.RecencyWeight != tt.wantRecency {
+				t.Errorf("recency weight = %v, want %v", got.Ranking.RecencyWeight, tt.wantRecency)
+			}
+			if got.Ranking.PopularityWeight != defaults.PopularityWeight {
+				t.Errorf("popularity weight = %v, want the default", got.Ranking.PopularityWeight)
+			}
+		})
+	}
+}
+
+func TestAssignSplitsTraffic(t *testing.T) {
+	s := settings.Default()
+	s.Experiments = []settings.Experiment{{
+		Name:     "split",
+		Enabled:  true,
+		Feeds:    []string{"recommended"},
+		Variants: []settings.Variant{{Name: "control", Weight: 1}, {Name: "treatment", Weight: 3}},
+	}}
+	snap := &settings.Snapshot{Settings: s}
+
+	const users = 20000
+	treated := 0
+	for userID := 0; userID < users; userID++ {
+		if Assign(snap, userID, "recommended").Variants["split"] == "treatment" {
+			treated++
+		}
+	}
+	if share := float64(treated) / users; math.Abs(share-0.75) > 0.02 {
+		t.Errorf("treatment share = %.3f, want about 0.75", share)
+	}
+}
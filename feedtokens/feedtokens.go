@@ -0,0 +1,138 @@
+package feedtokens
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// prefix marks feed tokens so they are recognisable in URLs and secret scanners
+const prefix = "ft_"
+
+// maxTokensPerUser bounds how many active tokens a user can hold
+const maxTokensPerUser = 20
+
+var (
+	// ErrNotFound is returned when a token does not exist or is revoked
+	ErrNotFound = errors.New("feed token not found")
+	// ErrTooMany is returned when a user already holds the maximum number of tokens
+	ErrTooMany = errors.New("too many feed tokens")
+)
+
+// Token describes a feed token. The secret itself is only returned when the
+// token is created; the store keeps a SHA-256 hash of it.
+type Token struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     int                `bson:"user_id" json:"-"`
+	Name       string             `bson:"name" json:"name"`
+	Hash       string             `bson:"hash" json:"-"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	LastUsedAt *time.Time         `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+}
+
+// Store issues, looks up and revokes feed tokens, which let feed readers
+// that cannot send bearer tokens fetch a user's feeds
+type Store struct {
+	collection *mongo.Collection
+}
+
+// NewStore creates the store's indexes
+func NewStore(ctx context.Context, collection *mongo.Collection) (*Store, error) {
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{collection: collection}, nil
+}
+
+// Create issues a new token for a user and returns its secret
+func (s *Store) Create(ctx context.Context, userID int, name string) (string, *Token, error) {
+	count, err := s.collection.CountDocuments(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return "", nil, err
+	}
+	if count >= maxTokensPerUser {
+		return "", nil, ErrTooMany
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	secret := prefix + hex.EncodeToString(b)
+
+	token := &Token{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Name:      name,
+		Hash:      hash(secret),
+		CreatedAt: time.Now().UTC(),
+	}
+	if _, err := s.collection.InsertOne(ctx, token); err != nil {
+		return "", nil, err
+	}
+	return secret, token, nil
+}
+
+// List returns a user's tokens, newest first
+func (s *Store) List(ctx context.Context, userID int) ([]Token, error) {
+	cursor, err := s.collection.Find(ctx, bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := []Token{}
+	if err := cursor.All(ctx, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Revoke deletes one of a user's tokens; feeds using it stop working at once
+func (s *Store) Revoke(ctx context.Context, userID int, id primitive.ObjectID) error {
+	res, err := s.collection.DeleteOne(ctx, bson.M{"_id": id, "user_id": userID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Authenticate returns the user a token secret belongs to and records its use
+func (s *Store) Authenticate(ctx context.Context, secret string) (int, error) {
+	if len(secret) <= len(prefix) || secret[:len(prefix)] != prefix {
+		return 0, ErrNotFound
+	}
+
+	var token Token
+	err := s.collection.FindOneAndUpdate(ctx,
+		bson.M{"hash": hash(secret)},
+		bson.M{"$set": bson.M{"last_used_at": time.Now().UTC()}},
+	).Decode(&token)
+	if err == mongo.ErrNoDocuments {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return token.UserID, nil
+}
+
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
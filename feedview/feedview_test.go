@@ -0,0 +1,71 @@
+package feedview
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/CircleConnectApp/feed-service/models"
+)
+
+func TestPreview(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		n       int
+		want    string
+	}{
+		{name: "short text is kept", content: "Hello world", n: 20, want: "Hello world"},
+		{name: "markdown is stripped", content: "# Title\n\nSome **bold** and [a link](https://x.example) with `code`", n: 100, want: "Title Some bold and a link with code"},
+		{name: "cut at a word boundary", content: "The quick brown fox jumps over the lazy dog", n: 20, want: "The quick brown…"},
+		{name: "multibyte characters", content: "héllo wörld ünïcode", n: 8, want: "héllo…"},
+		{name: "one character", content: "Hello", n: 1, want: "H"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Preview(tt.content, tt.n); got != tt.want {
+				t.Errorf("Preview(%q, %d) = %q, want %q", tt.content, tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestView(t *testing.T) {
+	item := models.FeedItem{UserID: 7, Title: "Hi", Content: "**Long** content here", Tags: []string{"go"}}
+
+	tests := []struct {
+		name     string
+		spec     string
+		preview  int
+		want     map[string]interface{}
+		wantFull bool
+		wantErr  bool
+	}{
+		{name: "selected fields", spec: "title, user_id", want: map[string]interface{}{"title": "Hi", "user_id": 7}},
+		{name: "unset optional fields are left out", spec: "title,source,tags", want: map[string]interface{}{"title": "Hi", "tags": []string{"go"}}},
+		{name: "content preview", spec: "content", preview: 8, want: map[string]interface{}{"content": "Long…"}},
+		{name: "unknown field", spec: "title,secret", wantErr: true},
+		{name: "all fields", wantFull: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			view, err := New(tt.spec, tt.preview)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if view.Full() != tt.wantFull {
+				t.Errorf("full = %v, want %v", view.Full(), tt.wantFull)
+			}
+			if tt.want == nil {
+				return
+			}
+			if got := view.Items([]models.FeedItem{item})[0]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("item = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
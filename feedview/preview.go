@@ -0,0 +1,58 @@
+package feedview
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Markdown constructs removed or replaced by their text, applied in order
+var markdownRules = []struct {
+	pattern *regexp.Regexp
+	replace string
+}{
+	{regexp.MustCompile("(?m)^\\s*(```|~~~).*$"), ""},        // code fences
+	{regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`), "$1"},     // images
+	{regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`), "$1"},      // inline links
+	{regexp.MustCompile(`\[([^\]]+)\]\[[^\]]*\]`), "$1"},     // reference links
+	{regexp.MustCompile(`(?m)^\s*\[[^\]]+\]:\s*\S+.*$`), ""}, // link definitions
+	{regexp.MustCompile(`<[^>]+>`), ""},                      // HTML tags
+	{regexp.MustCompile(`(?m)^\s{0,3}#{1,6}\s+`), ""},        // headings
+	{regexp.MustCompile(`(?m)^\s{0,3}>\s?`), ""},             // block quotes
+	{regexp.MustCompile(`(?m)^\s*([-*_]\s*){3,}$`), ""},      // horizontal rules
+	{regexp.MustCompile(`(?m)^\s*([-*+]|\d+[.)])\s+`), ""},   // list markers
+	{regexp.MustCompile(`\*\*(.+?)\*\*`), "$1"},              // bold
+	{regexp.MustCompile(`\b__(.+?)__\b`), "$1"},              // bold
+	{regexp.MustCompile(`\*(\S(?:.*?\S)?)\*`), "$1"},         // italics
+	{regexp.MustCompile(`\b_(\S(?:.*?\S)?)_\b`), "$1"},       // italics
+	{regexp.MustCompile(`~~(.+?)~~`), "$1"},                  // strikethrough
+	{regexp.MustCompile("`([^`]+)`"), "$1"},                  // inline code
+}
+
+// PlainText strips Markdown formatting from s and collapses whitespace
+func PlainText(s string) string {
+	for _, rule := range markdownRules {
+		s = rule.pattern.ReplaceAllString(s, rule.replace)
+	}
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Preview returns a plain-text excerpt of Markdown content of at most n
+// characters. Cut text ends at a word boundary where possible and with an
+// ellipsis.
+func Preview(content string, n int) string {
+	text := PlainText(content)
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	if n <= 1 {
+		return string([]rune(text)[:n])
+	}
+
+	runes := []rune(text)[:n-1]
+	cut := string(runes)
+	if i := strings.LastIndexByte(cut, ' '); i > len(cut)/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " .,;:-") + "…"
+}
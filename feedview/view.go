@@ -0,0 +1,119 @@
+// Package feedview shapes feed items for clients that want fewer fields or
+// shorter content than the full FeedItem
+package feedview
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/CircleConnectApp/feed-service/models"
+)
+
+// field reads one FeedItem field by its JSON name. Optional fields report
+// whether they are set, matching the omitempty JSON encoding.
+type field struct {
+	name string
+	get  func(models.FeedItem) (interface{}, bool)
+}
+
+func always(get func(models.FeedItem) interface{}) func(models.FeedItem) (interface{}, bool) {
+	return func(item models.FeedItem) (interface{}, bool) { return get(item), true }
+}
+
+// fields lists the FeedItem fields in JSON encoding order
+var fields = []field{
+	{"id", always(func(i models.FeedItem) interface{} { return i.ID.Hex() })},
+	{"post_id", always(func(i models.FeedItem) interface{} { return i.PostID.Hex() })},
+	{"user_id", always(func(i models.FeedItem) interface{} { return i.UserID })},
+	{"community_id", always(func(i models.FeedItem) interface{} { return i.CommunityID })},
+	{"community_name", func(i models.FeedItem) (interface{}, bool) { return i.CommunityName, i.CommunityName != "" }},
+	{"community_icon", func(i models.FeedItem) (interface{}, bool) { return i.CommunityIcon, i.CommunityIcon != "" }},
+	{"title", always(func(i models.FeedItem) interface{} { return i.Title })},
+	{"content", always(func(i models.FeedItem) interface{} { return i.Content })},
+	{"like_count", always(func(i models.FeedItem) interface{} { return i.LikeCount })},
+	{"media_urls", func(i models.FeedItem) (interface{}, bool) { return i.MediaURLs, len(i.MediaURLs) > 0 }},
+	{"tags", func(i models.FeedItem) (interface{}, bool) { return i.Tags, len(i.Tags) > 0 }},
+	{"created_at", always(func(i models.FeedItem) interface{} { return i.CreatedAt })},
+	{"relevance", always(func(i models.FeedItem) interface{} { return i.Relevance })},
+	{"author_name", always(func(i models.FeedItem) interface{} { return i.AuthorName })},
+	{"author_avatar", func(i models.FeedItem) (interface{}, bool) { return i.AuthorAvatar, i.AuthorAvatar != "" }},
+	{"source", func(i models.FeedItem) (interface{}, bool) { return i.Source, i.Source != "" }},
+	{"pinned", func(i models.FeedItem) (interface{}, bool) { return i.Pinned, i.Pinned }},
+}
+
+// View selects the fields returned for each item and how content is shown
+type View struct {
+	fields         []field
+	contentPreview int
+}
+
+// New builds a view from a comma-separated list of JSON field names (all
+// fields when empty) and a content preview length (full content when 0)
+func New(spec string, contentPreview int) (*View, error) {
+	v := &View{contentPreview: contentPreview}
+	if strings.TrimSpace(spec) == "" {
+		v.fields = fields
+		return v, nil
+	}
+
+	selected := map[string]bool{}
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !known(name) {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+		selected[name] = true
+	}
+	for _, f := range fields {
+		if selected[f.name] {
+			v.fields = append(v.fields, f)
+		}
+	}
+	return v, nil
+}
+
+// Full reports whether the view returns items unchanged
+func (v *View) Full() bool {
+	return len(v.fields) == len(fields) && v.contentPreview == 0
+}
+
+// Items returns the selected fields of each item, keyed by JSON name
+func (v *View) Items(items []models.FeedItem) []map[string]interface{} {
+	out := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		m := make(map[string]interface{}, len(v.fields))
+		for _, f := range v.fields {
+			value, ok := f.get(item)
+			if !ok {
+				continue
+			}
+			if f.name == "content" && v.contentPreview > 0 {
+				value = Preview(item.Content, v.contentPreview)
+			}
+			m[f.name] = value
+		}
+		out[i] = m
+	}
+	return out
+}
+
+// Fields returns the JSON names of all selectable fields
+func Fields() []string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.name
+	}
+	return names
+}
+
+func known(name string) bool {
+	for _, f := range fields {
+		if f.name == name {
+			return true
+		}
+	}
+	return false
+}
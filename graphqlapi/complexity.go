@@ -0,0 +1,151 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// pagedFields return up to `first` items, so the cost of their selections
+// is multiplied by the page size
+var pagedFields = map[string]bool{
+	"feed":          true,
+	"homeFeed":      true,
+	"communityFeed": true,
+	"recommended":   true,
+	"trendingPosts": true,
+	"trendingTags":  true,
+}
+
+// fieldCosts are fields costing more than 1, such as those calling an
+// upstream service per item
+var fieldCosts = map[string]int{
+	"post": 5,
+}
+
+// costModel computes the complexity and depth of an operation. variables
+// are the operation's variables after coerceVariables.
+type costModel struct {
+	fragments   map[string]*ast.FragmentDefinition
+	variables   map[string]interface{}
+	defaultPage int
+	maxPage     int
+}
+
+// measure returns the complexity and depth of op
+func (m costModel) measure(op *ast.OperationDefinition) (complexity, depth int, err error) {
+	return m.selectionSet(op.SelectionSet, map[string]bool{})
+}
+
+func (m costModel) selectionSet(set *ast.SelectionSet, visiting map[string]bool) (int, int, error) {
+	if set == nil {
+		return 0, 0, nil
+	}
+
+	total, deepest := 0, 0
+	for _, selection := range set.Selections {
+		var cost, depth int
+		var err error
+		switch s := selection.(type) {
+		case *ast.Field:
+			cost, depth, err = m.field(s, visiting)
+		case *ast.InlineFragment:
+			cost, depth, err = m.selectionSet(s.SelectionSet, visiting)
+		case *ast.FragmentSpread:
+			name := s.Name.Value
+			fragment, ok := m.fragments[name]
+			if !ok {
+				return 0, 0, fmt.Errorf("unknown fragment %q", name)
+			}
+			if visiting[name] {
+				return 0, 0, fmt.Errorf("fragment %q spreads itself", name)
+			}
+			visiting[name] = true
+			cost, depth, err = m.selectionSet(fragment.SelectionSet, visiting)
+			delete(visiting, name)
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		total += cost
+		if depth > deepest {
+			deepest = depth
+		}
+	}
+	return total, deepest, nil
+}
+
+func (m costModel) field(field *ast.Field, visiting map[string]bool) (int, int, error) {
+	childCost, childDepth, err := m.selectionSet(field.SelectionSet, visiting)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	name := field.Name.Value
+	cost, ok := fieldCosts[name]
+	if !ok {
+		cost = 1
+	}
+	if pagedFields[name] {
+		childCost *= m.pageSize(field)
+	}
+	return cost + childCost, childDepth + 1, nil
+}
+
+// pageSize returns the page size a paged field will be served with
+func (m costModel) pageSize(field *ast.Field) int {
+	size := m.defaultPage
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "first" {
+			continue
+		}
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(v.Value); err == nil {
+				size = n
+			}
+		case *ast.Variable:
+			if n, ok := m.variables[v.Name.Value].(int); ok {
+				size = n
+			}
+		}
+	}
+	if size <= 0 {
+		size = m.defaultPage
+	}
+	if size > m.maxPage {
+		size = m.maxPage
+	}
+	return size
+}
+
+// coerceVariables returns the scalar variables of op as execution will see
+// them: values are coerced to their declared types and missing ones take
+// their defaults. Invalid values are left for execution to reject.
+func coerceVariables(schema *graphql.Schema, op *ast.OperationDefinition, raw map[string]interface{}) map[string]interface{} {
+	vars := make(map[string]interface{}, len(op.VariableDefinitions))
+	for _, def := range op.VariableDefinitions {
+		typ := def.Type
+		if nonNull, ok := typ.(*ast.NonNull); ok {
+			typ = nonNull.Type
+		}
+		named, ok := typ.(*ast.Named)
+		if !ok {
+			continue
+		}
+		scalar, ok := schema.Type(named.Name.Value).(*graphql.Scalar)
+		if !ok {
+			continue
+		}
+
+		name := def.Variable.Name.Value
+		if value := raw[name]; value != nil {
+			vars[name] = scalar.ParseValue(value)
+		} else if def.DefaultValue != nil {
+			vars[name] = scalar.ParseLiteral(def.DefaultValue)
+		}
+	}
+	return vars
+}
@@ -0,0 +1,85 @@
+package graphqlapi
+
+import (
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// costSchema has a paged feed whose items have a post
+var costSchema = func() graphql.Schema {
+	post := graphql.NewObject(graphql.ObjectConfig{Name: "Post", Fields: graphql.Fields{
+		"title": &graphql.Field{Type: graphql.String},
+	}})
+	item := graphql.NewObject(graphql.ObjectConfig{Name: "Item", Fields: graphql.Fields{
+		"id":   &graphql.Field{Type: graphql.String},
+		"post": &graphql.Field{Type: post},
+	}})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: graphql.Fields{
+		"feed": &graphql.Field{Type: graphql.NewList(item), Args: graphql.FieldConfigArgument{
+			"first": &graphql.ArgumentConfig{Type: graphql.Int},
+		}},
+	}})})
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}()
+
+func TestCostModel(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		variables      map[string]interface{}
+		wantComplexity int
+		wantDepth      int
+		wantErr        bool
+	}{
+		{name: "default page size", query: `{ feed { id } }`, wantComplexity: 1 + 10, wantDepth: 2},
+		{name: "literal page size", query: `{ feed(first: 3) { id post { title } } }`, wantComplexity: 1 + 3*(1+5+1), wantDepth: 3},
+		{name: "page size is capped", query: `{ feed(first: 500) { id } }`, wantComplexity: 1 + 50, wantDepth: 2},
+		{name: "variable", query: `query($n: Int) { feed(first: $n) { id } }`, variables: map[string]interface{}{"n": float64(20)}, wantComplexity: 1 + 20, wantDepth: 2},
+		{name: "variable default", query: `query($n: Int = 40) { feed(first: $n) { id } }`, wantComplexity: 1 + 40, wantDepth: 2},
+		{name: "non-null variable default", query: `query($n: Int! = 40) { feed(first: $n) { id } }`, wantComplexity: 1 + 40, wantDepth: 2},
+		{name: "variable overrides its default", query: `query($n: Int = 40) { feed(first: $n) { id } }`, variables: map[string]interface{}{"n": float64(5)}, wantComplexity: 1 + 5, wantDepth: 2},
+		{name: "variable given as a string", query: `query($n: Int) { feed(first: $n) { id } }`, variables: map[string]interface{}{"n": "30"}, wantComplexity: 1 + 30, wantDepth: 2},
+		{
+			name:           "fragments",
+			query:          `query { feed(first: 2) { ...item } } fragment item on Item { id post { title } }`,
+			wantComplexity: 1 + 2*(1+5+1),
+			wantDepth:      3,
+		},
+		{name: "fragment spreading itself", query: `{ feed { ...a } } fragment a on Item { id ...a }`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+			if err != nil {
+				t.Fatal(err)
+			}
+			op, fragments, err := operation(doc, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			model := costModel{
+				fragments:   fragments,
+				variables:   coerceVariables(&costSchema, op, tt.variables),
+				defaultPage: 10,
+				maxPage:     50,
+			}
+			complexity, depth, err := model.measure(op)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if complexity != tt.wantComplexity || depth != tt.wantDepth {
+				t.Errorf("complexity, depth = %d, %d; want %d, %d", complexity, depth, tt.wantComplexity, tt.wantDepth)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package graphqlapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/CircleConnectApp/feed-service/controllers"
+	"github.com/CircleConnectApp/feed-service/settings"
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// request is a GraphQL request, sent as a JSON body or as query parameters
+type request struct {
+	Query         string                 `json:"query" form:"query"`
+	OperationName string                 `json:"operationName" form:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Handler serves GraphQL queries over HTTP
+type Handler struct {
+	schema   graphql.Schema
+	settings *settings.Store
+}
+
+// NewHandler builds the schema and returns a handler serving it
+func NewHandler(feedController *controllers.FeedController, trendingController *controllers.TrendingController, settingsStore *settings.Store) (*Handler, error) {
+	schema, err := NewSchema(feedController, trendingController, settingsStore)
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{schema: schema, settings: settingsStore}, nil
+}
+
+// Serve executes a query. Mutations are only accepted over POST.
+func (h *Handler) Serve(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req request
+	if c.Request.Method == http.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if raw := c.Query("variables"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &req.Variables); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "variables must be a JSON object"})
+				return
+			}
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "query is required"})
+		return
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"})})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+	if result := graphql.ValidateDocument(&h.schema, doc, nil); !result.IsValid {
+		c.JSON(http.StatusBadRequest, &graphql.Result{Errors: result.Errors})
+		return
+	}
+
+	op, fragments, err := operation(doc, req.OperationName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+	if op.Operation == ast.OperationTypeMutation && c.Request.Method != http.MethodPost {
+		c.Header("Allow", http.MethodPost)
+		c.JSON(http.StatusMethodNotAllowed, gin.H{"error": "Mutations must be sent with POST"})
+		return
+	}
+
+	current := h.settings.Current()
+	model := costModel{
+		fragments:   fragments,
+		variables:   coerceVariables(&h.schema, op, req.Variables),
+		defaultPage: current.Limits.DefaultPageSize,
+		maxPage:     current.Limits.MaxPageSize,
+	}
+	complexity, depth, err := model.measure(op)
+	if err == nil && depth > current.GraphQL.MaxDepth {
+		err = fmt.Errorf("query depth %d exceeds the limit of %d", depth, current.GraphQL.MaxDepth)
+	}
+	if err == nil && complexity > current.GraphQL.MaxComplexity {
+		err = fmt.Errorf("query complexity %d exceeds the limit of %d", complexity, current.GraphQL.MaxComplexity)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        h.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       withUser(c.Request.Context(), userID.(int)),
+	})
+	c.JSON(http.StatusOK, result)
+}
+
+// operation selects the operation to run and collects the document's fragments
+func operation(doc *ast.Document, name string) (*ast.OperationDefinition, map[string]*ast.FragmentDefinition, error) {
+	var ops []*ast.OperationDefinition
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, def := range doc.Definitions {
+		switch d := def.(type) {
+		case *ast.OperationDefinition:
+			if name == "" || (d.Name != nil && d.Name.Value == name) {
+				ops = append(ops, d)
+			}
+		case *ast.FragmentDefinition:
+			fragments[d.Name.Value] = d
+		}
+	}
+
+	switch {
+	case len(ops) == 0 && name != "":
+		return nil, nil, fmt.Errorf("unknown operation %q", name)
+	case len(ops) == 0:
+		return nil, nil, fmt.Errorf("document contains no operations")
+	case len(ops) > 1:
+		return nil, nil, fmt.Errorf("operationName is required when the document has several operations")
+	}
+	return ops[0], fragments, nil
+}
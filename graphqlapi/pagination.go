@@ -0,0 +1,104 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/CircleConnectApp/feed-service/models"
+)
+
+const cursorPrefix = "offset:"
+
+// connection is a window of a feed in Relay connection form
+type connection struct {
+	items       []models.FeedItem
+	offset      int
+	total       int
+	experiments map[string]string
+}
+
+type edge struct {
+	cursor string
+	node   models.FeedItem
+}
+
+// pageFetcher returns one page of a page-based feed
+type pageFetcher func(ctx context.Context, page, limit int) (*models.Feed, error)
+
+// encodeCursor makes an opaque cursor for the item at offset
+func encodeCursor(offset int) string {
+	return base64.StdEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+// decodeCursor returns the offset of the item after cursor; an empty cursor
+// starts at the beginning
+func decodeCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), cursorPrefix) {
+		return 0, errors.New("invalid cursor")
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(raw), cursorPrefix))
+	if err != nil || offset < 0 {
+		return 0, errors.New("invalid cursor")
+	}
+	return offset + 1, nil
+}
+
+// fetchWindow serves first items after the cursor from a page-based feed
+// with a single fetch. The page size is the smallest from first up to
+// maxLimit whose page holds the whole window; a cursor from a window of a
+// different size may need none, and the window then ends with the page of
+// size first and is short.
+func fetchWindow(ctx context.Context, fetch pageFetcher, after string, first, maxLimit int) (*connection, error) {
+	offset, err := decodeCursor(after)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := first
+	for size := first; size <= maxLimit; size++ {
+		if offset%size+first <= size {
+			limit = size
+			break
+		}
+	}
+	page, skip := offset/limit+1, offset%limit
+	feed, err := fetch(ctx, page, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &connection{offset: offset, total: feed.Total, experiments: feed.Experiments}
+	if skip < len(feed.Items) {
+		conn.items = append(conn.items, feed.Items[skip:min(skip+first, len(feed.Items))]...)
+	}
+	return conn, nil
+}
+
+func (c *connection) edges() []edge {
+	edges := make([]edge, len(c.items))
+	for i, item := range c.items {
+		edges[i] = edge{cursor: encodeCursor(c.offset + i), node: item}
+	}
+	return edges
+}
+
+func (c *connection) pageInfo() map[string]interface{} {
+	info := map[string]interface{}{
+		"hasNextPage":     c.offset+len(c.items) < c.total,
+		"hasPreviousPage": c.offset > 0,
+		"startCursor":     nil,
+		"endCursor":       nil,
+	}
+	if len(c.items) > 0 {
+		info["startCursor"] = encodeCursor(c.offset)
+		info["endCursor"] = encodeCursor(c.offset + len(c.items) - 1)
+	}
+	return info
+}
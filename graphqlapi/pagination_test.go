@@ -0,0 +1,104 @@
+package graphqlapi
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/CircleConnectApp/feed-service/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// numberedFeed serves pages of a feed of total items whose PostIDs end in
+// their position, and logs the pages fetched
+type numberedFeed struct {
+	total   int
+	fetched [][2]int // page and limit of each fetch
+}
+
+func (f *numberedFeed) fetch(ctx context.Context, page, limit int) (*models.Feed, error) {
+	f.fetched = append(f.fetched, [2]int{page, limit})
+	feed := &models.Feed{Total: f.total, Page: page, Limit: limit}
+	for i := (page - 1) * limit; i < min(page*limit, f.total); i++ {
+		var id primitive.ObjectID
+		id[len(id)-1] = byte(i)
+		feed.Items = append(feed.Items, models.FeedItem{PostID: id})
+	}
+	return feed, nil
+}
+
+func positions(items []models.FeedItem) []int {
+	out := []int{}
+	for _, item := range items {
+		out = append(out, int(item.PostID[len(item.PostID)-1]))
+	}
+	return out
+}
+
+func TestFetchWindow(t *testing.T) {
+	tests := []struct {
+		name        string
+		after       string
+		first       int
+		maxLimit    int
+		want        []int
+		wantFetched [2]int
+		wantNext    bool
+	}{
+		{name: "first page", first: 3, maxLimit: 10, want: []int{0, 1, 2}, wantFetched: [2]int{1, 3}, wantNext: true},
+		{name: "on a page boundary", after: encodeCursor(2), first: 3, maxLimit: 10, want: []int{3, 4, 5}, wantFetched: [2]int{2, 3}, wantNext: true},
+		// Items 2-4 lie in page 1 of size 5
+		{name: "off a page boundary", after: encodeCursor(1), first: 3, maxLimit: 10, want: []int{2, 3, 4}, wantFetched: [2]int{1, 5}, wantNext: true},
+		// Items 7-10 lie in page 2 of size 6
+		{name: "larger page further in", after: encodeCursor(6), first: 4, maxLimit: 10, want: []int{7, 8, 9, 10}, wantFetched: [2]int{2, 6}, wantNext: true},
+		{name: "short when no page fits", after: encodeCursor(1), first: 3, maxLimit: 4, want: []int{2}, wantFetched: [2]int{1, 3}, wantNext: true},
+		{name: "last items", after: encodeCursor(17), first: 3, maxLimit: 10, want: []int{18, 19}, wantFetched: [2]int{7, 3}},
+		{name: "past the end", after: encodeCursor(19), first: 3, maxLimit: 10, want: []int{}, wantFetched: [2]int{6, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed := &numberedFeed{total: 20}
+			conn, err := fetchWindow(context.Background(), feed.fetch, tt.after, tt.first, tt.maxLimit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := [][2]int{tt.wantFetched}; !reflect.DeepEqual(feed.fetched, want) {
+				t.Errorf("fetched pages %v, want %v", feed.fetched, want)
+			}
+			if got := positions(conn.items); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("items = %v, want %v", got, tt.want)
+			}
+			if got := conn.pageInfo()["hasNextPage"]; got != tt.wantNext {
+				t.Errorf("hasNextPage = %v, want %v", got, tt.wantNext)
+			}
+			for i, e := range conn.edges() {
+				if offset, _ := decodeCursor(e.cursor); offset-1 != tt.want[i] {
+					t.Errorf("edge %d cursor points at %d, want %d", i, offset-1, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	tests := []struct {
+		cursor  string
+		want    int
+		wantErr bool
+	}{
+		{cursor: "", want: 0},
+		{cursor: encodeCursor(0), want: 1},
+		{cursor: encodeCursor(41), want: 42},
+		{cursor: "not base64!", wantErr: true},
+		{cursor: "b2Zmc2V0Oi0x", wantErr: true}, // offset:-1
+		{cursor: "cGFnZTox", wantErr: true},     // page:1
+	}
+
+	for _, tt := range tests {
+		got, err := decodeCursor(tt.cursor)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("decodeCursor(%q) = %d, %v; want %d, error %v", tt.cursor, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,658 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/CircleConnectApp/feed-service/controllers"
+	"github.com/CircleConnectApp/feed-service/models"
+	"github.com/CircleConnectApp/feed-service/settings"
+	"github.com/CircleConnectApp/feed-service/trending"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+type userKey struct{}
+
+// withUser stores the authenticated user for the resolvers
+func withUser(ctx context.Context, userID int) context.Context {
+	return context.WithValue(ctx, userKey{}, userID)
+}
+
+func userFrom(ctx context.Context) (int, error) {
+	userID, ok := ctx.Value(userKey{}).(int)
+	if !ok {
+		return 0, &Error{message: "User not authenticated", code: "UNAUTHENTICATED"}
+	}
+	return userID, nil
+}
+
+// Error is a resolver error with a machine-readable code in its extensions
+type Error struct {
+	message string
+	code    string
+}
+
+func (e *Error) Error() string {
+	return e.message
+}
+
+// Extensions implements gqlerrors.ExtendedError
+func (e *Error) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+// toError translates a controller error into a GraphQL error
+func toError(err error) error {
+	var reqErr *controllers.Error
+	if !errors.As(err, &reqErr) {
+		return &Error{message: "Internal server error", code: "INTERNAL"}
+	}
+
+	code := "INTERNAL"
+	switch reqErr.Status {
+	case http.StatusBadRequest:
+		code = "BAD_REQUEST"
+	case http.StatusUnauthorized:
+		code = "UNAUTHENTICATED"
+	case http.StatusForbidden:
+		code = "FORBIDDEN"
+	case http.StatusNotFound:
+		code = "NOT_FOUND"
+	}
+	return &Error{message: reqErr.Message, code: code}
+}
+
+// resolvers run the REST controllers' logic for the schema
+type resolvers struct {
+	feed     *controllers.FeedController
+	trending *controllers.TrendingController
+	settings *settings.Store
+}
+
+// NewSchema builds the GraphQL schema over the feed and trending controllers
+func NewSchema(feedController *controllers.FeedController, trendingController *controllers.TrendingController, settingsStore *settings.Store) (graphql.Schema, error) {
+	r := &resolvers{feed: feedController, trending: trendingController, settings: settingsStore}
+
+	feedItem := graphql.NewObject(graphql.ObjectConfig{
+		Name: "FeedItem",
+		Fields: graphql.Fields{
+			"id":            itemField(graphql.NewNonNull(graphql.ID), func(i models.FeedItem) interface{} { return i.ID.Hex() }),
+			"postId":        itemField(graphql.NewNonNull(graphql.ID), func(i models.FeedItem) interface{} { return i.PostID.Hex() }),
+			"userId":        itemField(graphql.NewNonNull(graphql.Int), func(i models.FeedItem) interface{} { return i.UserID }),
+			"communityId":   itemField(graphql.NewNonNull(graphql.Int), func(i models.FeedItem) interface{} { return i.CommunityID }),
+			"communityName": itemField(graphql.String, func(i models.FeedItem) interface{} { return nonEmpty(i.CommunityName) }),
+			"communityIcon": itemField(graphql.String, func(i models.FeedItem) interface{} { return nonEmpty(i.CommunityIcon) }),
+			"title":         itemField(graphql.NewNonNull(graphql.String), func(i models.FeedItem) interface{} { return i.Title }),
+			"content": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "The post's text, cut to maxLength characters when given",
+				Args: graphql.FieldConfigArgument{
+					"maxLength": &graphql.ArgumentConfig{Type: graphql.Int},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					content := p.Source.(models.FeedItem).Content
+					if maxLength, ok := p.Args["maxLength"].(int); ok && maxLength >= 0 {
+						content = truncate(content, maxLength)
+					}
+					return content, nil
+				},
+			},
+			"likeCount":    itemField(graphql.NewNonNull(graphql.Int), func(i models.FeedItem) interface{} { return i.LikeCount }),
+			"mediaUrls":    itemField(graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))), func(i models.FeedItem) interface{} { return nonNilStrings(i.MediaURLs) }),
+			"tags":         itemField(graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))), func(i models.FeedItem) interface{} { return nonNilStrings(i.Tags) }),
+			"createdAt":    itemField(graphql.NewNonNull(graphql.DateTime), func(i models.FeedItem) interface{} { return i.CreatedAt }),
+			"relevance":    itemField(graphql.NewNonNull(graphql.Float), func(i models.FeedItem) interface{} { return i.Relevance }),
+			"authorName":   itemField(graphql.NewNonNull(graphql.String), func(i models.FeedItem) interface{} { return i.AuthorName }),
+			"authorAvatar": itemField(graphql.String, func(i models.FeedItem) interface{} { return nonEmpty(i.AuthorAvatar) }),
+			"source":       itemField(graphql.String, func(i models.FeedItem) interface{} { return nonEmpty(i.Source) }),
+			"pinned":       itemField(graphql.NewNonNull(graphql.Boolean), func(i models.FeedItem) interface{} { return i.Pinned }),
+		},
+	})
+
+	pageInfo := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage":     &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"hasPreviousPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"startCursor":     &graphql.Field{Type: graphql.String},
+			"endCursor":       &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	feedEdge := graphql.NewObject(graphql.ObjectConfig{
+		Name: "FeedEdge",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) { return p.Source.(edge).cursor, nil },
+			},
+			"node": &graphql.Field{
+				Type:    graphql.NewNonNull(feedItem),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) { return p.Source.(edge).node, nil },
+			},
+		},
+	})
+
+	feedConnection := graphql.NewObject(graphql.ObjectConfig{
+		Name: "FeedConnection",
+		Fields: graphql.Fields{
+			"edges": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(feedEdge))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) { return p.Source.(*connection).edges(), nil },
+			},
+			"nodes": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(feedItem))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) { return p.Source.(*connection).items, nil },
+			},
+			"pageInfo": &graphql.Field{
+				Type:    graphql.NewNonNull(pageInfo),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) { return p.Source.(*connection).pageInfo(), nil },
+			},
+			"totalCount": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) { return p.Source.(*connection).total, nil },
+			},
+			"experiments": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(experimentType))),
+				Description: "Experiment variants that ranked this page",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return experimentList(p.Source.(*connection).experiments), nil
+				},
+			},
+		},
+	})
+
+	preferences := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Preferences",
+		Fields: graphql.Fields{
+			"feedSortMethod": prefField(graphql.NewNonNull(graphql.String), func(p *models.UserPreference) interface{} { return p.FeedSortMethod }),
+			"feedSortPeriod": prefField(graphql.String, func(p *models.UserPreference) interface{} { return nonEmpty(p.FeedSortPeriod) }),
+			"preferedTags":   prefField(graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))), func(p *models.UserPreference) interface{} { return nonNilStrings(p.PreferedTags) }),
+			"excludedTags":   prefField(graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))), func(p *models.UserPreference) interface{} { return nonNilStrings(p.ExcludedTags) }),
+			"preferedCommunities": prefField(graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.Int))), func(p *models.UserPreference) interface{} {
+				if p.PreferedCommunities == nil {
+					return []int{}
+				}
+				return p.PreferedCommunities
+			}),
+			"updatedAt": prefField(graphql.NewNonNull(graphql.DateTime), func(p *models.UserPreference) interface{} { return p.UpdatedAt }),
+		},
+	})
+
+	trendingPost := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TrendingPost",
+		Fields: graphql.Fields{
+			"postId":      trendingField(graphql.NewNonNull(graphql.ID), func(t trendingPost) interface{} { return t.PostID }),
+			"communityId": trendingField(graphql.NewNonNull(graphql.Int), func(t trendingPost) interface{} { return t.CommunityID }),
+			"authorId":    trendingField(graphql.NewNonNull(graphql.Int), func(t trendingPost) interface{} { return t.AuthorID }),
+			"tags":        trendingField(graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))), func(t trendingPost) interface{} { return nonNilStrings(t.Tags) }),
+			"likes":       trendingField(graphql.NewNonNull(graphql.Int), func(t trendingPost) interface{} { return t.Likes }),
+			"velocity":    trendingField(graphql.NewNonNull(graphql.Float), func(t trendingPost) interface{} { return t.Velocity }),
+			"score":       trendingField(graphql.NewNonNull(graphql.Float), func(t trendingPost) interface{} { return t.Score }),
+			"post": &graphql.Field{
+				Type:        feedItem,
+				Description: "The post itself, fetched from the post service only when selected",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if post := p.Source.(trendingPost).post; post != nil {
+						return *post, nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+
+	trendingTag := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TrendingTag",
+		Fields: graphql.Fields{
+			"tag":   tagField(graphql.NewNonNull(graphql.String), func(t trending.Tag) interface{} { return t.Tag }),
+			"posts": tagField(graphql.NewNonNull(graphql.Int), func(t trending.Tag) interface{} { return t.Posts }),
+			"likes": tagField(graphql.NewNonNull(graphql.Int), func(t trending.Tag) interface{} { return t.Likes }),
+			"score": tagField(graphql.NewNonNull(graphql.Float), func(t trending.Tag) interface{} { return t.Score }),
+		},
+	})
+
+	pageArgs := func(extra graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+		args := graphql.FieldConfigArgument{
+			"first": &graphql.ArgumentConfig{Type: graphql.Int, Description: "Page size; defaults to the configured page size"},
+			"after": &graphql.ArgumentConfig{Type: graphql.String, Description: "Cursor of the item to continue after"},
+		}
+		for name, arg := range extra {
+			args[name] = arg
+		}
+		return args
+	}
+	trendingArgs := graphql.FieldConfigArgument{
+		"window":      &graphql.ArgumentConfig{Type: graphql.String, Description: "1h, 24h (default) or 7d"},
+		"communityId": &graphql.ArgumentConfig{Type: graphql.Int},
+		"first":       &graphql.ArgumentConfig{Type: graphql.Int},
+	}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"feed": &graphql.Field{
+				Type:        graphql.NewNonNull(feedConnection),
+				Description: "The personalized feed from joined communities",
+				Args: pageArgs(graphql.FieldConfigArgument{
+					"sortBy":      &graphql.ArgumentConfig{Type: graphql.String},
+					"period":      &graphql.ArgumentConfig{Type: graphql.String},
+					"tags":        &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+					"communityId": &graphql.ArgumentConfig{Type: graphql.Int},
+				}),
+				Resolve: r.resolveFeed,
+			},
+			"homeFeed": &graphql.Field{
+				Type:        graphql.NewNonNull(feedConnection),
+				Description: "Joined-community posts interleaved with recommendations",
+				Args: pageArgs(graphql.FieldConfigArgument{
+					"sortBy": &graphql.ArgumentConfig{Type: graphql.String},
+					"period": &graphql.ArgumentConfig{Type: graphql.String},
+				}),
+				Resolve: r.resolveHomeFeed,
+			},
+			"communityFeed": &graphql.Field{
+				Type:        graphql.NewNonNull(feedConnection),
+				Description: "The feed of one community",
+				Args: pageArgs(graphql.FieldConfigArgument{
+					"communityId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"sort":        &graphql.ArgumentConfig{Type: graphql.String, Description: "hot (default), new, top or rising"},
+					"period":      &graphql.ArgumentConfig{Type: graphql.String},
+				}),
+				Resolve: r.resolveCommunityFeed,
+			},
+			"recommended": &graphql.Field{
+				Type:        graphql.NewNonNull(feedConnection),
+				Description: "Recommended posts",
+				Args:        pageArgs(nil),
+				Resolve:     r.resolveRecommended,
+			},
+			"preferences": &graphql.Field{
+				Type:    graphql.NewNonNull(preferences),
+				Resolve: r.resolvePreferences,
+			},
+			"trendingPosts": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(trendingPost))),
+				Args:    trendingArgs,
+				Resolve: r.resolveTrendingPosts,
+			},
+			"trendingTags": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(trendingTag))),
+				Args:    trendingArgs,
+				Resolve: r.resolveTrendingTags,
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"updatePreferences": &graphql.Field{
+				Type: graphql.NewNonNull(preferences),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewInputObject(graphql.InputObjectConfig{
+						Name: "PreferencesInput",
+						Fields: graphql.InputObjectConfigFieldMap{
+							"feedSortMethod":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+							"feedSortPeriod":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+							"preferedTags":        &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+							"excludedTags":        &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+							"preferedCommunities": &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.Int))},
+						},
+					}))},
+				},
+				Resolve: r.resolveUpdatePreferences,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+var experimentType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Experiment",
+	Fields: graphql.Fields{
+		"name":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"variant": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+	},
+})
+
+// trendingPost is a trending post with the post itself when it was selected
+type trendingPost struct {
+	trending.Post
+	post *models.FeedItem
+}
+
+func (r *resolvers) resolveFeed(p graphql.ResolveParams) (interface{}, error) {
+	userID, err := userFrom(p.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	query := models.FeedQuery{
+		SortBy:      stringArg(p.Args, "sortBy"),
+		Period:      stringArg(p.Args, "period"),
+		Tags:        stringsArg(p.Args, "tags"),
+		CommunityID: intArg(p.Args, "communityId"),
+	}
+	if err := validate(&query); err != nil {
+		return nil, err
+	}
+
+	return r.window(p, userID, "personal", func(ctx context.Context, page, limit int) (*models.Feed, error) {
+		query.Page, query.Limit = page, limit
+		return r.feed.PersonalFeed(ctx, userID, query)
+	})
+}
+
+func (r *resolvers) resolveHomeFeed(p graphql.ResolveParams) (interface{}, error) {
+	userID, err := userFrom(p.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	query := models.FeedQuery{SortBy: stringArg(p.Args, "sortBy"), Period: stringArg(p.Args, "period")}
+	if err := validate(&query); err != nil {
+		return nil, err
+	}
+
+	return r.window(p, userID, "home", func(ctx context.Context, page, limit int) (*models.Feed, error) {
+		query.Page, query.Limit = page, limit
+		return r.feed.HomeFeed(ctx, userID, query)
+	})
+}
+
+func (r *resolvers) resolveCommunityFeed(p graphql.ResolveParams) (interface{}, error) {
+	userID, err := userFrom(p.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	communityID := intArg(p.Args, "communityId")
+	if communityID <= 0 {
+		return nil, &Error{message: "Invalid community ID", code: "BAD_REQUEST"}
+	}
+	query := models.CommunityFeedQuery{Sort: stringArg(p.Args, "sort"), Period: stringArg(p.Args, "period")}
+	if err := validate(&query); err != nil {
+		return nil, err
+	}
+
+	return r.window(p, userID, "community", func(ctx context.Context, page, limit int) (*models.Feed, error) {
+		query.Page, query.Limit = page, limit
+		return r.feed.CommunityFeed(ctx, userID, communityID, query)
+	})
+}
+
+func (r *resolvers) resolveRecommended(p graphql.ResolveParams) (interface{}, error) {
+	userID, err := userFrom(p.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.window(p, userID, "recommended", func(ctx context.Context, page, limit int) (*models.Feed, error) {
+		return r.feed.RecommendedFeed(ctx, userID, models.FeedQuery{Page: page, Limit: limit})
+	})
+}
+
+// window serves the page of a feed selected by the first and after
+// arguments, and records the impressions of the items it serves when the
+// query selects them
+func (r *resolvers) window(p graphql.ResolveParams, userID int, feedName string, fetch pageFetcher) (interface{}, error) {
+	limits := r.settings.Current().Limits
+	first := intArg(p.Args, "first")
+	if first <= 0 {
+		first = limits.DefaultPageSize
+	}
+	if first > limits.MaxPageSize {
+		first = limits.MaxPageSize
+	}
+
+	// Only look up authors and communities that are shown, and only record
+	// impressions of items that are served
+	ctx := p.Context
+	items, hydrated := itemSelection(p.Info.FieldASTs, p.Info.Fragments)
+	if !hydrated {
+		ctx = controllers.WithoutHydration(ctx)
+	}
+
+	conn, err := fetchWindow(ctx, func(ctx context.Context, page, limit int) (*models.Feed, error) {
+		feed, err := fetch(ctx, page, limit)
+		if err != nil {
+			return nil, toError(err)
+		}
+		return feed, nil
+	}, stringArg(p.Args, "after"), first, limits.MaxPageSize)
+	if err != nil {
+		var gqlErr *Error
+		if errors.As(err, &gqlErr) {
+			return nil, err
+		}
+		return nil, &Error{message: err.Error(), code: "BAD_REQUEST"}
+	}
+	if items {
+		r.feed.RecordImpressions(p.Context, userID, feedName, conn.items, conn.offset, conn.experiments)
+	}
+	return conn, nil
+}
+
+func (r *resolvers) resolvePreferences(p graphql.ResolveParams) (interface{}, error) {
+	userID, err := userFrom(p.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	pref, err := r.feed.Preferences(p.Context, userID)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return pref, nil
+}
+
+func (r *resolvers) resolveUpdatePreferences(p graphql.ResolveParams) (interface{}, error) {
+	userID, err := userFrom(p.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	input, _ := p.Args["input"].(map[string]interface{})
+	req := models.UpdatePreferenceRequest{
+		FeedSortMethod: stringArg(input, "feedSortMethod"),
+		FeedSortPeriod: stringArg(input, "feedSortPeriod"),
+		PreferedTags:   stringsArg(input, "preferedTags"),
+		ExcludedTags:   stringsArg(input, "excludedTags"),
+	}
+	if ids, ok := input["preferedCommunities"].([]interface{}); ok {
+		req.PreferedCommunities = make([]int, 0, len(ids))
+		for _, id := range ids {
+			req.PreferedCommunities = append(req.PreferedCommunities, id.(int))
+		}
+	}
+	if err := validate(&req); err != nil {
+		return nil, err
+	}
+
+	pref, err := r.feed.UpdatePreferences(p.Context, userID, req)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return pref, nil
+}
+
+func (r *resolvers) resolveTrendingPosts(p graphql.ResolveParams) (interface{}, error) {
+	posts, err := r.trending.TrendingPosts(p.Context, stringArg(p.Args, "window"), intArg(p.Args, "communityId"), intArg(p.Args, "first"))
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	out := make([]trendingPost, len(posts))
+	for i, post := range posts {
+		out[i].Post = post
+	}
+	if selects(p.Info.FieldASTs, p.Info.Fragments, "post") {
+		r.attachPosts(p.Context, out)
+	}
+	return out, nil
+}
+
+// attachPosts fetches trending posts from the post service, hydrating them
+// together. Posts that cannot be fetched are left null.
+func (r *resolvers) attachPosts(ctx context.Context, posts []trendingPost) {
+	ids := make([]string, len(posts))
+	for i, tp := range posts {
+		ids[i] = tp.PostID
+	}
+	fetched := r.feed.Posts(ctx, ids)
+	for i := range posts {
+		posts[i].post = fetched[posts[i].PostID]
+	}
+}
+
+func (r *resolvers) resolveTrendingTags(p graphql.ResolveParams) (interface{}, error) {
+	tags, err := r.trending.TrendingTags(p.Context, stringArg(p.Args, "window"), intArg(p.Args, "communityId"), intArg(p.Args, "first"))
+	if err != nil {
+		return nil, toError(err)
+	}
+	return tags, nil
+}
+
+// selects reports whether any of fields selects a sub-field called name,
+// directly or through fragments
+func selects(fields []*ast.Field, fragments map[string]ast.Definition, name string) bool {
+	return len(subFields(fields, fragments, name)) > 0
+}
+
+// subFields returns the sub-fields called name that fields select, directly
+// or through fragments
+func subFields(fields []*ast.Field, fragments map[string]ast.Definition, name string) []*ast.Field {
+	var found []*ast.Field
+	var walk func(set *ast.SelectionSet)
+	walk = func(set *ast.SelectionSet) {
+		if set == nil {
+			return
+		}
+		for _, selection := range set.Selections {
+			switch s := selection.(type) {
+			case *ast.Field:
+				if s.Name.Value == name {
+					found = append(found, s)
+				}
+			case *ast.InlineFragment:
+				walk(s.SelectionSet)
+			case *ast.FragmentSpread:
+				if fragment, ok := fragments[s.Name.Value].(*ast.FragmentDefinition); ok {
+					walk(fragment.SelectionSet)
+				}
+			}
+		}
+	}
+	for _, field := range fields {
+		walk(field.SelectionSet)
+	}
+	return found
+}
+
+// hydratedFields are the FeedItem fields filled in from the user and
+// community services
+var hydratedFields = []string{"authorName", "authorAvatar", "communityName", "communityIcon"}
+
+// itemSelection reports whether a feed connection's fields select any of
+// its items, and whether they select any of the items' hydrated fields
+func itemSelection(fields []*ast.Field, fragments map[string]ast.Definition) (items, hydrated bool) {
+	nodes := append(subFields(fields, fragments, "nodes"), subFields(subFields(fields, fragments, "edges"), fragments, "node")...)
+	for _, name := range hydratedFields {
+		if selects(nodes, fragments, name) {
+			return true, true
+		}
+	}
+	return len(nodes) > 0, false
+}
+
+// validate checks a request against the same binding rules as the REST API
+func validate(req interface{}) error {
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return &Error{message: err.Error(), code: "BAD_REQUEST"}
+	}
+	return nil
+}
+
+func itemField(typ graphql.Output, get func(models.FeedItem) interface{}) *graphql.Field {
+	return &graphql.Field{Type: typ, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(models.FeedItem)), nil
+	}}
+}
+
+func prefField(typ graphql.Output, get func(*models.UserPreference) interface{}) *graphql.Field {
+	return &graphql.Field{Type: typ, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(*models.UserPreference)), nil
+	}}
+}
+
+func trendingField(typ graphql.Output, get func(trendingPost) interface{}) *graphql.Field {
+	return &graphql.Field{Type: typ, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(trendingPost)), nil
+	}}
+}
+
+func tagField(typ graphql.Output, get func(trending.Tag) interface{}) *graphql.Field {
+	return &graphql.Field{Type: typ, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(trending.Tag)), nil
+	}}
+}
+
+func experimentList(experiments map[string]string) []map[string]interface{} {
+	list := make([]map[string]interface{}, 0, len(experiments))
+	for name, variant := range experiments {
+		list = append(list, map[string]interface{}{"name": name, "variant": variant})
+	}
+	return list
+}
+
+func stringArg(args map[string]interface{}, name string) string {
+	s, _ := args[name].(string)
+	return s
+}
+
+func intArg(args map[string]interface{}, name string) int {
+	n, _ := args[name].(int)
+	return n
+}
+
+// stringsArg returns a list argument, or nil when it was not given
+func stringsArg(args map[string]interface{}, name string) []string {
+	values, ok := args[name].([]interface{})
+	if !ok {
+		return nil
+	}
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		out = append(out, v.(string))
+	}
+	return out
+}
+
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+func nonEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// truncate cuts s to at most n characters
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n])
+}
@@ -0,0 +1,56 @@
+package graphqlapi
+
+import (
+	"testing"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+func TestItemSelection(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		wantItems    bool
+		wantHydrated bool
+	}{
+		{name: "total only", query: `{ feed { totalCount pageInfo { hasNextPage } } }`},
+		{name: "cursors only", query: `{ feed { edges { cursor } } }`},
+		{name: "nodes", query: `{ feed { nodes { postId title } } }`, wantItems: true},
+		{name: "edge nodes", query: `{ feed { edges { cursor node { postId } } } }`, wantItems: true},
+		{name: "author of nodes", query: `{ feed { nodes { authorName } } }`, wantItems: true, wantHydrated: true},
+		{name: "community of edge nodes", query: `{ feed { edges { node { communityIcon } } } }`, wantItems: true, wantHydrated: true},
+		{name: "inline fragment", query: `{ feed { ... on FeedConnection { nodes { authorAvatar } } } }`, wantItems: true, wantHydrated: true},
+		{
+			name:         "fragments",
+			query:        `{ feed { ...page } } fragment page on FeedConnection { edges { node { ...item } } } fragment item on FeedItem { communityName }`,
+			wantItems:    true,
+			wantHydrated: true,
+		},
+		{name: "unhydrated fragment", query: `{ feed { nodes { ...item } } } fragment item on FeedItem { title }`, wantItems: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fields []*ast.Field
+			fragments := map[string]ast.Definition{}
+			for _, def := range doc.Definitions {
+				switch d := def.(type) {
+				case *ast.OperationDefinition:
+					fields = append(fields, d.SelectionSet.Selections[0].(*ast.Field))
+				case *ast.FragmentDefinition:
+					fragments[d.Name.Value] = d
+				}
+			}
+
+			items, hydrated := itemSelection(fields, fragments)
+			if items != tt.wantItems || hydrated != tt.wantHydrated {
+				t.Errorf("itemSelection() = %v, %v, want %v, %v", items, hydrated, tt.wantItems, tt.wantHydrated)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package grpcapi
+
+import (
+	"context"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/controllers"
+	"github.com/CircleConnectApp/feed-service/models"
+	feedv1 "github.com/CircleConnectApp/feed-service/proto/feed/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// FeedServer implements feedv1.FeedServiceServer on the REST controllers'
+// logic
+type FeedServer struct {
+	feedv1.UnimplementedFeedServiceServer
+	feed     *controllers.FeedController
+	trending *controllers.TrendingController
+}
+
+// GetFeed returns the user's personalized feed
+func (s *FeedServer) GetFeed(ctx context.Context, req *feedv1.GetFeedRequest) (*feedv1.Feed, error) {
+	userID, err := userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := models.FeedQuery{
+		SortBy:      req.SortBy,
+		Period:      req.Period,
+		Tags:        req.Tags,
+		CommunityID: int(req.CommunityId),
+		Page:        int(req.Page),
+		Limit:       int(req.Limit),
+	}
+	if err := validate(&query); err != nil {
+		return nil, err
+	}
+
+	feed, err := s.feed.PersonalFeed(ctx, userID, query)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	s.feed.RecordImpressions(ctx, userID, "personal", feed.Items, (feed.Page-1)*feed.Limit, feed.Experiments)
+	return toFeed(feed), nil
+}
+
+// GetRecommended returns recommended posts for the user
+func (s *FeedServer) GetRecommended(ctx context.Context, req *feedv1.GetRecommendedRequest) (*feedv1.Feed, error) {
+	userID, err := userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	feed, err := s.feed.RecommendedFeed(ctx, userID, models.FeedQuery{Page: int(req.Page), Limit: int(req.Limit)})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	s.feed.RecordImpressions(ctx, userID, "recommended", feed.Items, (feed.Page-1)*feed.Limit, feed.Experiments)
+	return toFeed(feed), nil
+}
+
+// GetPreferences returns the user's feed preferences
+func (s *FeedServer) GetPreferences(ctx context.Context, _ *feedv1.GetPreferencesRequest) (*feedv1.Preferences, error) {
+	userID, err := userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pref, err := s.feed.Preferences(ctx, userID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toPreferences(pref), nil
+}
+
+// UpdatePreferences updates the fields of the user's preferences set in req
+func (s *FeedServer) UpdatePreferences(ctx context.Context, req *feedv1.UpdatePreferencesRequest) (*feedv1.Preferences, error) {
+	userID, err := userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	update := models.UpdatePreferenceRequest{
+		FeedSortMethod: req.FeedSortMethod,
+		FeedSortPeriod: req.FeedSortPeriod,
+	}
+	if req.PreferedTags != nil {
+		update.PreferedTags = append([]string{}, req.PreferedTags.Values...)
+	}
+	if req.ExcludedTags != nil {
+		update.ExcludedTags = append([]string{}, req.ExcludedTags.Values...)
+	}
+	if req.PreferedCommunities != nil {
+		update.PreferedCommunities = make([]int, 0, len(req.PreferedCommunities.Values))
+		for _, id := range req.PreferedCommunities.Values {
+			update.PreferedCommunities = append(update.PreferedCommunities, int(id))
+		}
+	}
+	if err := validate(&update); err != nil {
+		return nil, err
+	}
+
+	pref, err := s.feed.UpdatePreferences(ctx, userID, update)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toPreferences(pref), nil
+}
+
+// RecordInteractions logs the user's interactions with feed items
+func (s *FeedServer) RecordInteractions(ctx context.Context, req *feedv1.RecordInteractionsRequest) (*feedv1.RecordInteractionsResponse, error) {
+	userID, err := userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	interactions := models.InteractionRequest{Events: make([]models.InteractionEvent, 0, len(req.Events))}
+	for _, e := range req.Events {
+		event := models.InteractionEvent{
+			PostID:   e.PostId,
+			Type:     e.Type,
+			Feed:     e.Feed,
+			Position: int(e.Position),
+		}
+		if e.OccurredAt != nil {
+			occurredAt := e.OccurredAt.AsTime()
+			event.OccurredAt = &occurredAt
+		}
+		interactions.Events = append(interactions.Events, event)
+	}
+	if err := validate(&interactions); err != nil {
+		return nil, err
+	}
+
+	accepted, err := s.feed.LogInteractions(ctx, userID, interactions)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &feedv1.RecordInteractionsResponse{Accepted: int32(accepted)}, nil
+}
+
+// IngestPostEvents records likes and unlikes reported by the post service
+func (s *FeedServer) IngestPostEvents(ctx context.Context, req *feedv1.IngestPostEventsRequest) (*feedv1.IngestPostEventsResponse, error) {
+	events := models.PostEventsRequest{Events: make([]models.PostEvent, 0, len(req.Events))}
+	for _, e := range req.Events {
+		event := models.PostEvent{
+			Type:        e.Type,
+			PostID:      e.PostId,
+			CommunityID: int(e.CommunityId),
+			AuthorID:    int(e.AuthorId),
+			Tags:        e.Tags,
+		}
+		if e.OccurredAt != nil {
+			event.OccurredAt = e.OccurredAt.AsTime()
+		}
+		events.Events = append(events.Events, event)
+	}
+	if err := validate(&events); err != nil {
+		return nil, err
+	}
+
+	accepted, err := s.trending.RecordPostEvents(ctx, events)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &feedv1.IngestPostEventsResponse{Accepted: int32(accepted)}, nil
+}
+
+func toFeed(feed *models.Feed) *feedv1.Feed {
+	out := &feedv1.Feed{
+		Items:       make([]*feedv1.FeedItem, 0, len(feed.Items)),
+		Total:       int32(feed.Total),
+		Page:        int32(feed.Page),
+		Limit:       int32(feed.Limit),
+		Experiments: feed.Experiments,
+	}
+	for _, item := range feed.Items {
+		out.Items = append(out.Items, &feedv1.FeedItem{
+			Id:            item.ID.Hex(),
+			PostId:        item.PostID.Hex(),
+			UserId:        int32(item.UserID),
+			CommunityId:   int32(item.CommunityID),
+			Title:         item.Title,
+			Content:       item.Content,
+			LikeCount:     int32(item.LikeCount),
+			MediaUrls:     item.MediaURLs,
+			Tags:          item.Tags,
+			CreatedAt:     timestamp(item.CreatedAt),
+			Relevance:     item.Relevance,
+			AuthorName:    item.AuthorName,
+			AuthorAvatar:  item.AuthorAvatar,
+			Source:        item.Source,
+			Pinned:        item.Pinned,
+			CommunityName: item.CommunityName,
+			CommunityIcon: item.CommunityIcon,
+		})
+	}
+	return out
+}
+
+func toPreferences(pref *models.UserPreference) *feedv1.Preferences {
+	out := &feedv1.Preferences{
+		UserId:         int32(pref.UserID),
+		FeedSortMethod: pref.FeedSortMethod,
+		FeedSortPeriod: pref.FeedSortPeriod,
+		PreferedTags:   pref.PreferedTags,
+		ExcludedTags:   pref.ExcludedTags,
+		UpdatedAt:      timestamp(pref.UpdatedAt),
+	}
+	for _, id := range pref.PreferedCommunities {
+		out.PreferedCommunities = append(out.PreferedCommunities, int32(id))
+	}
+	return out
+}
+
+// timestamp converts t, leaving zero times unset
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
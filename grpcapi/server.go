@@ -0,0 +1,98 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/CircleConnectApp/feed-service/controllers"
+	"github.com/CircleConnectApp/feed-service/health"
+	"github.com/CircleConnectApp/feed-service/middleware"
+	feedv1 "github.com/CircleConnectApp/feed-service/proto/feed/v1"
+	"github.com/CircleConnectApp/feed-service/ratelimit"
+	"github.com/CircleConnectApp/feed-service/settings"
+	"github.com/gin-gonic/gin/binding"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// NewServer creates the gRPC server with the feed, health and reflection
+// services. Calls are authenticated with the same JWTs as the REST API and
+// draw from the same rate limit buckets in limitStore.
+func NewServer(jwtSecret string, checker *health.Checker, settingsStore *settings.Store, limitStore ratelimit.Store, feedController *controllers.FeedController, trendingController *controllers.TrendingController) *grpc.Server {
+	limitRules := func(group string) (ratelimit.Rule, ratelimit.Rule) {
+		return settingsStore.Current().RateLimitRules(group)
+	}
+
+	srv := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(
+			middleware.GRPCRequestIDInterceptor(),
+			middleware.GRPCLoggerInterceptor(),
+			middleware.GRPCMetricsInterceptor(),
+			middleware.GRPCAuthInterceptor(jwtSecret, map[string]string{
+				feedv1.FeedService_IngestPostEvents_FullMethodName: "service",
+			}),
+			middleware.GRPCRateLimitInterceptor(limitStore, map[string]string{
+				feedv1.FeedService_GetFeed_FullMethodName:            "feed",
+				feedv1.FeedService_GetRecommended_FullMethodName:     "feed",
+				feedv1.FeedService_GetPreferences_FullMethodName:     "preferences",
+				feedv1.FeedService_UpdatePreferences_FullMethodName:  "preferences",
+				feedv1.FeedService_RecordInteractions_FullMethodName: "interactions",
+			}, limitRules),
+		),
+	)
+
+	feedv1.RegisterFeedServiceServer(srv, &FeedServer{feed: feedController, trending: trendingController})
+	healthpb.RegisterHealthServer(srv, health.NewGRPCServer(checker, feedv1.FeedService_ServiceDesc.ServiceName))
+	reflection.Register(srv)
+	return srv
+}
+
+// userID returns the authenticated user of a call
+func userID(ctx context.Context) (int, error) {
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return 0, status.Error(codes.Unauthenticated, "User not authenticated")
+	}
+	return claims.UserID, nil
+}
+
+// validate checks a request against the same binding rules as the REST API
+func validate(req interface{}) error {
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+// toStatus translates a controller error into a gRPC status
+func toStatus(err error) error {
+	var reqErr *controllers.Error
+	if !errors.As(err, &reqErr) {
+		return status.Error(codes.Internal, "Internal server error")
+	}
+
+	code := codes.Internal
+	switch reqErr.Status {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusConflict:
+		code = codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	case http.StatusGatewayTimeout:
+		code = codes.DeadlineExceeded
+	}
+	return status.Error(code, reqErr.Message)
+}
@@ -0,0 +1,36 @@
+package grpcapi
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/CircleConnectApp/feed-service/controllers"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantCode    codes.Code
+		wantMessage string
+	}{
+		{name: "bad request", err: &controllers.Error{Status: http.StatusBadRequest, Message: "Invalid limit"}, wantCode: codes.InvalidArgument, wantMessage: "Invalid limit"},
+		{name: "forbidden", err: &controllers.Error{Status: http.StatusForbidden, Message: "Only members can view this community"}, wantCode: codes.PermissionDenied, wantMessage: "Only members can view this community"},
+		{name: "not found", err: &controllers.Error{Status: http.StatusNotFound, Message: "Community not found"}, wantCode: codes.NotFound, wantMessage: "Community not found"},
+		{name: "timeout", err: &controllers.Error{Status: http.StatusGatewayTimeout, Message: "Failed to build feed"}, wantCode: codes.DeadlineExceeded, wantMessage: "Failed to build feed"},
+		{name: "server error", err: &controllers.Error{Status: http.StatusInternalServerError, Message: "Failed to build feed"}, wantCode: codes.Internal, wantMessage: "Failed to build feed"},
+		{name: "unexpected error", err: errors.New("boom"), wantCode: codes.Internal, wantMessage: "Internal server error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.Convert(toStatus(tt.err))
+			if st.Code() != tt.wantCode || st.Message() != tt.wantMessage {
+				t.Errorf("toStatus() = %v %q, want %v %q", st.Code(), st.Message(), tt.wantCode, tt.wantMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package health
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCServer implements the standard gRPC health service on top of the
+// readiness checks. The empty service name and each name in services report
+// SERVING while the instance is ready or degraded.
+type GRPCServer struct {
+	healthpb.UnimplementedHealthServer
+	checker  *Checker
+	services map[string]bool
+}
+
+// NewGRPCServer creates a health service reporting the checker's readiness
+// for the named gRPC services
+func NewGRPCServer(checker *Checker, services ...string) *GRPCServer {
+	known := map[string]bool{"": true}
+	for _, service := range services {
+		known[service] = true
+	}
+	return &GRPCServer{checker: checker, services: known}
+}
+
+// Check runs the readiness checks
+func (s *GRPCServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if !s.services[req.Service] {
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", req.Service)
+	}
+
+	report := s.checker.Run(ctx)
+	if report.Status == "not_ready" || report.Status == "shutting_down" {
+		return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING}, nil
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
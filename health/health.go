@@ -0,0 +1,173 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/metrics"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// CheckFunc probes a single dependency, returning an error if it is unusable
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name     string
+	critical bool
+	fn       CheckFunc
+}
+
+// CheckResult is the outcome of one dependency check
+type CheckResult struct {
+	Status    string  `json:"status"` // "up" or "down"
+	Critical  bool    `json:"critical"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the body returned by the readiness endpoint
+type Report struct {
+	Status string                 `json:"status"` // "ready", "degraded", "not_ready" or "shutting_down"
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// Checker runs dependency checks for readiness and tracks whether the
+// instance is draining for shutdown
+type Checker struct {
+	timeout  time.Duration
+	checks   []check
+	draining atomic.Bool
+}
+
+// NewChecker creates a Checker that gives each check at most timeout to finish
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Register adds a named check. A failing critical check makes the instance
+// not ready; a failing non-critical check only marks it degraded.
+func (hc *Checker) Register(name string, critical bool, fn CheckFunc) {
+	hc.checks = append(hc.checks, check{name: name, critical: critical, fn: fn})
+}
+
+// SetDraining marks the instance as shutting down so readiness fails
+// immediately and load balancers stop routing new traffic to it
+func (hc *Checker) SetDraining() {
+	hc.draining.Store(true)
+}
+
+// Run executes every check concurrently and summarises the results
+func (hc *Checker) Run(ctx context.Context) Report {
+	if hc.draining.Load() {
+		return Report{Status: "shutting_down"}
+	}
+
+	results := make(map[string]CheckResult, len(hc.checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, chk := range hc.checks {
+		wg.Add(1)
+		go func(chk check) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, hc.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := chk.fn(checkCtx)
+			result := CheckResult{
+				Status:    "up",
+				Critical:  chk.critical,
+				LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				result.Status = "down"
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			results[chk.name] = result
+			mu.Unlock()
+		}(chk)
+	}
+	wg.Wait()
+
+	report := Report{Status: "ready", Checks: results}
+	for _, result := range results {
+		if result.Status == "up" {
+			continue
+		}
+		if result.Critical {
+			report.Status = "not_ready"
+			break
+		}
+		report.Status = "degraded"
+	}
+	return report
+}
+
+// Liveness reports that the process is running and able to serve requests.
+// It never touches dependencies, so a dependency outage does not trigger restarts.
+func (hc *Checker) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "ok",
+		"service": "feed-service",
+	})
+}
+
+// Readiness reports whether the instance should receive traffic, with the
+// status and latency of each dependency
+func (hc *Checker) Readiness(c *gin.Context) {
+	report := hc.Run(c.Request.Context())
+
+	status := http.StatusOK
+	if report.Status == "not_ready" || report.Status == "shutting_down" {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+// MongoCheck pings the primary of client
+func MongoCheck(client *mongo.Client) CheckFunc {
+	return func(ctx context.Context) error {
+		return client.Ping(ctx, readpref.Primary())
+	}
+}
+
+// PostgresCheck pings db
+func PostgresCheck(db *sql.DB) CheckFunc {
+	return func(ctx context.Context) error {
+		start := time.Now()
+		err := db.PingContext(ctx)
+		metrics.ObserveDB("postgres", "ping", start, err)
+		return err
+	}
+}
+
+// HTTPCheck treats an upstream as reachable when a GET to url returns any
+// response below 500
+func HTTPCheck(client *http.Client, url string) CheckFunc {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("status %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,113 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func up(context.Context) error   { return nil }
+func down(context.Context) error { return errors.New("unreachable") }
+
+// slow blocks until its check times out
+func slow(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestReadiness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	type registration struct {
+		name     string
+		critical bool
+		fn       CheckFunc
+	}
+	tests := []struct {
+		name       string
+		checks     []registration
+		draining   bool
+		wantCode   int
+		wantStatus string
+		wantDown   []string
+	}{
+		{name: "all up", checks: []registration{{"mongo", true, up}, {"post_service", false, up}}, wantCode: http.StatusOK, wantStatus: "ready"},
+		{name: "non-critical down", checks: []registration{{"mongo", true, up}, {"post_service", false, down}}, wantCode: http.StatusOK, wantStatus: "degraded", wantDown: []string{"post_service"}},
+		{name: "critical down", checks: []registration{{"mongo", true, down}, {"post_service", false, down}}, wantCode: http.StatusServiceUnavailable, wantStatus: "not_ready", wantDown: []string{"mongo", "post_service"}},
+		{name: "critical timeout", checks: []registration{{"postgres", true, slow}}, wantCode: http.StatusServiceUnavailable, wantStatus: "not_ready", wantDown: []string{"postgres"}},
+		{name: "draining", checks: []registration{{"mongo", true, up}}, draining: true, wantCode: http.StatusServiceUnavailable, wantStatus: "shutting_down"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc := NewChecker(10 * time.Millisecond)
+			for _, chk := range tt.checks {
+				hc.Register(chk.name, chk.critical, chk.fn)
+			}
+			if tt.draining {
+				hc.SetDraining()
+			}
+
+			r := gin.New()
+			r.GET("/readyz", hc.Readiness)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if w.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", w.Code, tt.wantCode)
+			}
+			var report Report
+			if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+				t.Fatal(err)
+			}
+			if report.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", report.Status, tt.wantStatus)
+			}
+			down := map[string]bool{}
+			for _, name := range tt.wantDown {
+				down[name] = true
+			}
+			for name, result := range report.Checks {
+				if (result.Status == "down") != down[name] {
+					t.Errorf("%s = %q, want down %v", name, result.Status, down[name])
+				}
+				if result.Status == "down" && result.Error == "" {
+					t.Errorf("%s is down without an error", name)
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "ok", status: http.StatusOK},
+		// Any answer shows the service is reachable
+		{name: "not found", status: http.StatusNotFound},
+		{name: "server error", status: http.StatusServiceUnavailable, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			err := HTTPCheck(srv.Client(), srv.URL)(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package interactions
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/batch"
+	"github.com/CircleConnectApp/feed-service/metrics"
+	"github.com/lib/pq"
+)
+
+// Event types accepted by the interaction log
+const (
+	Impression = "impression"
+	Click      = "click"
+	Like       = "like"
+	Share      = "share"
+	Comment    = "comment"
+	Hide       = "hide"
+)
+
+// ClientEventTypes are the event types clients may submit; impressions are
+// recorded by the service itself when it serves a feed
+var ClientEventTypes = map[string]bool{Click: true, Like: true, Share: true, Comment: true, Hide: true}
+
+// Event is one row of the interaction log
+type Event struct {
+	UserID      int
+	PostID      string
+	Type        string
+	Feed        string
+	Position    int
+	RequestID   string
+	Experiments map[string]string
+	Features    map[string]interface{} // post features at serve time, kept with impressions for offline evaluation
+	OccurredAt  time.Time
+}
+
+type exposure struct {
+	experiment string
+	variant    string
+	userID     int
+	at         time.Time
+}
+
+// Recorder writes interaction events and experiment exposures to PostgreSQL
+// in the background, so serving a feed never waits on the log. Records are
+// dropped (and counted) if the buffer is full.
+type Recorder struct {
+	db        *sql.DB
+	events    *batch.Recorder[Event]
+	exposures *batch.Recorder[exposure]
+}
+
+// NewRecorder starts a recorder buffering up to bufferSize events and as many
+// exposures
+func NewRecorder(db *sql.DB, bufferSize int) *Recorder {
+	r := &Recorder{db: db}
+	r.events = batch.NewRecorder(bufferSize, metrics.InteractionEventsDropped, r.flushEvents)
+	r.exposures = batch.NewRecorder(bufferSize, metrics.InteractionEventsDropped, r.flushExposures)
+	return r
+}
+
+// Record queues events for writing
+func (r *Recorder) Record(events ...Event) {
+	for _, e := range events {
+		if e.OccurredAt.IsZero() {
+			e.OccurredAt = time.Now().UTC()
+		}
+		r.events.Record(e)
+	}
+}
+
+// Expose records that a user was served the given experiment variants
+func (r *Recorder) Expose(userID int, variants map[string]string) {
+	now := time.Now().UTC()
+	for experiment, variant := range variants {
+		r.exposures.Record(exposure{experiment: experiment, variant: variant, userID: userID, at: now})
+	}
+}
+
+// Close stops accepting records and waits for the buffer to be flushed or
+// for ctx to end
+func (r *Recorder) Close(ctx context.Context) error {
+	return errors.Join(r.events.Close(ctx), r.exposures.Close(ctx))
+}
+
+func (r *Recorder) flushEvents(events []Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	const columns = 9
+	placeholders := make([]string, 0, len(events))
+	args := make([]interface{}, 0, len(events)*columns)
+	for i, e := range events {
+		experiments, _ := json.Marshal(e.Experiments)
+		var features interface{}
+		if e.Features != nil {
+			data, _ := json.Marshal(e.Features)
+			features = string(data)
+		}
+
+		base := i * columns
+		placeholders = append(placeholders, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			base+1, base+2, base+3, base+4, base+5, base+6, base+7, base+8, base+9))
+		args = append(args, e.UserID, e.PostID, e.Type, e.Feed, e.Position, e.RequestID, string(experiments), features, e.OccurredAt)
+	}
+
+	query := `INSERT INTO feed_interactions
+		(user_id, post_id, event_type, feed, position, request_id, experiments, features, occurred_at)
+		VALUES ` + strings.Join(placeholders, ", ")
+
+	start := time.Now()
+	_, err := r.db.ExecContext(ctx, query, args...)
+	metrics.ObserveDB("postgres", "insert_interactions", start, err)
+	if err != nil {
+		metrics.InteractionEventsDropped.Add(float64(len(events)))
+		slog.Error("Failed to write interaction events", "count", len(events), "error", err)
+	}
+}
+
+func (r *Recorder) flushExposures(exposures []exposure) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	err := r.upsertExposures(ctx, exposures)
+	metrics.ObserveDB("postgres", "upsert_exposures", start, err)
+	if err != nil {
+		metrics.InteractionEventsDropped.Add(float64(len(exposures)))
+		slog.Error("Failed to write experiment exposures", "count", len(exposures), "error", err)
+	}
+}
+
+func (r *Recorder) upsertExposures(ctx context.Context, exposures []exposure) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO experiment_exposures
+		(experiment, variant, user_id, exposures, first_exposed_at, last_exposed_at)
+		VALUES ($1, $2, $3, 1, $4, $4)
+		ON CONFLICT (experiment, variant, user_id) DO UPDATE SET
+			exposures = experiment_exposures.exposures + 1,
+			last_exposed_at = GREATEST(experiment_exposures.last_exposed_at, EXCLUDED.last_exposed_at)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, e := range exposures {
+		if _, err := stmt.ExecContext(ctx, e.experiment, e.variant, e.userID, e.at); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// VariantExposure summarises how many users saw an experiment variant
+type VariantExposure struct {
+	Experiment     string    `json:"experiment"`
+	Variant        string    `json:"variant"`
+	Users          int64     `json:"users"`
+	Exposures      int64     `json:"exposures"`
+	FirstExposedAt time.Time `json:"first_exposed_at"`
+	LastExposedAt  time.Time `json:"last_exposed_at"`
+}
+
+// ExposureSummary returns exposure counts per experiment variant
+func ExposureSummary(ctx context.Context, db *sql.DB) ([]VariantExposure, error) {
+	start := time.Now()
+	rows, err := db.QueryContext(ctx, `SELECT experiment, variant, COUNT(*), SUM(exposures),
+			MIN(first_exposed_at), MAX(last_exposed_at)
+		FROM experiment_exposures
+		GROUP BY experiment, variant
+		ORDER BY experiment, variant`)
+	metrics.ObserveDB("postgres", "exposure_summary", start, err)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summary := []VariantExposure{}
+	for rows.Next() {
+		var v VariantExposure
+		if err := rows.Scan(&v.Experiment, &v.Variant, &v.Users, &v.Exposures, &v.FirstExposedAt, &v.LastExposedAt); err != nil {
+			return nil, err
+		}
+		summary = append(summary, v)
+	}
+	return summary, rows.Err()
+}
+
+// engagementTypes are the interactions that show interest in a post's author
+var engagementTypes = []string{Click, Like, Share, Comment}
+
+// EngagedAuthors returns the authors of the posts a user engaged with since
+// the given time, most engaged with first. Authors are taken from the
+// features logged with the posts' impressions.
+func EngagedAuthors(ctx context.Context, db *sql.DB, userID int, since time.Time, limit int) ([]int, error) {
+	engaged, err := engagedBy(ctx, db, "author_id", "engaged_authors", userID, since, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var authors []int
+	for _, e := range engaged {
+		if e.ID != userID {
+			authors = append(authors, e.ID)
+		}
+	}
+	return authors, nil
+}
+
+// Engagement counts the posts a user engaged with that share a feature value,
+// such as a community
+type Engagement struct {
+	ID    int
+	Posts int
+}
+
+// EngagedCommunities returns the communities of the posts a user engaged
+// with since the given time, most engaged with first
+func EngagedCommunities(ctx context.Context, db *sql.DB, userID int, since time.Time, limit int) ([]Engagement, error) {
+	return engagedBy(ctx, db, "community_id", "engaged_communities", userID, since, limit)
+}
+
+// engagedBy groups the posts a user engaged with by an integer feature
+// logged with their impressions
+func engagedBy(ctx context.Context, db *sql.DB, feature, operation string, userID int, since time.Time, limit int) ([]Engagement, error) {
+	start := time.Now()
+	rows, err := db.QueryContext(ctx, `SELECT (i.features->>$6)::int AS id, COUNT(DISTINCT e.post_id) AS posts
+		FROM feed_interactions e
+		JOIN feed_interactions i ON i.user_id = e.user_id AND i.post_id = e.post_id AND i.event_type = $2
+		WHERE e.user_id = $1 AND e.event_type = ANY($3) AND e.occurred_at >= $4
+			AND i.features ? $6
+		GROUP BY 1
+		ORDER BY posts DESC, id
+		LIMIT $5`, userID, Impression, pq.Array(engagementTypes), since, limit, feature)
+	metrics.ObserveDB("postgres", operation, start, err)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var engaged []Engagement
+	for rows.Next() {
+		var e Engagement
+		if err := rows.Scan(&e.ID, &e.Posts); err != nil {
+			return nil, err
+		}
+		if e.ID > 0 {
+			engaged = append(engaged, e)
+		}
+	}
+	return engaged, rows.Err()
+}
@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "bearer token", in: "Authorization: Bearer abc.def-ghi", want: "Authorization: Bearer [REDACTED]"},
+		{name: "JWT", in: "token eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.sig rejected", want: "token [REDACTED] rejected"},
+		{name: "URL password", in: "dial postgres://feed:hunter2@db:5432/feed failed", want: "dial postgres://feed:[REDACTED]@db:5432/feed failed"},
+		{name: "nothing secret", in: "user 42 not found", want: "user 42 not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Redact(tt.in); got != tt.want {
+				t.Errorf("Redact(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogger(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs []interface{}
+		want  map[string]string
+	}{
+		{name: "sensitive keys", attrs: []interface{}{"jwt_secret", "s3cret", "feed_token", "abc", "Authorization", "Basic xyz"}, want: map[string]string{"jwt_secret": redacted, "feed_token": redacted, "Authorization": redacted}},
+		{name: "secrets in values", attrs: []interface{}{"header", "Bearer abc"}, want: map[string]string{"header": "Bearer " + redacted}},
+		{name: "secrets in errors", attrs: []interface{}{"error", errors.New("dial mongodb://u:p@host failed")}, want: map[string]string{"error": "dial mongodb://u:" + redacted + "@host failed"}},
+		{name: "plain values", attrs: []interface{}{"user_id", "42"}, want: map[string]string{"user_id": "42"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := New(&buf, "json", "info")
+			log.InfoContext(WithRequestID(context.Background(), "req-1"), "message", tt.attrs...)
+
+			var record map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+				t.Fatalf("%v: %s", err, buf.String())
+			}
+			for key, want := range tt.want {
+				if record[key] != want {
+					t.Errorf("%s = %v, want %q", key, record[key], want)
+				}
+			}
+			if record["request_id"] != "req-1" {
+				t.Errorf("request_id = %v, want req-1", record["request_id"])
+			}
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]slog.Level{
+		"debug":   slog.LevelDebug,
+		"WARN":    slog.LevelWarn,
+		"warning": slog.LevelWarn,
+		"error":   slog.LevelError,
+		"info":    slog.LevelInfo,
+		"":        slog.LevelInfo,
+		"verbose": slog.LevelInfo,
+	}
+	for level, want := range tests {
+		if got := ParseLevel(level); got != want {
+			t.Errorf("ParseLevel(%q) = %v, want %v", level, got, want)
+		}
+	}
+}
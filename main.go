@@ -2,51 +2,278 @@ package main
 
 import (
 	"context"
-	"log"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/CircleConnectApp/feed-service/config"
+	"github.com/CircleConnectApp/feed-service/controllers"
 	"github.com/CircleConnectApp/feed-service/database"
+	"github.com/CircleConnectApp/feed-service/feedtokens"
+	"github.com/CircleConnectApp/feed-service/grpcapi"
+	"github.com/CircleConnectApp/feed-service/health"
+	"github.com/CircleConnectApp/feed-service/interactions"
+	"github.com/CircleConnectApp/feed-service/logger"
+	"github.com/CircleConnectApp/feed-service/memberships"
+	"github.com/CircleConnectApp/feed-service/pins"
 	"github.com/CircleConnectApp/feed-service/routes"
+	"github.com/CircleConnectApp/feed-service/seen"
+	"github.com/CircleConnectApp/feed-service/settings"
+	"github.com/CircleConnectApp/feed-service/tracing"
+	"github.com/CircleConnectApp/feed-service/trending"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
 
 func main() {
-	if err := godotenv.Load(); err != nil {
-		log.Println("Warning: .env file not found")
+	envErr := godotenv.Load()
+
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "eval" {
+		os.Exit(runEval(args[1:]))
+	}
+
+	printOnly := len(args) > 0 && args[0] == "config"
+	if printOnly {
+		args = args[1:]
+	}
+
+	cfg, err := config.Load(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
+
+	if printOnly {
+		out, _ := json.MarshalIndent(cfg.Redacted(), "", "  ")
+		fmt.Println(string(out))
+		return
 	}
 
-	cfg := config.LoadConfig()
+	slog.SetDefault(logger.New(os.Stdout, cfg.LogFormat, cfg.LogLevel))
+	if envErr != nil {
+		slog.Warn(".env file not found")
+	}
+	slog.Info("Effective configuration", "config", cfg.Redacted())
+
+	exporter, err := tracing.NewExporter(context.Background(), cfg.TracingExporter)
+	if err != nil {
+		fatal("Failed to create trace exporter", err)
+	}
+	shutdownTracing, err := tracing.Setup(context.Background(), exporter, cfg.TracingSampleRatio)
+	if err != nil {
+		fatal("Failed to set up tracing", err)
+	}
 
 	mongoClient, err := database.ConnectMongoDB(cfg.MongoURI)
 	if err != nil {
-		log.Fatalf("Failed to connect to MongoDB: %v", err)
+		fatal("Failed to connect to MongoDB", err)
 	}
-	defer mongoClient.Disconnect(context.Background())
-	log.Println("Connected to MongoDB")
+	slog.Info("Connected to MongoDB")
 
 	pgDB, err := database.ConnectPostgres(cfg.PostgresURI)
 	if err != nil {
-		log.Fatalf("Failed to connect to PostgreSQL: %v", err)
+		fatal("Failed to connect to PostgreSQL", err)
+	}
+	slog.Info("Connected to PostgreSQL")
+
+	if err := database.MigratePostgres(context.Background(), pgDB); err != nil {
+		fatal("Failed to migrate PostgreSQL schema", err)
 	}
-	defer pgDB.Close()
-	log.Println("Connected to PostgreSQL")
 
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	router := gin.Default()
+	checker := health.NewChecker(cfg.HealthCheckTimeout)
+	checker.Register("mongo", true, health.MongoCheck(mongoClient))
+	checker.Register("postgres", true, health.PostgresCheck(pgDB))
+	upstreamClient := &http.Client{}
+	checker.Register("user_service", cfg.ReadinessRequireUpstreams, health.HTTPCheck(upstreamClient, cfg.UserServiceURL))
+	checker.Register("post_service", cfg.ReadinessRequireUpstreams, health.HTTPCheck(upstreamClient, cfg.PostServiceURL))
+	checker.Register("community_service", cfg.ReadinessRequireUpstreams, health.HTTPCheck(upstreamClient, cfg.CommunityServiceURL))
+
+	router := gin.New()
+	router.Use(gin.Recovery())
+
+	mongoDB := mongoClient.Database(cfg.MongoDBName)
+	settingsStore, err := settings.NewStore(
+		context.Background(),
+		runtimeDefaults(cfg),
+		cfg.RuntimeSettingsFile,
+		mongoDB.Collection(database.SettingsCollection),
+		mongoDB.Collection(database.SettingsHistory),
+	)
+	if err != nil {
+		fatal("Failed to load runtime settings", err)
+	}
+
+	trendingStore, err := trending.NewStore(context.Background(), mongoDB.Collection(database.TrendingCollection))
+	if err != nil {
+		fatal("Failed to initialise trending store", err)
+	}
+
+	tokenStore, err := feedtokens.NewStore(context.Background(), mongoDB.Collection(database.FeedTokensCollection))
+	if err != nil {
+		fatal("Failed to initialise feed token store", err)
+	}
+
+	seenStore, err := seen.NewStore(context.Background(), mongoDB.Collection(database.SeenPostsCollection))
+	if err != nil {
+		fatal("Failed to initialise seen posts store", err)
+	}
+
+	membershipStore, err := memberships.NewStore(context.Background(), mongoDB.Collection(database.MembershipsCollection))
+	if err != nil {
+		fatal("Failed to initialise community memberships store", err)
+	}
+
+	pinStore, err := pins.NewStore(context.Background(), mongoDB.Collection(database.PinsCollection))
+	if err != nil {
+		fatal("Failed to initialise pinned posts store", err)
+	}
+
+	recorder := interactions.NewRecorder(pgDB, cfg.InteractionBufferSize)
+	seenRecorder := seen.NewRecorder(seenStore, cfg.InteractionBufferSize)
+	membershipRecorder := memberships.NewRecorder(membershipStore, cfg.InteractionBufferSize)
+
+	// The REST and gRPC APIs share the controllers
+	feedController := controllers.NewFeedController(
+		mongoDB,
+		pgDB,
+		cfg.UserServiceURL,
+		cfg.PostServiceURL,
+		cfg.CommunityServiceURL,
+		cfg.WebAppURL,
+		cfg.FeedRequestTimeout,
+		settingsStore,
+		recorder,
+		seenStore,
+		seenRecorder,
+		membershipStore,
+		membershipRecorder,
+		trendingStore,
+		pinStore,
+	)
+	trendingController := controllers.NewTrendingController(trendingStore, settingsStore)
+
+	// The REST and gRPC APIs draw from the same rate limit buckets
+	limitStore := routes.NewRateLimitStore(cfg.RateLimitBackend, mongoDB)
+
+	routes.SetupRoutes(router, cfg, pgDB, checker, settingsStore, feedController, trendingController, tokenStore, limitStore)
+	grpcServer := grpcapi.NewServer(cfg.JWTSecret, checker, settingsStore, limitStore, feedController, trendingController)
+
+	srv := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           router,
+		ReadTimeout:       cfg.HTTPReadTimeout,
+		ReadHeaderTimeout: cfg.HTTPReadHeaderTimeout,
+		WriteTimeout:      cfg.HTTPWriteTimeout,
+		IdleTimeout:       cfg.HTTPIdleTimeout,
+		MaxHeaderBytes:    cfg.HTTPMaxHeaderBytes,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	routes.SetupRoutes(router, mongoClient.Database(cfg.MongoDBName), pgDB)
+	go settingsStore.Watch(ctx, cfg.RuntimeSettingsReloadInterval)
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "4004"
+	grpcListener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+	if err != nil {
+		fatal("Failed to listen for gRPC", err)
 	}
-	log.Printf("Feed service running on port %s", port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+
+	serverErr := make(chan error, 1)
+	grpcErr := make(chan error, 1)
+	go func() {
+		slog.Info("Feed service running", "port", cfg.Port)
+		serverErr <- srv.ListenAndServe()
+	}()
+	go func() {
+		slog.Info("gRPC server running", "port", cfg.GRPCPort)
+		grpcErr <- grpcServer.Serve(grpcListener)
+	}()
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			fatal("Failed to start server", err)
+		}
+	case err := <-grpcErr:
+		fatal("Failed to start gRPC server", err)
+	case <-ctx.Done():
+		stop()
+		slog.Info("Shutdown signal received, draining")
 	}
+
+	// Fail readiness first so load balancers stop sending new requests while
+	// the listener is still accepting them
+	checker.SetDraining()
+	time.Sleep(cfg.ShutdownDrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	// Both servers wait for in-flight requests; connections still open at the
+	// deadline are closed forcibly
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("Server did not drain before the deadline, closing remaining connections", "error", err)
+		srv.Close()
+	}
+	select {
+	case <-grpcStopped:
+	case <-shutdownCtx.Done():
+		slog.Warn("gRPC server did not drain before the deadline, closing remaining connections")
+		grpcServer.Stop()
+	}
+
+	// The recorders write through the database clients, so they are flushed
+	// before the clients are closed
+	if err := recorder.Close(shutdownCtx); err != nil {
+		slog.Error("Failed to flush interaction log", "error", err)
+	}
+	if err := seenRecorder.Close(shutdownCtx); err != nil {
+		slog.Error("Failed to flush seen posts", "error", err)
+	}
+	if err := membershipRecorder.Close(shutdownCtx); err != nil {
+		slog.Error("Failed to flush community memberships", "error", err)
+	}
+	if err := mongoClient.Disconnect(shutdownCtx); err != nil {
+		slog.Error("Failed to disconnect from MongoDB", "error", err)
+	}
+	if err := pgDB.Close(); err != nil {
+		slog.Error("Failed to close PostgreSQL connection", "error", err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
+	}
+
+	slog.Info("Feed service stopped")
+}
+
+// runtimeDefaults seeds the runtime settings with the rate limits from the
+// static configuration, so they apply until overridden
+func runtimeDefaults(cfg config.Config) settings.Settings {
+	defaults := settings.Default()
+	defaults.RateLimits["feed"] = settings.RateLimit{User: cfg.RateLimitFeedUser, IP: cfg.RateLimitFeedIP}
+	defaults.RateLimits["preferences"] = settings.RateLimit{User: cfg.RateLimitPreferencesUser, IP: cfg.RateLimitPreferencesIP}
+	return defaults
+}
+
+// fatal logs err and exits; deferred cleanup does not run
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
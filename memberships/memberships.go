@@ -0,0 +1,160 @@
+// Package memberships keeps a snapshot of the communities each user has
+// joined, taken whenever the feed service looks them up, so communities can
+// be suggested from what similar users joined
+package memberships
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/batch"
+	"github.com/CircleConnectApp/feed-service/metrics"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// Retention is how long a user's snapshot is kept after it was last taken
+	Retention = 90 * 24 * time.Hour
+	// RefreshInterval is how often an unchanged snapshot is retaken, keeping
+	// it among the recent ones CoMemberships samples
+	RefreshInterval = 24 * time.Hour
+	// writeTimeout bounds each background write
+	writeTimeout = 5 * time.Second
+)
+
+// CoMembership is a community joined by users who share communities with a
+// user
+type CoMembership struct {
+	CommunityID int `bson:"_id"`
+	Members     int `bson:"members"` // similar users in the community
+	Overlap     int `bson:"overlap"` // communities those users share with the user, summed
+}
+
+// Store keeps one document per user listing their joined communities
+type Store struct {
+	collection *mongo.Collection
+}
+
+// NewStore creates the store's indexes
+func NewStore(ctx context.Context, collection *mongo.Collection) (*Store, error) {
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "communities", Value: 1}, {Key: "updated_at", Value: -1}}},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{collection: collection}, nil
+}
+
+// Save replaces a user's snapshot if their communities changed or it was
+// taken more than RefreshInterval before at
+func (s *Store) Save(ctx context.Context, userID int, communities []int, at time.Time) error {
+	sorted := append([]int{}, communities...)
+	sort.Ints(sorted)
+	_, err := s.collection.UpdateOne(ctx,
+		bson.M{"user_id": userID, "$or": bson.A{
+			bson.M{"communities": bson.M{"$ne": sorted}},
+			bson.M{"updated_at": bson.M{"$lte": at.Add(-RefreshInterval)}},
+		}},
+		bson.M{"$set": bson.M{"communities": sorted, "updated_at": at, "expires_at": at.Add(Retention)}},
+		options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// The snapshot is up to date, so the upsert tried to start another
+		return nil
+	}
+	return err
+}
+
+// CoMemberships returns the communities joined by the most recently seen
+// maxUsers users sharing at least one of joined with the user, leaving out
+// joined itself. Communities are ordered by how much their members overlap
+// with the user.
+func (s *Store) CoMemberships(ctx context.Context, userID int, joined []int, maxUsers, limit int) ([]CoMembership, error) {
+	if len(joined) == 0 {
+		return nil, nil
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"communities": bson.M{"$in": joined}, "user_id": bson.M{"$ne": userID}}}},
+		{{Key: "$sort", Value: bson.M{"updated_at": -1}}},
+		{{Key: "$limit", Value: maxUsers}},
+		{{Key: "$project", Value: bson.M{
+			"communities": 1,
+			"overlap":     bson.M{"$size": bson.M{"$setIntersection": bson.A{"$communities", joined}}},
+		}}},
+		{{Key: "$unwind", Value: "$communities"}},
+		{{Key: "$match", Value: bson.M{"communities": bson.M{"$nin": joined}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     "$communities",
+			"members": bson.M{"$sum": 1},
+			"overlap": bson.M{"$sum": "$overlap"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "overlap", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var results []CoMembership
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+type snapshot struct {
+	userID      int
+	communities []int
+	at          time.Time
+}
+
+// Recorder saves snapshots to the store in the background, so looking up a
+// user's communities never waits on the write. Snapshots are dropped (and
+// counted) if the buffer is full; the next lookup takes another.
+type Recorder struct {
+	store   *Store
+	batches *batch.Recorder[snapshot]
+}
+
+// NewRecorder starts a recorder buffering up to bufferSize snapshots
+func NewRecorder(store *Store, bufferSize int) *Recorder {
+	r := &Recorder{store: store}
+	r.batches = batch.NewRecorder(bufferSize, metrics.MembershipSnapshotsDropped, r.write)
+	return r
+}
+
+// Record queues a snapshot of a user's joined communities
+func (r *Recorder) Record(userID int, communities []int, at time.Time) {
+	r.batches.Record(snapshot{userID: userID, communities: communities, at: at})
+}
+
+// Close stops accepting snapshots and waits for the buffer to be written or
+// for ctx to end
+func (r *Recorder) Close(ctx context.Context) error {
+	return r.batches.Close(ctx)
+}
+
+func (r *Recorder) write(snapshots []snapshot) {
+	for _, snap := range snapshots {
+		ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+		err := r.store.Save(ctx, snap.userID, snap.communities, snap.at)
+		cancel()
+		if err != nil {
+			metrics.MembershipSnapshotsDropped.Inc()
+			slog.Warn("Failed to save community memberships", "user_id", snap.userID, "error", err)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package memberships
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// testStore returns a store on the mocked database
+func testStore(mt *mtest.T) *Store {
+	mt.AddMockResponses(mtest.CreateSuccessResponse())
+	store, err := NewStore(context.Background(), mt.Coll)
+	if err != nil {
+		mt.Fatal(err)
+	}
+	return store
+}
+
+// startedCommands returns the commands named name sent to the mocked database
+func startedCommands(mt *mtest.T, name string) []bson.Raw {
+	var out []bson.Raw
+	for _, event := range mt.GetAllStartedEvents() {
+		if event.CommandName == name {
+			out = append(out, event.Command)
+		}
+	}
+	return out
+}
+
+// ints decodes a BSON array of integers
+func ints(mt *mtest.T, value bson.RawValue) []int {
+	var out []int
+	if err := value.Unmarshal(&out); err != nil {
+		mt.Fatal(err)
+	}
+	return out
+}
+
+func TestStoreSave(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	at := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		communities     []int
+		response        bson.D
+		wantCommunities []int
+		wantErr         bool
+	}{
+		{name: "saves the communities in order", communities: []int{7, 3, 5}, response: mtest.CreateSuccessResponse(), wantCommunities: []int{3, 5, 7}},
+		{name: "saves no communities", response: mtest.CreateSuccessResponse(), wantCommunities: []int{}},
+		// Neither changed nor stale, so the filter misses and the upsert
+		// collides with the existing snapshot
+		{name: "up to date snapshot", communities: []int{3}, response: mtest.CreateWriteErrorsResponse(mtest.WriteError{Code: 11000, Message: "duplicate key"}), wantCommunities: []int{3}},
+		{name: "write failure", communities: []int{3}, response: mtest.CreateWriteErrorsResponse(mtest.WriteError{Code: 2, Message: "bad"}), wantCommunities: []int{3}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			store := testStore(mt)
+			mt.AddMockResponses(tt.response)
+			mt.ClearEvents()
+
+			err := store.Save(context.Background(), 9, tt.communities, at)
+			if (err != nil) != tt.wantErr {
+				mt.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+
+			commands := startedCommands(mt, "update")
+			if len(commands) != 1 {
+				mt.Fatalf("%d updates, want 1", len(commands))
+			}
+			update := commands[0].Lookup("updates", "0").Document()
+			if got := ints(mt, update.Lookup("u", "$set", "communities")); !reflect.DeepEqual(got, tt.wantCommunities) {
+				mt.Errorf("communities = %v, want %v", got, tt.wantCommunities)
+			}
+			if got := ints(mt, update.Lookup("q", "$or", "0", "communities", "$ne")); !reflect.DeepEqual(got, tt.wantCommunities) {
+				mt.Errorf("changed unless communities = %v, want %v", got, tt.wantCommunities)
+			}
+			if stale := update.Lookup("q", "$or", "1", "updated_at", "$lte").Time().UTC(); !stale.Equal(at.Add(-RefreshInterval)) {
+				mt.Errorf("stale before %v, want %v", stale, at.Add(-RefreshInterval))
+			}
+			if !update.Lookup("upsert").Boolean() {
+				mt.Error("not an upsert")
+			}
+		})
+	}
+}
+
+func TestStoreCoMemberships(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	tests := []struct {
+		name      string
+		joined    []int
+		results   []bson.D
+		wantQuery bool
+		want      []CoMembership
+	}{
+		{name: "no joined communities", wantQuery: false},
+		{
+			name:   "communities of similar users",
+			joined: []int{1, 2},
+			results: []bson.D{
+				{{Key: "_id", Value: 5}, {Key: "members", Value: 3}, {Key: "overlap", Value: 4}},
+				{{Key: "_id", Value: 6}, {Key: "members", Value: 1}, {Key: "overlap", Value: 1}},
+			},
+			wantQuery: true,
+			want:      []CoMembership{{CommunityID: 5, Members: 3, Overlap: 4}, {CommunityID: 6, Members: 1, Overlap: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			store := testStore(mt)
+			ns := mt.Coll.Database().Name() + "." + mt.Coll.Name()
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, tt.results...))
+			mt.ClearEvents()
+
+			got, err := store.CoMemberships(context.Background(), 9, tt.joined, 200, 10)
+			if err != nil {
+				mt.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				mt.Errorf("co-memberships = %+v, want %+v", got, tt.want)
+			}
+
+			commands := startedCommands(mt, "aggregate")
+			if !tt.wantQuery {
+				if len(commands) > 0 {
+					mt.Errorf("%d queries, want none", len(commands))
+				}
+				return
+			}
+			if len(commands) != 1 {
+				mt.Fatalf("%d queries, want 1", len(commands))
+			}
+			pipeline := commands[0].Lookup("pipeline")
+			match := pipeline.Array().Index(0).Value().Document().Lookup("$match").Document()
+			if got := ints(mt, match.Lookup("communities", "$in")); !reflect.DeepEqual(got, tt.joined) {
+				mt.Errorf("similar users share %v, want %v", got, tt.joined)
+			}
+			if user := match.Lookup("user_id", "$ne").AsInt64(); user != 9 {
+				mt.Errorf("similar users leave out %d, want the user", user)
+			}
+			if users := pipeline.Array().Index(2).Value().Document().Lookup("$limit").AsInt64(); users != 200 {
+				mt.Errorf("%d similar users, want 200", users)
+			}
+			stages, _ := pipeline.Array().Values()
+			if limit := stages[len(stages)-1].Document().Lookup("$limit").AsInt64(); limit != 10 {
+				mt.Errorf("%d communities, want 10", limit)
+			}
+		})
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	tests := []struct {
+		name       string
+		snapshots  [][]int
+		closeFirst bool
+		wantSaved  [][]int
+	}{
+		{name: "saves each snapshot", snapshots: [][]int{{2, 1}, {3}}, wantSaved: [][]int{{1, 2}, {3}}},
+		{name: "drops snapshots once closed", snapshots: [][]int{{1}}, closeFirst: true},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			store := testStore(mt)
+			for range tt.wantSaved {
+				mt.AddMockResponses(mtest.CreateSuccessResponse())
+			}
+			mt.ClearEvents()
+
+			recorder := NewRecorder(store, 4)
+			if tt.closeFirst {
+				if err := recorder.Close(context.Background()); err != nil {
+					mt.Fatal(err)
+				}
+			}
+			for _, communities := range tt.snapshots {
+				recorder.Record(9, communities, time.Now())
+			}
+			if err := recorder.Close(context.Background()); err != nil {
+				mt.Fatal(err)
+			}
+
+			var saved [][]int
+			for _, command := range startedCommands(mt, "update") {
+				saved = append(saved, ints(mt, command.Lookup("updates", "0", "u", "$set", "communities")))
+			}
+			if !reflect.DeepEqual(saved, tt.wantSaved) {
+				mt.Errorf("saved = %v, want %v", saved, tt.wantSaved)
+			}
+		})
+	}
+}
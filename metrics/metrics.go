@@ -0,0 +1,146 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// Label values are kept to fixed sets (route templates, service and operation
+// names chosen in code) so that series cardinality stays bounded.
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "feed",
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by route template, method and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "feed",
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route template and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	GRPCRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "feed",
+		Name:      "grpc_requests_total",
+		Help:      "gRPC calls handled, by full method name and status code.",
+	}, []string{"method", "code"})
+
+	GRPCRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "feed",
+		Name:      "grpc_request_duration_seconds",
+		Help:      "gRPC call latency by full method name.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	UpstreamRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "feed",
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Latency of calls to the user, post and community services.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"service", "operation"})
+
+	UpstreamErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "feed",
+		Name:      "upstream_errors_total",
+		Help:      "Failed calls to upstream services, by reason (transport or status).",
+	}, []string{"service", "operation", "reason"})
+
+	DBOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "feed",
+		Name:      "db_operation_duration_seconds",
+		Help:      "Latency of MongoDB commands and PostgreSQL queries.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"db", "operation", "outcome"})
+
+	FeedItems = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "feed",
+		Name:      "items_returned",
+		Help:      "Number of items returned per feed page.",
+		Buckets:   []float64{0, 1, 5, 10, 20, 50, 100},
+	}, []string{"feed"})
+
+	FeedItemsFiltered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "feed",
+		Name:      "items_filtered_total",
+		Help:      "Upstream posts dropped while assembling a feed, by reason.",
+	}, []string{"feed", "reason"})
+
+	Candidates = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "feed",
+		Name:      "candidates_total",
+		Help:      "Posts proposed for ranking, by feed and candidate source; a post proposed by several sources counts once per source.",
+	}, []string{"feed", "source"})
+
+	CandidateSourceErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "feed",
+		Name:      "candidate_source_errors_total",
+		Help:      "Candidate sources that failed and were skipped, by feed and source.",
+	}, []string{"feed", "source"})
+
+	InteractionEventsDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "feed",
+		Name:      "interaction_events_dropped_total",
+		Help:      "Interaction log records dropped because the write buffer was full or a flush failed.",
+	})
+
+	SeenPostsDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "feed",
+		Name:      "seen_posts_dropped_total",
+		Help:      "Seen post writes dropped because the write buffer was full or the write failed.",
+	})
+
+	MembershipSnapshotsDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "feed",
+		Name:      "membership_snapshots_dropped_total",
+		Help:      "Community membership snapshots dropped because the write buffer was full or the write failed.",
+	})
+
+	CacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "feed",
+		Name:      "cache_requests_total",
+		Help:      "Cache lookups by cache name and result (hit or miss).",
+	}, []string{"cache", "result"})
+)
+
+// ObserveUpstream records the latency of an upstream call started at start
+func ObserveUpstream(service, operation string, start time.Time) {
+	UpstreamRequestDuration.WithLabelValues(service, operation).Observe(time.Since(start).Seconds())
+}
+
+// ObserveDB records the latency and outcome of a database operation started at start
+func ObserveDB(db, operation string, start time.Time, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	DBOperationDuration.WithLabelValues(db, operation, outcome).Observe(time.Since(start).Seconds())
+}
+
+// MongoMonitor times every MongoDB command issued by a client, labelled by
+// command name (find, insert, update, ...)
+func MongoMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			DBOperationDuration.WithLabelValues("mongo", e.CommandName, "success").Observe(e.Duration.Seconds())
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			DBOperationDuration.WithLabelValues("mongo", e.CommandName, "error").Observe(e.Duration.Seconds())
+		},
+	}
+}
+
+// CacheLookup records a hit or miss for the named cache
+func CacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	CacheRequests.WithLabelValues(cache, result).Inc()
+}
@@ -1,21 +1,35 @@
 package middleware
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strings"
 
-	"github.com/CircleConnectApp/feed-service/config"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// RequireRole rejects requests whose token does not carry role. It must run
+// after AuthMiddleware.
+func RequireRole(role string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		log.Println("AuthMiddleware: Checking Authorization header...")
+		if actual, _ := c.Get("role"); actual != role {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			log.Println("AuthMiddleware: Missing Authorization header")
+			slog.DebugContext(ctx, "AuthMiddleware: Missing Authorization header")
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
 			c.Abort()
 			return
@@ -23,55 +37,69 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			log.Println("AuthMiddleware: Invalid Authorization header format")
+			slog.DebugContext(ctx, "AuthMiddleware: Invalid Authorization header format")
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header format must be Bearer {token}"})
 			c.Abort()
 			return
 		}
 
-		tokenString := parts[1]
-		log.Println("AuthMiddleware: Token extracted:", tokenString)
-
-		cfg := config.LoadConfig()
-
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				log.Println("AuthMiddleware: Invalid signing method")
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return []byte(cfg.JWTSecret), nil
-		})
-
+		claims, err := ParseToken(ctx, jwtSecret, parts[1])
 		if err != nil {
-			log.Printf("AuthMiddleware: Error parsing token: %v", err)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			message := "Invalid or expired token"
+			if errors.Is(err, ErrMissingUserID) {
+				message = "Invalid token: missing user_id"
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": message})
 			c.Abort()
 			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			log.Println("AuthMiddleware: Token is valid")
-			if userID, exists := claims["user_id"]; exists {
-				log.Println("AuthMiddleware: User ID found:", userID)
-				c.Set("user_id", int(userID.(float64)))
-			} else {
-				log.Println("AuthMiddleware: Missing user_id in token")
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token: missing user_id"})
-				c.Abort()
-				return
-			}
+		c.Set("user_id", claims.UserID)
+		if claims.Role != "" {
+			c.Set("role", claims.Role)
+		}
+		c.Next()
+	}
+}
 
-			if role, exists := claims["role"]; exists {
-				log.Println("AuthMiddleware: Role found:", role)
-				c.Set("role", role.(string))
-			}
+// Claims is the identity carried by a valid token
+type Claims struct {
+	UserID int
+	Role   string
+}
 
-			c.Next()
-		} else {
-			log.Println("AuthMiddleware: Invalid token")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
-			return
+// ErrMissingUserID is returned for valid tokens without a user_id claim
+var ErrMissingUserID = errors.New("token has no user_id")
+
+// ParseToken verifies an HMAC-signed JWT and returns its claims. It is shared
+// by the REST and gRPC servers.
+func ParseToken(ctx context.Context, jwtSecret, tokenString string) (Claims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			slog.WarnContext(ctx, "ParseToken: Invalid signing method", "alg", token.Header["alg"])
+			return nil, jwt.ErrSignatureInvalid
 		}
+		return []byte(jwtSecret), nil
+	})
+	if err != nil {
+		slog.DebugContext(ctx, "ParseToken: Error parsing token", "error", err)
+		return Claims{}, err
+	}
+
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		slog.DebugContext(ctx, "ParseToken: Invalid token")
+		return Claims{}, jwt.ErrTokenInvalidClaims
 	}
+
+	userID, ok := mapClaims["user_id"].(float64)
+	if !ok {
+		slog.WarnContext(ctx, "ParseToken: Missing user_id in token")
+		return Claims{}, ErrMissingUserID
+	}
+	slog.DebugContext(ctx, "ParseToken: Token is valid", "user_id", userID)
+
+	claims := Claims{UserID: int(userID)}
+	claims.Role, _ = mapClaims["role"].(string)
+	return claims, nil
 }
@@ -0,0 +1,242 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// minCompressSize is the smallest response worth compressing; smaller ones
+// are sent as they are
+const minCompressSize = 1024
+
+// compressibleTypes are the media types compressed; others, such as media
+// already compressed, pass through
+var compressibleTypes = map[string]bool{
+	"application/json":      true,
+	"application/feed+json": true,
+	"application/msgpack":   true,
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/xml":       true,
+	"text/plain":            true,
+	"text/html":             true,
+	"text/xml":              true,
+}
+
+var (
+	gzipWriters   = sync.Pool{New: func() interface{} { return gzip.NewWriter(io.Discard) }}
+	brotliWriters = sync.Pool{New: func() interface{} { return brotli.NewWriterLevel(io.Discard, brotli.DefaultCompression) }}
+)
+
+// CompressionMiddleware compresses responses with brotli or gzip, whichever
+// the client prefers in Accept-Encoding (brotli on a tie)
+func CompressionMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		w := &compressWriter{ResponseWriter: c.Writer, encoding: encoding}
+		c.Writer = w
+		defer w.finish()
+		c.Next()
+	}
+}
+
+// negotiateEncoding picks "br", "gzip" or "" (identity) from an Accept-Encoding header
+func negotiateEncoding(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if name != "br" && name != "gzip" || q <= 0 {
+			continue
+		}
+		if q > bestQ || q == bestQ && name == "br" {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// compressWriter buffers the start of a response until it knows whether the
+// response is large and compressible enough, then streams it compressed
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	buf      bytes.Buffer
+	decided  bool
+	encoder  io.WriteCloser
+	status   int
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.decided {
+		w.ResponseWriter.WriteHeader(w.statusCode())
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *compressWriter) Status() int {
+	if !w.decided {
+		return w.statusCode()
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *compressWriter) Written() bool {
+	return w.decided || w.buf.Len() > 0 || w.ResponseWriter.Written()
+}
+
+func (w *compressWriter) Size() int {
+	if !w.decided {
+		return w.buf.Len()
+	}
+	return w.ResponseWriter.Size()
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if w.decided {
+		if w.encoder != nil {
+			return w.encoder.Write(p)
+		}
+		return w.ResponseWriter.Write(p)
+	}
+
+	if !w.compressible() {
+		w.decide(false)
+		return w.ResponseWriter.Write(p)
+	}
+	w.buf.Write(p)
+	if w.buf.Len() >= minCompressSize {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends what has been written so far, compressing it if the response
+// is compressible. Streaming responses therefore skip the size threshold.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if err := w.decide(w.compressible()); err != nil {
+			return
+		}
+	}
+	if f, ok := w.encoder.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.Hijack()
+}
+
+// finish writes out a buffered response and closes the encoder
+func (w *compressWriter) finish() {
+	if !w.decided {
+		// Small responses and ones without a body are sent uncompressed
+		if w.buf.Len() == 0 && w.status == 0 {
+			return
+		}
+		w.decide(false)
+	}
+	if w.encoder != nil {
+		w.encoder.Close()
+		switch e := w.encoder.(type) {
+		case *gzip.Writer:
+			gzipWriters.Put(e)
+		case *brotli.Writer:
+			brotliWriters.Put(e)
+		}
+		w.encoder = nil
+	}
+}
+
+// decide sends the headers and any buffered body, compressed or not
+func (w *compressWriter) decide(compress bool) error {
+	w.decided = true
+	header := w.ResponseWriter.Header()
+	if compress {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		switch w.encoding {
+		case "br":
+			e := brotliWriters.Get().(*brotli.Writer)
+			e.Reset(w.ResponseWriter)
+			w.encoder = e
+		default:
+			e := gzipWriters.Get().(*gzip.Writer)
+			e.Reset(w.ResponseWriter)
+			w.encoder = e
+		}
+	}
+	w.ResponseWriter.WriteHeader(w.statusCode())
+
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if w.encoder != nil {
+		_, err = w.encoder.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+	return err
+}
+
+// compressible reports whether the response can be compressed, judging by
+// its status and headers
+func (w *compressWriter) compressible() bool {
+	status := w.statusCode()
+	if status < 200 || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+	header := w.ResponseWriter.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	return err == nil && compressibleTypes[mediaType]
+}
+
+func (w *compressWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
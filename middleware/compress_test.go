@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                      "",
+		"identity":              "",
+		"gzip":                  "gzip",
+		"gzip, br":              "br",
+		"br;q=0.5, gzip":        "gzip",
+		"br;q=0, gzip;q=0.1":    "gzip",
+		"GZIP;q=0.8, deflate":   "gzip",
+		"br;q=oops, gzip;q=0.2": "gzip",
+	}
+	for header, want := range tests {
+		if got := negotiateEncoding(header); got != want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestCompressionMiddleware(t *testing.T) {
+	large := strings.Repeat("feed item ", minCompressSize)
+
+	tests := []struct {
+		name         string
+		accept       string
+		contentType  string
+		body         string
+		wantEncoding string
+	}{
+		{name: "gzip", accept: "gzip", contentType: "application/json", body: large, wantEncoding: "gzip"},
+		{name: "brotli", accept: "br, gzip", contentType: "application/json", body: large, wantEncoding: "br"},
+		{name: "small body", accept: "gzip", contentType: "application/json", body: "{}"},
+		{name: "incompressible type", accept: "gzip", contentType: "image/png", body: large},
+		{name: "not accepted", contentType: "application/json", body: large},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(CompressionMiddleware())
+			r.GET("/x", func(c *gin.Context) {
+				c.Data(http.StatusOK, tt.contentType, []byte(tt.body))
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/x", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept-Encoding", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if got := w.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if !strings.Contains(w.Header().Get("Vary"), "Accept-Encoding") {
+				t.Error("response does not vary by Accept-Encoding")
+			}
+
+			var body io.Reader = w.Body
+			switch tt.wantEncoding {
+			case "gzip":
+				zr, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body = zr
+			case "br":
+				body = brotli.NewReader(w.Body)
+			}
+			var got bytes.Buffer
+			if _, err := io.Copy(&got, body); err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.body {
+				t.Errorf("body of %d bytes, want the %d bytes written", got.Len(), len(tt.body))
+			}
+		})
+	}
+}
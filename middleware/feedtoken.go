@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/CircleConnectApp/feed-service/feedtokens"
+	"github.com/gin-gonic/gin"
+)
+
+// FeedTokenMiddleware authenticates feed readers, which cannot send bearer
+// tokens, by a feed token in the token query parameter or as the HTTP Basic
+// password
+func FeedTokenMiddleware(store *feedtokens.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		secret := c.Query("token")
+		if secret == "" {
+			_, secret, _ = c.Request.BasicAuth()
+		}
+		if secret == "" {
+			c.Header("WWW-Authenticate", `Basic realm="feeds"`)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Feed token is required"})
+			c.Abort()
+			return
+		}
+
+		userID, err := store.Authenticate(ctx, secret)
+		if err != nil {
+			if errors.Is(err, feedtokens.ErrNotFound) {
+				slog.DebugContext(ctx, "FeedTokenMiddleware: Unknown or revoked feed token")
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or revoked feed token"})
+			} else {
+				slog.ErrorContext(ctx, "FeedTokenMiddleware: Failed to look up feed token", "error", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate feed token"})
+			}
+			c.Abort()
+			return
+		}
+
+		c.Set("user_id", userID)
+		c.Next()
+	}
+}
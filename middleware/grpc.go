@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/logger"
+	"github.com/CircleConnectApp/feed-service/metrics"
+	"github.com/CircleConnectApp/feed-service/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type claimsKey struct{}
+
+// ClaimsFromContext returns the identity GRPCAuthInterceptor authenticated
+func ClaimsFromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(Claims)
+	return claims, ok
+}
+
+// GRPCRequestIDInterceptor is the gRPC counterpart of RequestIDMiddleware,
+// reading and echoing the request ID in the x-request-id metadata key
+func GRPCRequestIDInterceptor() grpc.UnaryServerInterceptor {
+	key := strings.ToLower(logger.RequestIDHeader)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var requestID string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(key); len(values) > 0 {
+				requestID = values[0]
+			}
+		}
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+
+		grpc.SetHeader(ctx, metadata.Pairs(key, requestID))
+		return handler(logger.WithRequestID(ctx, requestID), req)
+	}
+}
+
+// GRPCLoggerInterceptor writes one structured access log entry per call
+func GRPCLoggerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		code := status.Code(err)
+
+		level := slog.LevelInfo
+		switch code {
+		case codes.OK:
+		case codes.Internal, codes.Unknown, codes.Unavailable, codes.DataLoss:
+			level = slog.LevelError
+		default:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", info.FullMethod),
+			slog.String("code", code.String()),
+			slog.Duration("latency", time.Since(start)),
+		}
+		if p, ok := peer.FromContext(ctx); ok {
+			attrs = append(attrs, slog.String("client_ip", p.Addr.String()))
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("errors", status.Convert(err).Message()))
+		}
+
+		slog.LogAttrs(ctx, level, "gRPC call handled", attrs...)
+		return resp, err
+	}
+}
+
+// GRPCMetricsInterceptor records call counts and latency per method
+func GRPCMetricsInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		metrics.GRPCRequests.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+		metrics.GRPCRequestDuration.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+		return resp, err
+	}
+}
+
+// GRPCAuthInterceptor is the gRPC counterpart of AuthMiddleware and
+// RequireRole. Calls carry "Bearer <token>" in the authorization metadata
+// key; methods listed in roles also require the token to carry that role.
+// The grpc.* services (health checks) are left open, like /health.
+func GRPCAuthInterceptor(jwtSecret string, roles map[string]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if strings.HasPrefix(info.FullMethod, "/grpc.") {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+		if len(values) == 0 {
+			return nil, status.Error(codes.Unauthenticated, "Authorization metadata is required")
+		}
+
+		parts := strings.Split(values[0], " ")
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			return nil, status.Error(codes.Unauthenticated, "Authorization metadata format must be Bearer {token}")
+		}
+
+		claims, err := ParseToken(ctx, jwtSecret, parts[1])
+		if err != nil {
+			if errors.Is(err, ErrMissingUserID) {
+				return nil, status.Error(codes.Unauthenticated, "Invalid token: missing user_id")
+			}
+			return nil, status.Error(codes.Unauthenticated, "Invalid or expired token")
+		}
+
+		if role, ok := roles[info.FullMethod]; ok && claims.Role != role {
+			return nil, status.Error(codes.PermissionDenied, "Insufficient permissions")
+		}
+
+		return handler(context.WithValue(ctx, claimsKey{}, claims), req)
+	}
+}
+
+// GRPCRateLimitInterceptor is the gRPC counterpart of RateLimitMiddleware.
+// groups maps full method names to the route group whose rules apply; other
+// methods are not limited. Buckets are shared with the REST API, so a user
+// draws from the same limit over both. It must run after GRPCAuthInterceptor
+// for user limits to apply. The rate limit headers are sent as metadata and
+// denied calls fail with ResourceExhausted.
+func GRPCRateLimitInterceptor(store ratelimit.Store, groups map[string]string, rules RateLimitRules) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		group, ok := groups[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		var userID interface{}
+		if claims, ok := ClaimsFromContext(ctx); ok {
+			userID = claims.UserID
+		}
+		var ip string
+		if p, ok := peer.FromContext(ctx); ok {
+			ip = p.Addr.String()
+			if host, _, err := net.SplitHostPort(ip); err == nil {
+				ip = host
+			}
+		}
+
+		allowed, headers := limitRequest(ctx, store, group, userID, ip, rules)
+		if len(headers) > 0 {
+			md := metadata.MD{}
+			for _, h := range headers {
+				md.Set(h.name, h.value)
+			}
+			grpc.SetHeader(ctx, md)
+		}
+
+		if !allowed {
+			return nil, status.Error(codes.ResourceExhausted, "Rate limit exceeded")
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/CircleConnectApp/feed-service/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPCRateLimitInterceptor(t *testing.T) {
+	const limited, open = "/feed.v1.FeedService/GetFeed", "/feed.v1.FeedService/IngestPostEvents"
+
+	type call struct {
+		method string
+		user   int // 0 for unauthenticated calls
+	}
+	tests := []struct {
+		name     string
+		user, ip string
+		calls    []call
+		want     []codes.Code
+	}{
+		{
+			name:  "user limit",
+			user:  "2/m",
+			ip:    "off",
+			calls: []call{{limited, 1}, {limited, 1}, {limited, 1}, {limited, 2}},
+			want:  []codes.Code{codes.OK, codes.OK, codes.ResourceExhausted, codes.OK},
+		},
+		{
+			name:  "IP limit",
+			user:  "off",
+			ip:    "2/m",
+			calls: []call{{limited, 1}, {limited, 2}, {limited, 3}},
+			want:  []codes.Code{codes.OK, codes.OK, codes.ResourceExhausted},
+		},
+		{
+			name:  "IP denial refunds user token",
+			user:  "1/m",
+			ip:    "1/m",
+			calls: []call{{limited, 2}, {limited, 1}, {limited, 1}},
+			want:  []codes.Code{codes.OK, codes.ResourceExhausted, codes.ResourceExhausted},
+		},
+		{
+			name:  "unauthenticated calls use the IP limit only",
+			user:  "1/m",
+			ip:    "2/m",
+			calls: []call{{limited, 0}, {limited, 0}, {limited, 0}},
+			want:  []codes.Code{codes.OK, codes.OK, codes.ResourceExhausted},
+		},
+		{
+			name:  "methods without a group are not limited",
+			user:  "1/m",
+			ip:    "1/m",
+			calls: []call{{open, 1}, {open, 1}, {open, 1}},
+			want:  []codes.Code{codes.OK, codes.OK, codes.OK},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interceptor := GRPCRateLimitInterceptor(ratelimit.NewMemoryStore(), map[string]string{limited: "feed"}, func(string) (ratelimit.Rule, ratelimit.Rule) {
+				u, _ := ratelimit.ParseRule(tt.user)
+				i, _ := ratelimit.ParseRule(tt.ip)
+				return u, i
+			})
+			for i, c := range tt.calls {
+				if got := status.Code(invoke(interceptor, c.method, c.user)); got != tt.want[i] {
+					t.Fatalf("call %d (%s, user %d): code %v, want %v", i, c.method, c.user, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGRPCRateLimitSharesRESTBuckets(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	r := rateLimitedRouter(store, "2/m", "off")
+	interceptor := GRPCRateLimitInterceptor(store, map[string]string{"/feed.v1.FeedService/GetFeed": "feed"}, func(string) (ratelimit.Rule, ratelimit.Rule) {
+		u, _ := ratelimit.ParseRule("2/m")
+		return u, ratelimit.Rule{}
+	})
+
+	// The REST router takes its user from the header as a string, the
+	// interceptor from the claims; both key the bucket by the same ID
+	if w := get(r, "1"); w.Code != http.StatusOK {
+		t.Fatalf("REST status %d, want 200", w.Code)
+	}
+	if err := invoke(interceptor, "/feed.v1.FeedService/GetFeed", 1); err != nil {
+		t.Fatalf("first gRPC call: %v", err)
+	}
+	if err := invoke(interceptor, "/feed.v1.FeedService/GetFeed", 1); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("gRPC call after the REST request used the limit: %v, want ResourceExhausted", err)
+	}
+}
+
+// invoke calls method through interceptor from 192.0.2.1 as user, or
+// unauthenticated for user 0
+func invoke(interceptor grpc.UnaryServerInterceptor, method string, user int) error {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1234}})
+	if user != 0 {
+		ctx = context.WithValue(ctx, claimsKey{}, Claims{UserID: user})
+	}
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(context.Context, interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	return err
+}
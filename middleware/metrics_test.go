@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CircleConnectApp/feed-service/metrics"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	r := gin.New()
+	r.Use(MetricsMiddleware())
+	r.GET("/posts/:id", func(c *gin.Context) {
+		c.Status(http.StatusTeapot)
+	})
+
+	tests := []struct {
+		name       string
+		path       string
+		wantRoute  string
+		wantStatus string
+	}{
+		// Path parameters are labelled by the route template
+		{name: "matched route", path: "/posts/42", wantRoute: "/posts/:id", wantStatus: "418"},
+		{name: "unmatched route", path: "/nope/42", wantRoute: "unmatched", wantStatus: "404"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := metrics.HTTPRequests.WithLabelValues(http.MethodGet, tt.wantRoute, tt.wantStatus)
+			before := testutil.ToFloat64(requests)
+
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if got := testutil.ToFloat64(requests) - before; got != 1 {
+				t.Errorf("requests counted = %v, want 1", got)
+			}
+		})
+	}
+}
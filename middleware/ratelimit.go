@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitRules returns the per-user and per-IP rules currently in force for
+// a route group. It is called on every request so limits can change at runtime.
+type RateLimitRules func(group string) (user, ip ratelimit.Rule)
+
+// RateLimitMiddleware enforces the rules for group using token buckets held
+// in store. User limits are keyed by the authenticated user_id, so the
+// middleware must run after AuthMiddleware for them to apply; IP limits are
+// keyed by the client address.
+func RateLimitMiddleware(store ratelimit.Store, group string, rules RateLimitRules) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+		allowed, headers := limitRequest(c.Request.Context(), store, group, userID, c.ClientIP(), rules)
+		for _, h := range headers {
+			c.Header(h.name, h.value)
+		}
+
+		if !allowed {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// limitHeader is a rate limit header reported with a response
+type limitHeader struct {
+	name, value string
+}
+
+// limitRequest takes a token for a request to group from each of its
+// enabled buckets. It reports whether the request is allowed and the headers
+// describing the most constrained bucket, none when no bucket applies.
+func limitRequest(ctx context.Context, store ratelimit.Store, group string, userID interface{}, ip string, rules RateLimitRules) (bool, []limitHeader) {
+	userRule, ipRule := rules(group)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	results := takeTokens(ctx, store, limitBuckets(group, userID, ip, userRule, ipRule))
+	if len(results) == 0 {
+		return true, nil
+	}
+
+	// Report the most constrained bucket and wait for the slowest denial
+	reported := results[0]
+	var retryAfter time.Duration
+	allowed := true
+	for _, res := range results {
+		if res.Remaining < reported.Remaining {
+			reported = res
+		}
+		if !res.Allowed {
+			allowed = false
+			retryAfter = max(retryAfter, res.RetryAfter)
+		}
+	}
+
+	headers := []limitHeader{
+		{name: "RateLimit-Limit", value: strconv.Itoa(reported.Limit)},
+		{name: "RateLimit-Remaining", value: strconv.Itoa(reported.Remaining)},
+		{name: "RateLimit-Reset", value: strconv.Itoa(ceilSeconds(reported.Reset))},
+	}
+	if !allowed {
+		headers = append(headers, limitHeader{name: "Retry-After", value: strconv.Itoa(ceilSeconds(retryAfter))})
+	}
+	return allowed, headers
+}
+
+// limitBucket is one token bucket a request draws from
+type limitBucket struct {
+	kind string // "user" or "IP"
+	key  string
+	rule ratelimit.Rule
+}
+
+// limitBuckets returns the enabled buckets for a request to group; userID is
+// nil for unauthenticated requests
+func limitBuckets(group string, userID interface{}, ip string, userRule, ipRule ratelimit.Rule) []limitBucket {
+	var buckets []limitBucket
+	if userRule.Enabled() && userID != nil {
+		buckets = append(buckets, limitBucket{kind: "user", key: fmt.Sprintf("%s:user:%v", group, userID), rule: userRule})
+	}
+	if ipRule.Enabled() && ip != "" {
+		buckets = append(buckets, limitBucket{kind: "IP", key: fmt.Sprintf("%s:ip:%s", group, ip), rule: ipRule})
+	}
+	return buckets
+}
+
+// takeTokens takes a token from each bucket in turn. It stops at the first
+// bucket that denies the request and refunds the tokens already taken, so a
+// denied request costs none of its buckets anything and users sharing an IP
+// are not penalised for one another. A bucket whose store fails is skipped,
+// allowing the request.
+func takeTokens(ctx context.Context, store ratelimit.Store, buckets []limitBucket) []ratelimit.Result {
+	var results []ratelimit.Result
+	// taken holds the buckets a token was taken from and the index of each
+	// one's result
+	type token struct {
+		bucket limitBucket
+		result int
+	}
+	var taken []token
+	for _, b := range buckets {
+		res, err := store.Take(ctx, b.key, b.rule)
+		if err != nil {
+			slog.WarnContext(ctx, "RateLimitMiddleware: Failed to check "+b.kind+" limit, allowing request", "error", err)
+			continue
+		}
+		results = append(results, res)
+		if res.Allowed {
+			taken = append(taken, token{bucket: b, result: len(results) - 1})
+			continue
+		}
+
+		for _, t := range taken {
+			if err := store.Refund(ctx, t.bucket.key, t.bucket.rule); err != nil {
+				slog.WarnContext(ctx, "RateLimitMiddleware: Failed to refund "+t.bucket.kind+" token", "error", err)
+				continue
+			}
+			// Report the bucket as it is after the refund
+			refunded := &results[t.result]
+			refunded.Remaining = min(refunded.Remaining+1, refunded.Limit)
+		}
+		break
+	}
+	return results
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CircleConnectApp/feed-service/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// rateLimitedRouter serves GET /x for the user given in the X-User header,
+// limited by rules
+func rateLimitedRouter(store ratelimit.Store, user, ip string) *gin.Engine {
+	r := gin.New()
+	r.GET("/x", func(c *gin.Context) {
+		if id := c.GetHeader("X-User"); id != "" {
+			c.Set("user_id", id)
+		}
+	}, RateLimitMiddleware(store, "feed", func(string) (ratelimit.Rule, ratelimit.Rule) {
+		u, _ := ratelimit.ParseRule(user)
+		i, _ := ratelimit.ParseRule(ip)
+		return u, i
+	}), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func get(r *gin.Engine, user string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/x", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		user, ip string
+		requests []string // user of each request
+		want     []int
+	}{
+		{
+			name:     "user limit",
+			user:     "2/m",
+			ip:       "off",
+			requests: []string{"1", "1", "1", "2"},
+			want:     []int{200, 200, 429, 200},
+		},
+		{
+			name:     "IP limit",
+			user:     "off",
+			ip:       "2/m",
+			requests: []string{"1", "2", "3"},
+			want:     []int{200, 200, 429},
+		},
+		{
+			// A user denied by their own limit must not use up the IP's
+			// tokens for the other users behind it
+			name:     "user denial keeps IP tokens",
+			user:     "1/m",
+			ip:       "3/m",
+			requests: []string{"1", "1", "1", "1", "2", "3", "4"},
+			want:     []int{200, 429, 429, 429, 200, 200, 429},
+		},
+		{
+			// A request denied by the IP limit does not cost the user a token
+			name:     "IP denial refunds user token",
+			user:     "1/m",
+			ip:       "1/m",
+			requests: []string{"1", "2", "2"},
+			want:     []int{200, 429, 429},
+		},
+		{
+			name:     "unauthenticated requests use the IP limit only",
+			user:     "1/m",
+			ip:       "2/m",
+			requests: []string{"", "", ""},
+			want:     []int{200, 200, 429},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := rateLimitedRouter(ratelimit.NewMemoryStore(), tt.user, tt.ip)
+			for i, user := range tt.requests {
+				w := get(r, user)
+				if w.Code != tt.want[i] {
+					t.Fatalf("request %d (user %q): status %d, want %d", i, user, w.Code, tt.want[i])
+				}
+				if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+					t.Errorf("request %d: 429 without Retry-After", i)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimitRefundedUserToken(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	r := rateLimitedRouter(store, "1/m", "1/m")
+
+	// User 2 exhausts the IP; user 1's denied request leaves user 1's own
+	// bucket full
+	get(r, "2")
+	if w := get(r, "1"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status %d, want 429", w.Code)
+	}
+	res, err := store.Take(context.Background(), "feed:user:1", ratelimit.Rule{Rate: 1.0 / 60, Burst: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Allowed {
+		t.Error("user 1 was charged for a request denied by the IP limit")
+	}
+}
+
+func TestRateLimitHeadersAfterRefund(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	r := rateLimitedRouter(store, "1/m", "2/m")
+
+	// Users 2 and 3 exhaust the IP. User 1's request takes user 1's only
+	// token before the IP denies it, and the refund puts it back, so the
+	// headers describe the IP bucket.
+	get(r, "2")
+	get(r, "3")
+	w := get(r, "1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status %d, want 429", w.Code)
+	}
+	if got := w.Header().Get("RateLimit-Limit"); got != "2" {
+		t.Errorf("RateLimit-Limit = %q, want the IP limit 2", got)
+	}
+	if got := w.Header().Get("RateLimit-Remaining"); got != "0" {
+		t.Errorf("RateLimit-Remaining = %q, want 0", got)
+	}
+
+	results := takeTokens(context.Background(), store, []limitBucket{
+		{kind: "user", key: "feed:user:4", rule: ratelimit.Rule{Rate: 1.0 / 60, Burst: 3}},
+		{kind: "IP", key: "feed:ip:192.0.2.1", rule: ratelimit.Rule{Rate: 2.0 / 60, Burst: 2}},
+	})
+	if len(results) != 2 || results[1].Allowed {
+		t.Fatalf("results = %+v, want the IP to deny", results)
+	}
+	if results[0].Remaining != 3 {
+		t.Errorf("refunded user bucket remaining = %d, want 3", results[0].Remaining)
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/CircleConnectApp/feed-service/logger"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		wantSame bool
+	}{
+		{name: "accepts the caller's ID", header: "abc-123", wantSame: true},
+		{name: "generates a missing ID"},
+		{name: "replaces an ID with spaces", header: "abc 123"},
+		{name: "replaces an overlong ID", header: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fromContext string
+			r := gin.New()
+			r.Use(RequestIDMiddleware())
+			r.GET("/x", func(c *gin.Context) {
+				fromContext = logger.RequestID(c.Request.Context())
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/x", nil)
+			if tt.header != "" {
+				req.Header.Set(logger.RequestIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			got := w.Header().Get(logger.RequestIDHeader)
+			if tt.wantSame && got != tt.header {
+				t.Errorf("request ID = %q, want %q", got, tt.header)
+			}
+			if !tt.wantSame && (got == tt.header || len(got) != 32) {
+				t.Errorf("request ID = %q, want a generated one", got)
+			}
+			if fromContext != got {
+				t.Errorf("context request ID = %q, want %q", fromContext, got)
+			}
+		})
+	}
+}
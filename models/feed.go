@@ -3,31 +3,45 @@ package models
 import (
 	"time"
 
+	"github.com/CircleConnectApp/feed-service/ranking"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // FeedItem represents a post in the user's feed
 type FeedItem struct {
-	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	PostID       primitive.ObjectID `bson:"post_id" json:"post_id"`
-	UserID       int                `bson:"user_id" json:"user_id"`
-	CommunityID  int                `bson:"community_id" json:"community_id"`
-	Title        string             `bson:"title" json:"title"`
-	Content      string             `bson:"content" json:"content"`
-	LikeCount    int                `bson:"like_count" json:"like_count"`
-	MediaURLs    []string           `bson:"media_urls,omitempty" json:"media_urls,omitempty"`
-	Tags         []string           `bson:"tags,omitempty" json:"tags,omitempty"`
-	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
-	Relevance    float64            `bson:"relevance" json:"relevance"`
-	AuthorName   string             `bson:"author_name" json:"author_name"`
-	AuthorAvatar string             `bson:"author_avatar,omitempty" json:"author_avatar,omitempty"`
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PostID        primitive.ObjectID `bson:"post_id" json:"post_id"`
+	UserID        int                `bson:"user_id" json:"user_id"`
+	CommunityID   int                `bson:"community_id" json:"community_id"`
+	CommunityName string             `bson:"community_name,omitempty" json:"community_name,omitempty"`
+	CommunityIcon string             `bson:"community_icon,omitempty" json:"community_icon,omitempty"`
+	Title         string             `bson:"title" json:"title"`
+	Content       string             `bson:"content" json:"content"`
+	LikeCount     int                `bson:"like_count" json:"like_count"`
+	MediaURLs     []string           `bson:"media_urls,omitempty" json:"media_urls,omitempty"`
+	Tags          []string           `bson:"tags,omitempty" json:"tags,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	Relevance     float64            `bson:"relevance" json:"relevance"`
+	AuthorName    string             `bson:"author_name" json:"author_name"`
+	AuthorAvatar  string             `bson:"author_avatar,omitempty" json:"author_avatar,omitempty"`
+	Source        string             `bson:"source,omitempty" json:"source,omitempty"` // "joined", "recommended" or "trending" in blended feeds
+	Pinned        bool               `bson:"pinned,omitempty" json:"pinned,omitempty"`
+	Signals       ranking.Signals    `bson:"-" json:"-"` // ranking inputs, logged with impressions
 }
 
+// Sources of items in a blended feed
+const (
+	SourceJoined      = "joined"
+	SourceRecommended = "recommended"
+	SourceTrending    = "trending"
+)
+
 // UserPreference stores a user's feed preferences
 type UserPreference struct {
 	ID                  primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	UserID              int                `bson:"user_id" json:"user_id"`
-	FeedSortMethod      string             `bson:"feed_sort_method" json:"feed_sort_method"` // "date", "relevance", "popular"
+	FeedSortMethod      string             `bson:"feed_sort_method" json:"feed_sort_method"`                     // "date", "relevance", "popular", "hot", "top", "rising"
+	FeedSortPeriod      string             `bson:"feed_sort_period,omitempty" json:"feed_sort_period,omitempty"` // period for "top": "day", "week", "month", "year", "all"
 	PreferedTags        []string           `bson:"prefered_tags,omitempty" json:"prefered_tags,omitempty"`
 	ExcludedTags        []string           `bson:"excluded_tags,omitempty" json:"excluded_tags,omitempty"`
 	PreferedCommunities []int              `bson:"prefered_communities,omitempty" json:"prefered_communities,omitempty"`
@@ -36,25 +50,123 @@ type UserPreference struct {
 
 // Feed represents a collection of FeedItems
 type Feed struct {
-	Items []FeedItem `json:"items"`
-	Total int        `json:"total"`
-	Page  int        `json:"page"`
-	Limit int        `json:"limit"`
+	Items       []FeedItem        `json:"items"`
+	Total       int               `json:"total"`
+	Page        int               `json:"page"`
+	Limit       int               `json:"limit"`
+	Experiments map[string]string `json:"experiments,omitempty"` // experiment name -> variant served
 }
 
 // FeedQuery represents query parameters for feed retrieval
 type FeedQuery struct {
-	SortBy      string   `form:"sort_by" json:"sort_by"` // date, relevance, popular
+	SortBy      string   `form:"sort_by" json:"sort_by" binding:"omitempty,oneof=date relevance popular hot top rising"`
+	Period      string   `form:"period" json:"period" binding:"omitempty,oneof=day week month year all"` // for top
 	Tags        []string `form:"tags" json:"tags"`
 	CommunityID int      `form:"community_id" json:"community_id"`
 	Page        int      `form:"page" json:"page"`
 	Limit       int      `form:"limit" json:"limit"`
 }
 
+// FeedViewQuery selects the fields returned for feed items and how their
+// content is shown
+type FeedViewQuery struct {
+	Fields         string `form:"fields"`                                             // comma-separated JSON field names
+	ContentPreview int    `form:"content_preview" binding:"omitempty,min=1,max=2000"` // plain-text excerpt length instead of full content
+}
+
+// CommunitySuggestion is a community recommended to a user, with the
+// signals that suggested it
+type CommunitySuggestion struct {
+	CommunityID int                `json:"community_id"`
+	Name        string             `json:"name"`
+	Icon        string             `json:"icon,omitempty"`
+	Score       float64            `json:"score"`
+	Reasons     []SuggestionReason `json:"reasons"`
+}
+
+// Reasons a community is suggested
+const (
+	ReasonTagOverlap   = "tag_overlap"
+	ReasonCoMembership = "co_membership"
+	ReasonEngagement   = "engagement"
+)
+
+// SuggestionReason explains one signal behind a community suggestion
+type SuggestionReason struct {
+	Type    string   `json:"type"`
+	Tags    []string `json:"tags,omitempty"`    // tag_overlap: preferred tags on the community's popular posts
+	Posts   int      `json:"posts,omitempty"`   // tag_overlap and engagement: posts behind the reason
+	Members int      `json:"members,omitempty"` // co_membership: similar users who joined
+}
+
+// CommunitySuggestionQuery represents query parameters for community suggestions
+type CommunitySuggestionQuery struct {
+	Limit int `form:"limit" binding:"omitempty,min=1,max=50"`
+}
+
+// CommunityFeedQuery represents query parameters for a community feed
+type CommunityFeedQuery struct {
+	Sort   string `form:"sort" binding:"omitempty,oneof=new hot top rising"`
+	Period string `form:"t" binding:"omitempty,oneof=day week month year all"` // for top
+	Page   int    `form:"page"`
+	Limit  int    `form:"limit"`
+}
+
+// CommunityPin is a post pinned to the top of a community feed
+type CommunityPin struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	CommunityID int                `bson:"community_id" json:"community_id"`
+	PostID      string             `bson:"post_id" json:"post_id"`
+	PinnedBy    int                `bson:"pinned_by" json:"pinned_by"`
+	PinnedAt    time.Time          `bson:"pinned_at" json:"pinned_at"`
+	Slot        int                `bson:"slot" json:"-"` // one of the community's pin slots, unique within it
+}
+
+// InteractionRequest reports user interactions with feed items
+type InteractionRequest struct {
+	Events []InteractionEvent `json:"events" binding:"required,min=1,max=100,dive"`
+}
+
+// InteractionEvent is a single interaction with a post shown in a feed
+type InteractionEvent struct {
+	PostID     string     `json:"post_id" binding:"required"`
+	Type       string     `json:"type" binding:"required"` // click, like, share, comment, hide
+	Feed       string     `json:"feed"`                    // personal, recommended, home, community
+	Position   int        `json:"position"`
+	OccurredAt *time.Time `json:"occurred_at,omitempty"`
+}
+
+// PostEventsRequest reports changes to posts' likes from the post service
+type PostEventsRequest struct {
+	Events []PostEvent `json:"events" binding:"required,min=1,max=500,dive"`
+}
+
+// PostEvent is a like or unlike of a post
+type PostEvent struct {
+	Type        string    `json:"type" binding:"required,oneof=post_liked post_unliked"`
+	PostID      string    `json:"post_id" binding:"required"`
+	CommunityID int       `json:"community_id"`
+	AuthorID    int       `json:"author_id"`
+	Tags        []string  `json:"tags,omitempty"`
+	OccurredAt  time.Time `json:"occurred_at" binding:"required"`
+}
+
 // UpdatePreferenceRequest is used to update user preferences
 type UpdatePreferenceRequest struct {
-	FeedSortMethod      string   `json:"feed_sort_method,omitempty"`
+	FeedSortMethod      string   `json:"feed_sort_method,omitempty" binding:"omitempty,oneof=date relevance popular hot top rising"`
+	FeedSortPeriod      string   `json:"feed_sort_period,omitempty" binding:"omitempty,oneof=day week month year all"`
 	PreferedTags        []string `json:"prefered_tags,omitempty"`
 	ExcludedTags        []string `json:"excluded_tags,omitempty"`
 	PreferedCommunities []int    `json:"prefered_communities,omitempty"`
 }
+
+// SyndicationQuery represents query parameters for the RSS, Atom and JSON Feed exports
+type SyndicationQuery struct {
+	CommunityID int `form:"community_id"` // export one community instead of the user's feed
+	Limit       int `form:"limit"`
+}
+
+// CreateFeedTokenRequest names a new feed token, typically after the reader using it
+type CreateFeedTokenRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
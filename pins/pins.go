@@ -0,0 +1,112 @@
+// Package pins keeps the posts communities pin to the top of their feeds
+package pins
+
+import (
+	"context"
+	"errors"
+
+	"github.com/CircleConnectApp/feed-service/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MaxPerCommunity bounds how many posts a community can pin
+const MaxPerCommunity = 3
+
+var (
+	// ErrNotFound is returned when unpinning a post that is not pinned
+	ErrNotFound = errors.New("post is not pinned")
+	// ErrTooMany is returned when a community already pins MaxPerCommunity posts
+	ErrTooMany = errors.New("too many pinned posts")
+)
+
+// Store keeps pinned posts, one document per pin. Each pin takes one of its
+// community's MaxPerCommunity slots, and unique indexes on the slots and on
+// the posts keep concurrent pins within the limit.
+type Store struct {
+	collection *mongo.Collection
+}
+
+// NewStore creates the store's indexes
+func NewStore(ctx context.Context, collection *mongo.Collection) (*Store, error) {
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "community_id", Value: 1}, {Key: "slot", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "community_id", Value: 1}, {Key: "post_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{collection: collection}, nil
+}
+
+// List returns a community's pinned posts, most recently pinned first
+func (s *Store) List(ctx context.Context, communityID int) ([]models.CommunityPin, error) {
+	cursor, err := s.collection.Find(ctx, bson.M{"community_id": communityID},
+		options.Find().SetSort(bson.D{{Key: "pinned_at", Value: -1}}).SetLimit(MaxPerCommunity))
+	if err != nil {
+		return nil, err
+	}
+
+	pins := []models.CommunityPin{}
+	if err := cursor.All(ctx, &pins); err != nil {
+		return nil, err
+	}
+	return pins, nil
+}
+
+// Pin pins a post in the first free slot of its community, or records who
+// pinned it again and when if it is already pinned. It returns ErrTooMany
+// when every slot is taken.
+func (s *Store) Pin(ctx context.Context, pin models.CommunityPin) error {
+	repinned, err := s.repin(ctx, pin)
+	if err != nil || repinned {
+		return err
+	}
+
+	for slot := 0; slot < MaxPerCommunity; slot++ {
+		pin.Slot = slot
+		_, err := s.collection.InsertOne(ctx, pin)
+		if !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+	}
+
+	// Every insert conflicted, with the pins of other posts or with a
+	// concurrent pin of this one
+	repinned, err = s.repin(ctx, pin)
+	if err != nil || repinned {
+		return err
+	}
+	return ErrTooMany
+}
+
+// repin updates a pin if the post is already pinned
+func (s *Store) repin(ctx context.Context, pin models.CommunityPin) (bool, error) {
+	res, err := s.collection.UpdateOne(ctx,
+		bson.M{"community_id": pin.CommunityID, "post_id": pin.PostID},
+		bson.M{"$set": bson.M{"pinned_by": pin.PinnedBy, "pinned_at": pin.PinnedAt}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+// Unpin removes a pinned post, freeing its slot
+func (s *Store) Unpin(ctx context.Context, communityID int, postID string) error {
+	res, err := s.collection.DeleteOne(ctx, bson.M{"community_id": communityID, "post_id": postID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
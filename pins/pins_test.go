@@ -0,0 +1,99 @@
+package pins
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// testStore returns a store on the mocked database
+func testStore(mt *mtest.T) *Store {
+	mt.AddMockResponses(mtest.CreateSuccessResponse())
+	store, err := NewStore(context.Background(), mt.Coll)
+	if err != nil {
+		mt.Fatal(err)
+	}
+	return store
+}
+
+func TestStorePin(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	matched := func(n int) bson.D { return mtest.CreateSuccessResponse(bson.E{Key: "n", Value: n}) }
+	taken := mtest.CreateWriteErrorsResponse(mtest.WriteError{Code: 11000, Message: "duplicate key"})
+	inserted := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1})
+
+	tests := []struct {
+		name      string
+		responses []bson.D
+		wantSlots []int // of the inserts, in order
+		wantErr   error
+	}{
+		{name: "takes the first slot", responses: []bson.D{matched(0), inserted}, wantSlots: []int{0}},
+		{name: "takes the first free slot", responses: []bson.D{matched(0), taken, inserted}, wantSlots: []int{0, 1}},
+		{name: "repins a pinned post", responses: []bson.D{matched(1)}},
+		{name: "every slot is taken", responses: []bson.D{matched(0), taken, taken, taken, matched(0)}, wantSlots: []int{0, 1, 2}, wantErr: ErrTooMany},
+		// The post was pinned by a concurrent request, which took a slot first
+		{name: "pinned concurrently", responses: []bson.D{matched(0), taken, taken, taken, matched(1)}, wantSlots: []int{0, 1, 2}},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			store := testStore(mt)
+			mt.AddMockResponses(tt.responses...)
+			mt.ClearEvents()
+
+			err := store.Pin(context.Background(), models.CommunityPin{CommunityID: 10, PostID: "p1", PinnedBy: 7, PinnedAt: time.Now().UTC()})
+			if !errors.Is(err, tt.wantErr) {
+				mt.Fatalf("Pin() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var slots []int
+			for _, event := range mt.GetAllStartedEvents() {
+				if event.CommandName != "insert" {
+					continue
+				}
+				docs, err := event.Command.Lookup("documents").Array().Values()
+				if err != nil {
+					mt.Fatal(err)
+				}
+				for _, doc := range docs {
+					slots = append(slots, int(doc.Document().Lookup("slot").Int32()))
+				}
+			}
+			if !reflect.DeepEqual(slots, tt.wantSlots) {
+				mt.Errorf("inserted slots = %v, want %v", slots, tt.wantSlots)
+			}
+		})
+	}
+}
+
+func TestStoreUnpin(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	tests := []struct {
+		name    string
+		deleted int
+		wantErr error
+	}{
+		{name: "unpins a post", deleted: 1},
+		{name: "post is not pinned", wantErr: ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			store := testStore(mt)
+			mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: tt.deleted}))
+
+			if err := store.Unpin(context.Background(), 10, "p1"); !errors.Is(err, tt.wantErr) {
+				mt.Errorf("Unpin() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
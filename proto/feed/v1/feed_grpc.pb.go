@@ -0,0 +1,306 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v25.3.0
+// source: feed/v1/feed.proto
+
+package feedv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	FeedService_GetFeed_FullMethodName            = "/feed.v1.FeedService/GetFeed"
+	FeedService_GetRecommended_FullMethodName     = "/feed.v1.FeedService/GetRecommended"
+	FeedService_GetPreferences_FullMethodName     = "/feed.v1.FeedService/GetPreferences"
+	FeedService_UpdatePreferences_FullMethodName  = "/feed.v1.FeedService/UpdatePreferences"
+	FeedService_RecordInteractions_FullMethodName = "/feed.v1.FeedService/RecordInteractions"
+	FeedService_IngestPostEvents_FullMethodName   = "/feed.v1.FeedService/IngestPostEvents"
+)
+
+// FeedServiceClient is the client API for FeedService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type FeedServiceClient interface {
+	// GetFeed returns the user's personalized feed (GET /api/feed)
+	GetFeed(ctx context.Context, in *GetFeedRequest, opts ...grpc.CallOption) (*Feed, error)
+	// GetRecommended returns recommended posts (GET /api/feed/recommended)
+	GetRecommended(ctx context.Context, in *GetRecommendedRequest, opts ...grpc.CallOption) (*Feed, error)
+	// GetPreferences returns the user's feed preferences (GET /api/feed/preferences)
+	GetPreferences(ctx context.Context, in *GetPreferencesRequest, opts ...grpc.CallOption) (*Preferences, error)
+	// UpdatePreferences updates the user's feed preferences (PUT /api/feed/preferences)
+	UpdatePreferences(ctx context.Context, in *UpdatePreferencesRequest, opts ...grpc.CallOption) (*Preferences, error)
+	// RecordInteractions logs the user's interactions with feed items (POST /api/feed/interactions)
+	RecordInteractions(ctx context.Context, in *RecordInteractionsRequest, opts ...grpc.CallOption) (*RecordInteractionsResponse, error)
+	// IngestPostEvents records likes and unlikes; requires the service role (POST /api/internal/post-events)
+	IngestPostEvents(ctx context.Context, in *IngestPostEventsRequest, opts ...grpc.CallOption) (*IngestPostEventsResponse, error)
+}
+
+type feedServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFeedServiceClient(cc grpc.ClientConnInterface) FeedServiceClient {
+	return &feedServiceClient{cc}
+}
+
+func (c *feedServiceClient) GetFeed(ctx context.Context, in *GetFeedRequest, opts ...grpc.CallOption) (*Feed, error) {
+	out := new(Feed)
+	err := c.cc.Invoke(ctx, FeedService_GetFeed_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *feedServiceClient) GetRecommended(ctx context.Context, in *GetRecommendedRequest, opts ...grpc.CallOption) (*Feed, error) {
+	out := new(Feed)
+	err := c.cc.Invoke(ctx, FeedService_GetRecommended_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *feedServiceClient) GetPreferences(ctx context.Context, in *GetPreferencesRequest, opts ...grpc.CallOption) (*Preferences, error) {
+	out := new(Preferences)
+	err := c.cc.Invoke(ctx, FeedService_GetPreferences_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *feedServiceClient) UpdatePreferences(ctx context.Context, in *UpdatePreferencesRequest, opts ...grpc.CallOption) (*Preferences, error) {
+	out := new(Preferences)
+	err := c.cc.Invoke(ctx, FeedService_UpdatePreferences_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *feedServiceClient) RecordInteractions(ctx context.Context, in *RecordInteractionsRequest, opts ...grpc.CallOption) (*RecordInteractionsResponse, error) {
+	out := new(RecordInteractionsResponse)
+	err := c.cc.Invoke(ctx, FeedService_RecordInteractions_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *feedServiceClient) IngestPostEvents(ctx context.Context, in *IngestPostEventsRequest, opts ...grpc.CallOption) (*IngestPostEventsResponse, error) {
+	out := new(IngestPostEventsResponse)
+	err := c.cc.Invoke(ctx, FeedService_IngestPostEvents_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// FeedServiceServer is the server API for FeedService service.
+// All implementations must embed UnimplementedFeedServiceServer
+// for forward compatibility
+type FeedServiceServer interface {
+	// GetFeed returns the user's personalized feed (GET /api/feed)
+	GetFeed(context.Context, *GetFeedRequest) (*Feed, error)
+	// GetRecommended returns recommended posts (GET /api/feed/recommended)
+	GetRecommended(context.Context, *GetRecommendedRequest) (*Feed, error)
+	// GetPreferences returns the user's feed preferences (GET /api/feed/preferences)
+	GetPreferences(context.Context, *GetPreferencesRequest) (*Preferences, error)
+	// UpdatePreferences updates the user's feed preferences (PUT /api/feed/preferences)
+	UpdatePreferences(context.Context, *UpdatePreferencesRequest) (*Preferences, error)
+	// RecordInteractions logs the user's interactions with feed items (POST /api/feed/interactions)
+	RecordInteractions(context.Context, *RecordInteractionsRequest) (*RecordInteractionsResponse, error)
+	// IngestPostEvents records likes and unlikes; requires the service role (POST /api/internal/post-events)
+	IngestPostEvents(context.Context, *IngestPostEventsRequest) (*IngestPostEventsResponse, error)
+	mustEmbedUnimplementedFeedServiceServer()
+}
+
+// UnimplementedFeedServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedFeedServiceServer struct {
+}
+
+func (UnimplementedFeedServiceServer) GetFeed(context.Context, *GetFeedRequest) (*Feed, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFeed not implemented")
+}
+func (UnimplementedFeedServiceServer) GetRecommended(context.Context, *GetRecommendedRequest) (*Feed, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRecommended not implemented")
+}
+func (UnimplementedFeedServiceServer) GetPreferences(context.Context, *GetPreferencesRequest) (*Preferences, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPreferences not implemented")
+}
+func (UnimplementedFeedServiceServer) UpdatePreferences(context.Context, *UpdatePreferencesRequest) (*Preferences, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdatePreferences not implemented")
+}
+func (UnimplementedFeedServiceServer) RecordInteractions(context.Context, *RecordInteractionsRequest) (*RecordInteractionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RecordInteractions not implemented")
+}
+func (UnimplementedFeedServiceServer) IngestPostEvents(context.Context, *IngestPostEventsRequest) (*IngestPostEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IngestPostEvents not implemented")
+}
+func (UnimplementedFeedServiceServer) mustEmbedUnimplementedFeedServiceServer() {}
+
+// UnsafeFeedServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to FeedServiceServer will
+// result in compilation errors.
+type UnsafeFeedServiceServer interface {
+	mustEmbedUnimplementedFeedServiceServer()
+}
+
+func RegisterFeedServiceServer(s grpc.ServiceRegistrar, srv FeedServiceServer) {
+	s.RegisterService(&FeedService_ServiceDesc, srv)
+}
+
+func _FeedService_GetFeed_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetFeedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FeedServiceServer).GetFeed(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FeedService_GetFeed_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FeedServiceServer).GetFeed(ctx, req.(*GetFeedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FeedService_GetRecommended_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRecommendedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FeedServiceServer).GetRecommended(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FeedService_GetRecommended_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FeedServiceServer).GetRecommended(ctx, req.(*GetRecommendedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FeedService_GetPreferences_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPreferencesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FeedServiceServer).GetPreferences(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FeedService_GetPreferences_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FeedServiceServer).GetPreferences(ctx, req.(*GetPreferencesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FeedService_UpdatePreferences_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdatePreferencesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FeedServiceServer).UpdatePreferences(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FeedService_UpdatePreferences_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FeedServiceServer).UpdatePreferences(ctx, req.(*UpdatePreferencesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FeedService_RecordInteractions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecordInteractionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FeedServiceServer).RecordInteractions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FeedService_RecordInteractions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FeedServiceServer).RecordInteractions(ctx, req.(*RecordInteractionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FeedService_IngestPostEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IngestPostEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FeedServiceServer).IngestPostEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FeedService_IngestPostEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FeedServiceServer).IngestPostEvents(ctx, req.(*IngestPostEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// FeedService_ServiceDesc is the grpc.ServiceDesc for FeedService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var FeedService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "feed.v1.FeedService",
+	HandlerType: (*FeedServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetFeed",
+			Handler:    _FeedService_GetFeed_Handler,
+		},
+		{
+			MethodName: "GetRecommended",
+			Handler:    _FeedService_GetRecommended_Handler,
+		},
+		{
+			MethodName: "GetPreferences",
+			Handler:    _FeedService_GetPreferences_Handler,
+		},
+		{
+			MethodName: "UpdatePreferences",
+			Handler:    _FeedService_UpdatePreferences_Handler,
+		},
+		{
+			MethodName: "RecordInteractions",
+			Handler:    _FeedService_RecordInteractions_Handler,
+		},
+		{
+			MethodName: "IngestPostEvents",
+			Handler:    _FeedService_IngestPostEvents_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "feed/v1/feed.proto",
+}
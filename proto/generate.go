@@ -0,0 +1,5 @@
+// Package proto holds the protobuf definitions of the gRPC API. Regenerate
+// the Go code with protoc, protoc-gen-go and protoc-gen-go-grpc installed.
+package proto
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative feed/v1/feed.proto
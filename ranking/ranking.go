@@ -0,0 +1,65 @@
+package ranking
+
+import (
+	"math"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/settings"
+)
+
+// Signals are the per-post inputs to the ranking formula. They are captured
+// when a feed is served so rankings can be replayed offline.
+type Signals struct {
+	LikeCount           int       `json:"like_count"`
+	CreatedAt           time.Time `json:"created_at"`
+	PreferredTagMatches int       `json:"preferred_tag_matches,omitempty"`
+	PreferredCommunity  bool      `json:"preferred_community,omitempty"`
+	DemographicMatches  int       `json:"demographic_matches,omitempty"`
+	Sources             []string  `json:"candidate_sources,omitempty"` // candidate sources that proposed a recommended post
+}
+
+// Periods are the periods of the top sort; zero means all time
+var Periods = map[string]time.Duration{
+	"day":   24 * time.Hour,
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+	"year":  365 * 24 * time.Hour,
+	"all":   0,
+}
+
+// hotEpoch anchors the time term of Hot; only differences between scores matter
+const hotEpoch = 1700000000
+
+// Hot scores a post for the hot sort: the order of magnitude of its likes
+// plus a term that grows with its creation time, so ten times the likes buys
+// a post 12.5 hours of age
+func Hot(likeCount int, createdAt time.Time) float64 {
+	order := math.Log10(math.Max(float64(likeCount), 1))
+	return order + float64(createdAt.Unix()-hotEpoch)/45000
+}
+
+// Rising scores a post for the rising sort: its likes per hour of age with a
+// gravity that favours posts picking up likes soon after they are created
+func Rising(likeCount int, createdAt, now time.Time) float64 {
+	ageHours := math.Max(now.Sub(createdAt).Hours(), 0)
+	return float64(likeCount) / math.Pow(ageHours+2, 1.5)
+}
+
+// Score ranks a post as of now; higher scores rank first
+func Score(s Signals, weights settings.RankingWeights, now time.Time) float64 {
+	// Simple algorithm based on popularity and recency
+	recencyFactor := 1.0 / (1.0 + now.Sub(s.CreatedAt).Hours()/24.0)   // Higher value for more recent posts
+	popularityFactor := float64(s.LikeCount) / weights.PopularityScale // Normalize like count
+
+	// Combine factors with weights
+	score := (weights.RecencyWeight * recencyFactor) + (weights.PopularityWeight * popularityFactor)
+
+	// Boosts for matching the user's preferences and demographics
+	score += float64(s.PreferredTagMatches) * weights.PreferredTagBoost
+	if s.PreferredCommunity {
+		score += weights.PreferredCommunityBoost
+	}
+	score += float64(s.DemographicMatches) * weights.DemographicBoost
+
+	return score
+}
@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const memorySweepInterval = time.Minute
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	rule      Rule
+}
+
+// MemoryStore keeps buckets in process memory. It is only accurate when the
+// service runs as a single replica.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewMemoryStore creates an empty in-memory bucket store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Take refills the bucket for key and consumes one token if available
+func (s *MemoryStore) Take(ctx context.Context, key string, rule Rule) (Result, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > memorySweepInterval {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rule.Burst), updatedAt: now}
+		s.buckets[key] = b
+	}
+	b.rule = rule
+
+	elapsed := now.Sub(b.updatedAt).Seconds()
+	b.tokens = math.Min(float64(rule.Burst), b.tokens+elapsed*rule.Rate)
+	b.updatedAt = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	return result(rule, b.tokens, allowed), nil
+}
+
+// Refund returns a token to the bucket for key
+func (s *MemoryStore) Refund(ctx context.Context, key string, rule Rule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if b, ok := s.buckets[key]; ok {
+		b.tokens = math.Min(float64(rule.Burst), b.tokens+1)
+	}
+	return nil
+}
+
+// sweep drops buckets that have refilled completely since they were last used
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		elapsed := now.Sub(b.updatedAt).Seconds()
+		if b.tokens+elapsed*b.rule.Rate >= float64(b.rule.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
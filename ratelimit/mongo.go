@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoStore keeps buckets in a MongoDB collection so that every replica of
+// the service shares the same limits. Each take is a single atomic
+// pipeline update, and exhausted buckets expire through a TTL index once they
+// would have refilled.
+type MongoStore struct {
+	collection *mongo.Collection
+}
+
+type mongoBucket struct {
+	Tokens  float64 `bson:"tokens"`
+	Allowed bool    `bson:"allowed"`
+}
+
+// NewMongoStore creates a shared bucket store backed by collection
+func NewMongoStore(collection *mongo.Collection) (*MongoStore, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &MongoStore{collection: collection}, nil
+}
+
+// Take refills the bucket for key and consumes one token if available
+func (s *MongoStore) Take(ctx context.Context, key string, rule Rule) (Result, error) {
+	elapsedSeconds := bson.M{"$divide": bson.A{
+		bson.M{"$subtract": bson.A{"$$NOW", bson.M{"$ifNull": bson.A{"$updated_at", "$$NOW"}}}},
+		1000,
+	}}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"tokens": bson.M{"$min": bson.A{
+				rule.Burst,
+				bson.M{"$add": bson.A{
+					bson.M{"$ifNull": bson.A{"$tokens", rule.Burst}},
+					bson.M{"$multiply": bson.A{elapsedSeconds, rule.Rate}},
+				}},
+			}},
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"allowed": bson.M{"$gte": bson.A{"$tokens", 1}},
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"tokens":     bson.M{"$cond": bson.A{"$allowed", bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}},
+			"updated_at": "$$NOW",
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"expires_at": bson.M{"$add": bson.A{
+				"$$NOW",
+				bson.M{"$multiply": bson.A{
+					bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{rule.Burst, "$tokens"}}, rule.Rate}},
+					1000,
+				}},
+			}},
+		}}},
+	}
+
+	opts := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{"tokens": 1, "allowed": 1})
+
+	var b mongoBucket
+	if err := s.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, pipeline, opts).Decode(&b); err != nil {
+		return Result{}, err
+	}
+
+	return result(rule, b.Tokens, b.Allowed), nil
+}
+
+// Refund returns a token to the bucket for key. Buckets that have expired
+// are already full, so nothing is created.
+func (s *MongoStore) Refund(ctx context.Context, key string, rule Rule) error {
+	_, err := s.collection.UpdateOne(ctx, bson.M{"_id": key}, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"tokens": bson.M{"$min": bson.A{rule.Burst, bson.M{"$add": bson.A{"$tokens", 1}}}},
+		}}},
+	})
+	return err
+}
@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule describes a token bucket: Burst tokens of capacity refilled at Rate tokens per second
+type Rule struct {
+	Rate  float64
+	Burst int
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration // time until the next token is available, zero when allowed
+	Reset      time.Duration // time until the bucket is full again
+}
+
+// Store takes tokens from named buckets
+type Store interface {
+	Take(ctx context.Context, key string, rule Rule) (Result, error)
+	// Refund returns a token taken from a bucket, up to its capacity
+	Refund(ctx context.Context, key string, rule Rule) error
+}
+
+// Enabled reports whether the rule limits anything
+func (r Rule) Enabled() bool {
+	return r.Rate > 0 && r.Burst > 0
+}
+
+// ParseRule parses a rule such as "120/m" or "10/s". The bucket holds the full
+// request count and refills evenly over the period. An empty string or "off"
+// yields a disabled rule.
+func ParseRule(spec string) (Rule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" || spec == "off" {
+		return Rule{}, nil
+	}
+
+	parts := strings.SplitN(spec, "/", 2)
+	if len(parts) != 2 {
+		return Rule{}, fmt.Errorf("invalid rate limit %q: expected <requests>/<period>", spec)
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || count <= 0 {
+		return Rule{}, fmt.Errorf("invalid rate limit %q: request count must be a positive integer", spec)
+	}
+
+	var period time.Duration
+	switch strings.TrimSpace(parts[1]) {
+	case "s":
+		period = time.Second
+	case "m":
+		period = time.Minute
+	case "h":
+		period = time.Hour
+	default:
+		return Rule{}, fmt.Errorf("invalid rate limit %q: period must be s, m or h", spec)
+	}
+
+	return Rule{
+		Rate:  float64(count) / period.Seconds(),
+		Burst: count,
+	}, nil
+}
+
+// result builds a Result from the tokens left in a bucket after a take
+func result(rule Rule, tokens float64, allowed bool) Result {
+	res := Result{
+		Allowed:   allowed,
+		Limit:     rule.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     secondsToDuration((float64(rule.Burst) - tokens) / rule.Rate),
+	}
+	if res.Remaining < 0 {
+		res.Remaining = 0
+	}
+	if !allowed {
+		res.RetryAfter = secondsToDuration((1 - tokens) / rule.Rate)
+	}
+	return res
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Rule
+		wantErr bool
+	}{
+		{spec: "", want: Rule{}},
+		{spec: "off", want: Rule{}},
+		{spec: "10/s", want: Rule{Rate: 10, Burst: 10}},
+		{spec: "120/m", want: Rule{Rate: 2, Burst: 120}},
+		{spec: " 36 / h ", want: Rule{Rate: 0.01, Burst: 36}},
+		{spec: "10", wantErr: true},
+		{spec: "0/m", wantErr: true},
+		{spec: "-5/m", wantErr: true},
+		{spec: "ten/m", wantErr: true},
+		{spec: "10/d", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseRule(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRule(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseRule(%q) = %+v, want %+v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	rule := Rule{Rate: 1.0 / 60, Burst: 2}
+
+	for i, wantAllowed := range []bool{true, true, false} {
+		res, err := store.Take(ctx, "k", rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Allowed != wantAllowed {
+			t.Fatalf("take %d: allowed = %v, want %v", i, res.Allowed, wantAllowed)
+		}
+		if res.Limit != 2 {
+			t.Errorf("take %d: limit = %d, want 2", i, res.Limit)
+		}
+		if !res.Allowed && (res.RetryAfter <= 0 || res.RetryAfter > time.Minute) {
+			t.Errorf("take %d: retry after = %v, want within a minute", i, res.RetryAfter)
+		}
+	}
+
+	if res, _ := store.Take(ctx, "other", rule); !res.Allowed || res.Remaining != 1 {
+		t.Errorf("separate key: got %+v, want allowed with 1 remaining", res)
+	}
+}
+
+func TestMemoryStoreRefund(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	rule := Rule{Rate: 1.0 / 60, Burst: 1}
+
+	if res, _ := store.Take(ctx, "k", rule); !res.Allowed {
+		t.Fatal("first take denied")
+	}
+	if err := store.Refund(ctx, "k", rule); err != nil {
+		t.Fatal(err)
+	}
+	if res, _ := store.Take(ctx, "k", rule); !res.Allowed {
+		t.Fatal("take after refund denied")
+	}
+
+	// Refunds never fill a bucket past its capacity
+	store.Refund(ctx, "k", rule)
+	store.Refund(ctx, "k", rule)
+	if res, _ := store.Take(ctx, "k", rule); !res.Allowed || res.Remaining != 0 {
+		t.Errorf("take after double refund = %+v, want allowed with 0 remaining", res)
+	}
+}
@@ -2,60 +2,125 @@ package routes
 
 import (
 	"database/sql"
-	"log"
+	"log/slog"
+	"os"
 
 	"github.com/CircleConnectApp/feed-service/config"
 	"github.com/CircleConnectApp/feed-service/controllers"
+	"github.com/CircleConnectApp/feed-service/database"
+	"github.com/CircleConnectApp/feed-service/feedtokens"
+	"github.com/CircleConnectApp/feed-service/graphqlapi"
+	"github.com/CircleConnectApp/feed-service/health"
 	"github.com/CircleConnectApp/feed-service/middleware"
+	"github.com/CircleConnectApp/feed-service/ratelimit"
+	"github.com/CircleConnectApp/feed-service/settings"
+	"github.com/CircleConnectApp/feed-service/tracing"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
-func SetupRoutes(r *gin.Engine, db *mongo.Database, pgDB *sql.DB) {
-	log.Println("Setting up routes...")
-
-	cfg := config.LoadConfig()
-	feedController := controllers.NewFeedController(
-		db,
-		pgDB,
-		cfg.UserServiceURL,
-		cfg.PostServiceURL,
-		cfg.CommunityServiceURL,
-	)
-
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":  "ok",
-			"service": "feed-service",
-		})
-	})
+func SetupRoutes(r *gin.Engine, cfg config.Config, pgDB *sql.DB, checker *health.Checker, settingsStore *settings.Store, feedController *controllers.FeedController, trendingController *controllers.TrendingController, tokenStore *feedtokens.Store, limitStore ratelimit.Store) {
+	slog.Debug("Setting up routes...")
+
+	r.Use(otelgin.Middleware(tracing.ServiceName))
+	r.Use(middleware.RequestIDMiddleware())
+	r.Use(middleware.RequestLoggerMiddleware())
+	r.Use(middleware.MetricsMiddleware())
+
+	settingsController := controllers.NewSettingsController(settingsStore)
+	experimentController := controllers.NewExperimentController(pgDB, settingsStore)
+	feedTokenController := controllers.NewFeedTokenController(tokenStore)
+	graphqlHandler, err := graphqlapi.NewHandler(feedController, trendingController, settingsStore)
+	if err != nil {
+		slog.Error("Failed to build GraphQL schema", "error", err)
+		os.Exit(1)
+	}
+
+	limitRules := func(group string) (ratelimit.Rule, ratelimit.Rule) {
+		return settingsStore.Current().RateLimitRules(group)
+	}
+	feedLimit := middleware.RateLimitMiddleware(limitStore, "feed", limitRules)
+	preferencesLimit := middleware.RateLimitMiddleware(limitStore, "preferences", limitRules)
+	interactionsLimit := middleware.RateLimitMiddleware(limitStore, "interactions", limitRules)
+
+	r.GET("/health", checker.Liveness)
+	r.GET("/livez", checker.Liveness)
+	r.GET("/readyz", checker.Readiness)
+
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	api := r.Group("/api")
-	log.Println("Registering /api routes...")
+	api.Use(middleware.CompressionMiddleware())
 
 	auth := api.Group("/")
-	auth.Use(middleware.AuthMiddleware())
+	auth.Use(middleware.AuthMiddleware(cfg.JWTSecret))
+	{
+		auth.GET("/feed", feedLimit, feedController.GetFeed)
+		auth.GET("/feed/recommended", feedLimit, feedController.GetRecommendedPosts)
+		auth.GET("/feed/recommended/communities", feedLimit, feedController.GetRecommendedCommunities)
+		auth.GET("/feed/home", feedLimit, feedController.GetHomeFeed)
+		auth.GET("/feed/trending", feedLimit, trendingController.GetTrendingPosts)
+		auth.GET("/feed/trending/tags", feedLimit, trendingController.GetTrendingTags)
+		auth.GET("/communities/:id/feed", feedLimit, feedController.GetCommunityFeed)
+		auth.GET("/feed/preferences", preferencesLimit, feedController.GetUserPreferences)
+		auth.PUT("/feed/preferences", preferencesLimit, feedController.UpdateUserPreferences)
+		auth.POST("/feed/interactions", interactionsLimit, feedController.RecordInteractions)
+		auth.GET("/feed/tokens", preferencesLimit, feedTokenController.GetFeedTokens)
+		auth.POST("/feed/tokens", preferencesLimit, feedTokenController.CreateFeedToken)
+		auth.DELETE("/feed/tokens/:id", preferencesLimit, feedTokenController.RevokeFeedToken)
+		auth.GET("/graphql", feedLimit, graphqlHandler.Serve)
+		auth.POST("/graphql", feedLimit, graphqlHandler.Serve)
+	}
+
+	// Feed exports for readers, which authenticate with feed tokens instead of bearer tokens
+	exports := api.Group("/")
+	exports.Use(middleware.FeedTokenMiddleware(tokenStore))
 	{
-		auth.GET("/feed", func(c *gin.Context) {
-			log.Println("GET /api/feed route hit")
-			feedController.GetFeed(c)
-		})
-
-		auth.GET("/feed/recommended", func(c *gin.Context) {
-			log.Println("GET /api/feed/recommended route hit")
-			feedController.GetRecommendedPosts(c)
-		})
-
-		auth.GET("/feed/preferences", func(c *gin.Context) {
-			log.Println("GET /api/feed/preferences route hit")
-			feedController.GetUserPreferences(c)
-		})
-
-		auth.PUT("/feed/preferences", func(c *gin.Context) {
-			log.Println("PUT /api/feed/preferences route hit")
-			feedController.UpdateUserPreferences(c)
-		})
+		exports.GET("/feed.rss", feedLimit, feedController.GetRSSFeed)
+		exports.GET("/feed.atom", feedLimit, feedController.GetAtomFeed)
+		exports.GET("/feed.json", feedLimit, feedController.GetJSONFeed)
 	}
 
-	log.Println("Routes registered successfully.")
+	// Service-to-service endpoints, called with tokens carrying the service role
+	internal := api.Group("/internal")
+	internal.Use(middleware.AuthMiddleware(cfg.JWTSecret), middleware.RequireRole("service"))
+	{
+		internal.POST("/post-events", trendingController.IngestPostEvents)
+		internal.PUT("/communities/:id/pins/:postId", feedController.PinPost)
+		internal.DELETE("/communities/:id/pins/:postId", feedController.UnpinPost)
+	}
+
+	admin := api.Group("/admin")
+	admin.Use(middleware.AuthMiddleware(cfg.JWTSecret), middleware.RequireRole("admin"))
+	{
+		admin.GET("/settings", settingsController.GetSettings)
+		admin.PATCH("/settings", settingsController.UpdateSettings)
+		admin.GET("/settings/history", settingsController.GetSettingsHistory)
+		admin.GET("/experiments", experimentController.GetExperiments)
+	}
+
+	slog.Debug("Routes registered successfully.")
+}
+
+// NewRateLimitStore selects the bucket store; "mongo" shares limits across replicas
+func NewRateLimitStore(backend string, db *mongo.Database) ratelimit.Store {
+	switch backend {
+	case "mongo":
+		store, err := ratelimit.NewMongoStore(db.Collection(database.RateLimitCollection))
+		if err != nil {
+			slog.Error("Failed to initialise MongoDB rate limit store", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("Using MongoDB rate limit store")
+		return store
+	case "memory", "":
+		slog.Info("Using in-memory rate limit store")
+		return ratelimit.NewMemoryStore()
+	default:
+		slog.Error("Unknown rate limit backend", "backend", backend)
+		os.Exit(1)
+		return nil
+	}
 }
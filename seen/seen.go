@@ -0,0 +1,149 @@
+// Package seen tracks how often each user has been shown each post, so feeds
+// can stop repeating posts a user has already scrolled past
+package seen
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/batch"
+	"github.com/CircleConnectApp/feed-service/metrics"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// bucketSize is the resolution of the impression counters
+	bucketSize = 24 * time.Hour
+	// Retention covers the longest cooldown
+	Retention = 30 * 24 * time.Hour
+	// MaxImpressionsPerDay bounds the impressions counted for a user in a
+	// day, and so the size of a bucket; later impressions are not counted
+	MaxImpressionsPerDay = 2000
+	// writeTimeout bounds each background write
+	writeTimeout = 5 * time.Second
+)
+
+// bucket is one user's impression counts for one day, keyed by post ID
+type bucket struct {
+	Posts map[string]int `bson:"posts"`
+}
+
+// Store keeps per-user impression counts in daily buckets, one document per
+// user and day, which expire after Retention
+type Store struct {
+	collection *mongo.Collection
+}
+
+// NewStore creates the store's indexes
+func NewStore(ctx context.Context, collection *mongo.Collection) (*Store, error) {
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "day", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{collection: collection}, nil
+}
+
+// Record counts one impression of each post for a user, unless the user's
+// bucket for the day already holds MaxImpressionsPerDay impressions
+func (s *Store) Record(ctx context.Context, userID int, postIDs []string, at time.Time) error {
+	if len(postIDs) == 0 {
+		return nil
+	}
+
+	day := at.UTC().Truncate(bucketSize)
+	inc := bson.M{"impressions": len(postIDs)}
+	for _, id := range postIDs {
+		inc["posts."+id] = 1
+	}
+	_, err := s.collection.UpdateOne(ctx,
+		bson.M{"user_id": userID, "day": day, "impressions": bson.M{"$not": bson.M{"$gte": MaxImpressionsPerDay}}},
+		bson.M{"$inc": inc, "$set": bson.M{"expires_at": day.Add(Retention)}},
+		options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// The bucket is full, so the upsert tried to start another
+		return nil
+	}
+	return err
+}
+
+// Counts returns how many times each post was shown to a user since the
+// given time, at the resolution of a day
+func (s *Store) Counts(ctx context.Context, userID int, since time.Time) (map[string]int, error) {
+	cursor, err := s.collection.Find(ctx,
+		bson.M{"user_id": userID, "day": bson.M{"$gte": since.UTC().Truncate(bucketSize)}},
+		options.Find().SetProjection(bson.M{"posts": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	var buckets []bucket
+	if err := cursor.All(ctx, &buckets); err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, b := range buckets {
+		for id, n := range b.Posts {
+			counts[id] += n
+		}
+	}
+	return counts, nil
+}
+
+type impressions struct {
+	userID  int
+	postIDs []string
+	at      time.Time
+}
+
+// Recorder writes seen posts to the store in the background, so serving a
+// feed never waits on the counts. Writes are dropped (and counted) if the
+// buffer is full; a lost write only means a post may be shown once more.
+type Recorder struct {
+	store   *Store
+	batches *batch.Recorder[impressions]
+}
+
+// NewRecorder starts a recorder buffering up to bufferSize writes
+func NewRecorder(store *Store, bufferSize int) *Recorder {
+	r := &Recorder{store: store}
+	r.batches = batch.NewRecorder(bufferSize, metrics.SeenPostsDropped, r.write)
+	return r
+}
+
+// Record queues one impression of each post for a user
+func (r *Recorder) Record(userID int, postIDs []string, at time.Time) {
+	if len(postIDs) == 0 {
+		return
+	}
+	r.batches.Record(impressions{userID: userID, postIDs: postIDs, at: at})
+}
+
+// Close stops accepting writes and waits for the buffer to be written or
+// for ctx to end
+func (r *Recorder) Close(ctx context.Context) error {
+	return r.batches.Close(ctx)
+}
+
+func (r *Recorder) write(records []impressions) {
+	for _, rec := range records {
+		ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+		err := r.store.Record(ctx, rec.userID, rec.postIDs, rec.at)
+		cancel()
+		if err != nil {
+			metrics.SeenPostsDropped.Inc()
+			slog.Warn("Failed to record seen posts", "user_id", rec.userID, "error", err)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package seen
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// testStore returns a store on the mocked database
+func testStore(mt *mtest.T) *Store {
+	mt.AddMockResponses(mtest.CreateSuccessResponse())
+	store, err := NewStore(context.Background(), mt.Coll)
+	if err != nil {
+		mt.Fatal(err)
+	}
+	return store
+}
+
+// updates returns the update commands sent to the mocked database
+func updates(mt *mtest.T) []bson.Raw {
+	var out []bson.Raw
+	for _, event := range mt.GetAllStartedEvents() {
+		if event.CommandName == "update" {
+			out = append(out, event.Command)
+		}
+	}
+	return out
+}
+
+// incremented returns the posts an update command counts an impression of
+func incremented(mt *mtest.T, command bson.Raw) []string {
+	elems, err := command.Lookup("updates", "0", "u", "$inc").Document().Elements()
+	if err != nil {
+		mt.Fatal(err)
+	}
+	var posts []string
+	for _, elem := range elems {
+		if key := elem.Key(); key != "impressions" {
+			posts = append(posts, key[len("posts."):])
+		}
+	}
+	sort.Strings(posts)
+	return posts
+}
+
+func TestStoreRecord(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	tests := []struct {
+		name      string
+		postIDs   []string
+		response  bson.D
+		wantWrite bool
+		wantErr   bool
+	}{
+		{name: "counts the posts", postIDs: []string{"b", "a"}, response: mtest.CreateSuccessResponse(), wantWrite: true},
+		{name: "nothing to count", wantWrite: false},
+		{name: "full bucket", postIDs: []string{"a"}, response: mtest.CreateWriteErrorsResponse(mtest.WriteError{Code: 11000, Message: "duplicate key"}), wantWrite: true},
+		{name: "write failure", postIDs: []string{"a"}, response: mtest.CreateWriteErrorsResponse(mtest.WriteError{Code: 2, Message: "bad"}), wantWrite: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			store := testStore(mt)
+			if tt.response != nil {
+				mt.AddMockResponses(tt.response)
+			}
+			mt.ClearEvents()
+
+			at := time.Date(2024, 5, 1, 15, 30, 0, 0, time.UTC)
+			err := store.Record(context.Background(), 7, tt.postIDs, at)
+			if (err != nil) != tt.wantErr {
+				mt.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+
+			commands := updates(mt)
+			if len(commands) == 0 {
+				if tt.wantWrite {
+					mt.Fatal("no update, want one")
+				}
+				return
+			}
+			if !tt.wantWrite {
+				mt.Fatalf("%d updates, want none", len(commands))
+			}
+			update := commands[0].Lookup("updates", "0")
+			if limit := update.Document().Lookup("q", "impressions", "$not", "$gte").Int32(); limit != MaxImpressionsPerDay {
+				mt.Errorf("cap = %d, want %d", limit, MaxImpressionsPerDay)
+			}
+			if day := update.Document().Lookup("q", "day").Time().UTC(); !day.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) {
+				mt.Errorf("day = %v, want the start of the day", day)
+			}
+			if n := update.Document().Lookup("u", "$inc", "impressions").Int32(); int(n) != len(tt.postIDs) {
+				mt.Errorf("impressions = %d, want %d", n, len(tt.postIDs))
+			}
+			want := append([]string(nil), tt.postIDs...)
+			sort.Strings(want)
+			if got := incremented(mt, commands[0]); !reflect.DeepEqual(got, want) {
+				mt.Errorf("posts = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestStoreCounts(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("sums the days", func(mt *mtest.T) {
+		store := testStore(mt)
+		ns := mt.Coll.Database().Name() + "." + mt.Coll.Name()
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+			bson.D{{Key: "posts", Value: bson.D{{Key: "a", Value: 1}, {Key: "b", Value: 2}}}},
+			bson.D{{Key: "posts", Value: bson.D{{Key: "a", Value: 2}}}},
+		))
+
+		counts, err := store.Counts(context.Background(), 7, time.Now().AddDate(0, 0, -6))
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if want := map[string]int{"a": 3, "b": 2}; !reflect.DeepEqual(counts, want) {
+			mt.Errorf("counts = %v, want %v", counts, want)
+		}
+	})
+}
+
+func TestRecorder(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	tests := []struct {
+		name        string
+		bufferSize  int
+		records     [][]string
+		closeFirst  bool
+		wantWritten [][]string
+	}{
+		{name: "writes each record", bufferSize: 4, records: [][]string{{"a"}, {"b", "c"}}, wantWritten: [][]string{{"a"}, {"b", "c"}}},
+		{name: "skips empty records", bufferSize: 4, records: [][]string{{}, {"a"}}, wantWritten: [][]string{{"a"}}},
+		{name: "drops records once closed", bufferSize: 4, records: [][]string{{"a"}}, closeFirst: true},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			store := testStore(mt)
+			for range tt.wantWritten {
+				mt.AddMockResponses(mtest.CreateSuccessResponse())
+			}
+			mt.ClearEvents()
+
+			recorder := NewRecorder(store, tt.bufferSize)
+			if tt.closeFirst {
+				if err := recorder.Close(context.Background()); err != nil {
+					mt.Fatal(err)
+				}
+			}
+			for _, postIDs := range tt.records {
+				recorder.Record(7, postIDs, time.Now())
+			}
+			if err := recorder.Close(context.Background()); err != nil {
+				mt.Fatal(err)
+			}
+
+			var written [][]string
+			for _, command := range updates(mt) {
+				written = append(written, incremented(mt, command))
+			}
+			if !reflect.DeepEqual(written, tt.wantWritten) {
+				mt.Errorf("written = %v, want %v", written, tt.wantWritten)
+			}
+		})
+	}
+}
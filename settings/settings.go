@@ -0,0 +1,440 @@
+package settings
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/ratelimit"
+)
+
+// Settings are the runtime-tunable parts of the service. Unlike config.Config
+// they can change while the service is running.
+type Settings struct {
+	Ranking     RankingWeights       `json:"ranking"`
+	Limits      PageLimits           `json:"limits"`
+	Home        HomeFeed             `json:"home"`
+	Candidates  CandidateSources     `json:"candidates"`
+	SeenPosts   SeenPosts            `json:"seen_posts"`
+	Communities CommunitySuggestions `json:"community_suggestions"`
+	Diversity   DiversityRules       `json:"diversity"`
+	GraphQL     GraphQLLimits        `json:"graphql"`
+	RateLimits  map[string]RateLimit `json:"rate_limits"` // keyed by route group
+	Features    map[string]bool      `json:"features"`
+	Experiments []Experiment         `json:"experiments"`
+}
+
+// RankingWeights tune ranking.Score
+type RankingWeights struct {
+	RecencyWeight           float64 `json:"recency_weight"`
+	PopularityWeight        float64 `json:"popularity_weight"`
+	PopularityScale         float64 `json:"popularity_scale"` // like count that contributes a popularity factor of 1
+	PreferredTagBoost       float64 `json:"preferred_tag_boost"`
+	PreferredCommunityBoost float64 `json:"preferred_community_boost"`
+	DemographicBoost        float64 `json:"demographic_boost"`
+}
+
+// Experiment splits users between ranker variants on one or more feeds
+type Experiment struct {
+	Name     string    `json:"name"`
+	Enabled  bool      `json:"enabled"`
+	Feeds    []string  `json:"feeds"` // "personal", "recommended"
+	Variants []Variant `json:"variants"`
+}
+
+// Variant is one arm of an experiment. Users are split between variants in
+// proportion to Weight; Ranking overrides individual ranking weights by their
+// JSON name, leaving the rest at the current settings.
+type Variant struct {
+	Name    string             `json:"name"`
+	Weight  int                `json:"weight"`
+	Ranking map[string]float64 `json:"ranking,omitempty"`
+}
+
+// PageLimits bound the page size clients may request
+type PageLimits struct {
+	DefaultPageSize int `json:"default_page_size"`
+	MaxPageSize     int `json:"max_page_size"`
+}
+
+// GraphQLLimits bound the cost of a single GraphQL query. Each field costs 1
+// and fields returning pages multiply the cost of their selections by the
+// page size requested.
+type GraphQLLimits struct {
+	MaxComplexity int `json:"max_complexity"`
+	MaxDepth      int `json:"max_depth"`
+}
+
+// HomeFeed controls how the home feed blends joined-community posts with
+// recommendations and trending posts
+type HomeFeed struct {
+	RecommendedRatio         float64 `json:"recommended_ratio"`          // share of each page given to recommendations
+	TrendingRatio            float64 `json:"trending_ratio"`             // share of each page given to trending posts
+	FirstRecommendedPosition int     `json:"first_recommended_position"` // 0-based position of the earliest recommended or trending post
+	MinRecommendedGap        int     `json:"min_recommended_gap"`        // joined posts required between recommended or trending posts
+}
+
+// CandidateSources size the sources recommendations are drawn from, as a
+// multiple of the page size. Zero disables a source.
+type CandidateSources struct {
+	Popular                float64 `json:"popular"`
+	PreferredCommunities   float64 `json:"preferred_communities"`
+	PreferredTags          float64 `json:"preferred_tags"`
+	EngagedAuthors         float64 `json:"engaged_authors"`
+	Fresh                  float64 `json:"fresh"`
+	EngagementLookbackDays int     `json:"engagement_lookback_days"` // how far back engaged authors are found
+	MaxEngagedAuthors      int     `json:"max_engaged_authors"`
+}
+
+// SeenPosts controls how recommendations suppress posts the user has already
+// been shown. Impressions older than the cooldown no longer count, so
+// suppressed posts can return once it passes.
+type SeenPosts struct {
+	MaxImpressions int `json:"max_impressions"` // impressions before a post is suppressed; 0 disables
+	CooldownDays   int `json:"cooldown_days"`
+}
+
+// CommunitySuggestions weight the signals communities are suggested from.
+// Each signal is scaled to the strongest suggestion before weighting, and
+// zero disables it.
+type CommunitySuggestions struct {
+	TagOverlap      float64 `json:"tag_overlap"`       // popular posts with the user's preferred tags
+	CoMembership    float64 `json:"co_membership"`     // joined by users sharing the user's communities
+	Engagement      float64 `json:"engagement"`        // posts the user engaged with
+	LookbackDays    int     `json:"lookback_days"`     // how far back engagement counts
+	MaxSimilarUsers int     `json:"max_similar_users"` // most recently active users compared
+}
+
+// DiversityRules bound how much of a page one author or community may take
+// and how similar posts may be. Zero disables a rule.
+type DiversityRules struct {
+	MaxConsecutiveAuthor    int     `json:"max_consecutive_author"`
+	MaxConsecutiveCommunity int     `json:"max_consecutive_community"`
+	MaxPerAuthor            int     `json:"max_per_author"`           // per page
+	MaxPerCommunity         int     `json:"max_per_community"`        // per page
+	NearDuplicateThreshold  float64 `json:"near_duplicate_threshold"` // Jaccard similarity of title and content shingles
+	ShingleSize             int     `json:"shingle_size"`             // words per shingle
+}
+
+// RateLimit holds the per-user and per-IP rules for a route group, written
+// as "<requests>/<period>" or "off"
+type RateLimit struct {
+	User string `json:"user"`
+	IP   string `json:"ip"`
+}
+
+// Snapshot is an immutable, validated version of the settings. Readers obtain
+// the current snapshot from a Store and use it for the rest of the request.
+type Snapshot struct {
+	Settings
+	Version   int64     `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UpdatedBy string    `json:"updated_by,omitempty"`
+
+	rules map[string][2]ratelimit.Rule
+}
+
+// ValidationError reports settings that are malformed or out of range
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid settings: " + e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Default returns the built-in settings
+func Default() Settings {
+	return Settings{
+		Ranking: RankingWeights{
+			RecencyWeight:           0.7,
+			PopularityWeight:        0.3,
+			PopularityScale:         100,
+			PreferredTagBoost:       0.2,
+			PreferredCommunityBoost: 0.3,
+			DemographicBoost:        0.1,
+		},
+		Limits: PageLimits{
+			DefaultPageSize: 20,
+			MaxPageSize:     100,
+		},
+		Home: HomeFeed{
+			RecommendedRatio:         0.25,
+			TrendingRatio:            0.1,
+			FirstRecommendedPosition: 2,
+			MinRecommendedGap:        2,
+		},
+		Candidates: CandidateSources{
+			Popular:                1,
+			PreferredCommunities:   0.5,
+			PreferredTags:          0.5,
+			EngagedAuthors:         0.5,
+			Fresh:                  0.25,
+			EngagementLookbackDays: 30,
+			MaxEngagedAuthors:      20,
+		},
+		SeenPosts: SeenPosts{
+			MaxImpressions: 3,
+			CooldownDays:   7,
+		},
+		Communities: CommunitySuggestions{
+			TagOverlap:      1,
+			CoMembership:    1,
+			Engagement:      1,
+			LookbackDays:    30,
+			MaxSimilarUsers: 500,
+		},
+		Diversity: DiversityRules{
+			MaxConsecutiveAuthor:    2,
+			MaxConsecutiveCommunity: 3,
+			MaxPerAuthor:            3,
+			NearDuplicateThreshold:  0.8,
+			ShingleSize:             3,
+		},
+		GraphQL: GraphQLLimits{
+			MaxComplexity: 2000,
+			MaxDepth:      10,
+		},
+		RateLimits: map[string]RateLimit{
+			"interactions": {User: "600/m", IP: "off"},
+		},
+		Features: map[string]bool{
+			"demographic_boost":          true,
+			"exclude_joined_communities": true,
+		},
+		Experiments: []Experiment{},
+	}
+}
+
+// Enabled reports whether the named feature flag is on; unknown flags are off
+func (s *Snapshot) Enabled(feature string) bool {
+	return s.Features[feature]
+}
+
+// RateLimitRules returns the parsed user and IP rules for a route group.
+// Groups without settings are not limited.
+func (s *Snapshot) RateLimitRules(group string) (user, ip ratelimit.Rule) {
+	rules := s.rules[group]
+	return rules[0], rules[1]
+}
+
+// newSnapshot validates settings and pre-parses rate limit rules
+func newSnapshot(s Settings, version int64, updatedAt time.Time, updatedBy string) (*Snapshot, error) {
+	if err := s.Validate(); err != nil {
+		return nil, &ValidationError{Err: err}
+	}
+
+	rules := make(map[string][2]ratelimit.Rule, len(s.RateLimits))
+	for group, limit := range s.RateLimits {
+		user, _ := ratelimit.ParseRule(limit.User)
+		ip, _ := ratelimit.ParseRule(limit.IP)
+		rules[group] = [2]ratelimit.Rule{user, ip}
+	}
+
+	return &Snapshot{
+		Settings:  s,
+		Version:   version,
+		UpdatedAt: updatedAt,
+		UpdatedBy: updatedBy,
+		rules:     rules,
+	}, nil
+}
+
+// Validate reports every invalid setting
+func (s Settings) Validate() error {
+	var errs []error
+
+	for name, weight := range map[string]float64{
+		"ranking.recency_weight":            s.Ranking.RecencyWeight,
+		"ranking.popularity_weight":         s.Ranking.PopularityWeight,
+		"ranking.preferred_tag_boost":       s.Ranking.PreferredTagBoost,
+		"ranking.preferred_community_boost": s.Ranking.PreferredCommunityBoost,
+		"ranking.demographic_boost":         s.Ranking.DemographicBoost,
+	} {
+		if weight < 0 {
+			errs = append(errs, fmt.Errorf("%s: must not be negative", name))
+		}
+	}
+	if s.Ranking.PopularityScale <= 0 {
+		errs = append(errs, errors.New("ranking.popularity_scale: must be positive"))
+	}
+
+	if s.Limits.MaxPageSize < 1 {
+		errs = append(errs, errors.New("limits.max_page_size: must be at least 1"))
+	}
+	if s.Limits.DefaultPageSize < 1 || s.Limits.DefaultPageSize > s.Limits.MaxPageSize {
+		errs = append(errs, errors.New("limits.default_page_size: must be between 1 and limits.max_page_size"))
+	}
+
+	if s.Home.RecommendedRatio < 0 || s.Home.RecommendedRatio > 1 {
+		errs = append(errs, errors.New("home.recommended_ratio: must be between 0 and 1"))
+	}
+	if s.Home.TrendingRatio < 0 || s.Home.RecommendedRatio+s.Home.TrendingRatio > 1 {
+		errs = append(errs, errors.New("home.trending_ratio: must be between 0 and 1 - home.recommended_ratio"))
+	}
+	if s.Home.FirstRecommendedPosition < 0 {
+		errs = append(errs, errors.New("home.first_recommended_position: must not be negative"))
+	}
+	if s.Home.MinRecommendedGap < 0 {
+		errs = append(errs, errors.New("home.min_recommended_gap: must not be negative"))
+	}
+
+	for name, share := range map[string]float64{
+		"candidates.popular":               s.Candidates.Popular,
+		"candidates.preferred_communities": s.Candidates.PreferredCommunities,
+		"candidates.preferred_tags":        s.Candidates.PreferredTags,
+		"candidates.engaged_authors":       s.Candidates.EngagedAuthors,
+		"candidates.fresh":                 s.Candidates.Fresh,
+	} {
+		if share < 0 {
+			errs = append(errs, fmt.Errorf("%s: must not be negative", name))
+		}
+	}
+	if s.Candidates.Popular+s.Candidates.Fresh <= 0 {
+		errs = append(errs, errors.New("candidates: popular or fresh must be enabled so every user has candidates"))
+	}
+	if s.Candidates.EngagementLookbackDays < 1 {
+		errs = append(errs, errors.New("candidates.engagement_lookback_days: must be at least 1"))
+	}
+	if s.Candidates.MaxEngagedAuthors < 1 {
+		errs = append(errs, errors.New("candidates.max_engaged_authors: must be at least 1"))
+	}
+
+	if s.SeenPosts.MaxImpressions < 0 {
+		errs = append(errs, errors.New("seen_posts.max_impressions: must not be negative"))
+	}
+	if s.SeenPosts.CooldownDays < 1 || s.SeenPosts.CooldownDays > 30 {
+		errs = append(errs, errors.New("seen_posts.cooldown_days: must be between 1 and 30"))
+	}
+
+	for name, weight := range map[string]float64{
+		"community_suggestions.tag_overlap":   s.Communities.TagOverlap,
+		"community_suggestions.co_membership": s.Communities.CoMembership,
+		"community_suggestions.engagement":    s.Communities.Engagement,
+	} {
+		if weight < 0 {
+			errs = append(errs, fmt.Errorf("%s: must not be negative", name))
+		}
+	}
+	if s.Communities.TagOverlap+s.Communities.CoMembership+s.Communities.Engagement <= 0 {
+		errs = append(errs, errors.New("community_suggestions: at least one signal must be enabled"))
+	}
+	if s.Communities.LookbackDays < 1 {
+		errs = append(errs, errors.New("community_suggestions.lookback_days: must be at least 1"))
+	}
+	if s.Communities.MaxSimilarUsers < 1 {
+		errs = append(errs, errors.New("community_suggestions.max_similar_users: must be at least 1"))
+	}
+
+	for name, value := range map[string]int{
+		"diversity.max_consecutive_author":    s.Diversity.MaxConsecutiveAuthor,
+		"diversity.max_consecutive_community": s.Diversity.MaxConsecutiveCommunity,
+		"diversity.max_per_author":            s.Diversity.MaxPerAuthor,
+		"diversity.max_per_community":         s.Diversity.MaxPerCommunity,
+	} {
+		if value < 0 {
+			errs = append(errs, fmt.Errorf("%s: must not be negative", name))
+		}
+	}
+	if s.Diversity.NearDuplicateThreshold < 0 || s.Diversity.NearDuplicateThreshold > 1 {
+		errs = append(errs, errors.New("diversity.near_duplicate_threshold: must be between 0 and 1"))
+	}
+	if s.Diversity.ShingleSize < 1 {
+		errs = append(errs, errors.New("diversity.shingle_size: must be at least 1"))
+	}
+
+	if s.GraphQL.MaxComplexity < 1 {
+		errs = append(errs, errors.New("graphql.max_complexity: must be at least 1"))
+	}
+	if s.GraphQL.MaxDepth < 1 {
+		errs = append(errs, errors.New("graphql.max_depth: must be at least 1"))
+	}
+
+	for group, limit := range s.RateLimits {
+		if _, err := ratelimit.ParseRule(limit.User); err != nil {
+			errs = append(errs, fmt.Errorf("rate_limits.%s.user: %w", group, err))
+		}
+		if _, err := ratelimit.ParseRule(limit.IP); err != nil {
+			errs = append(errs, fmt.Errorf("rate_limits.%s.ip: %w", group, err))
+		}
+	}
+
+	names := make(map[string]bool, len(s.Experiments))
+	for i, exp := range s.Experiments {
+		if err := exp.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("experiments[%d]: %w", i, err))
+		}
+		if names[exp.Name] {
+			errs = append(errs, fmt.Errorf("experiments[%d]: duplicate name %q", i, exp.Name))
+		}
+		names[exp.Name] = true
+	}
+
+	return errors.Join(errs...)
+}
+
+func (e Experiment) validate() error {
+	if e.Name == "" {
+		return errors.New("name must not be empty")
+	}
+	if len(e.Feeds) == 0 {
+		return errors.New("feeds must list at least one feed")
+	}
+	for _, feed := range e.Feeds {
+		if feed != "personal" && feed != "recommended" {
+			return fmt.Errorf("unknown feed %q", feed)
+		}
+	}
+	if len(e.Variants) < 2 {
+		return errors.New("at least two variants are required")
+	}
+
+	total := 0
+	variantNames := make(map[string]bool, len(e.Variants))
+	for _, v := range e.Variants {
+		if v.Name == "" || variantNames[v.Name] {
+			return fmt.Errorf("variant names must be unique and non-empty")
+		}
+		variantNames[v.Name] = true
+		if v.Weight < 0 {
+			return fmt.Errorf("variant %q: weight must not be negative", v.Name)
+		}
+		total += v.Weight
+		if _, err := (RankingWeights{}).WithOverrides(v.Ranking); err != nil {
+			return fmt.Errorf("variant %q: %w", v.Name, err)
+		}
+	}
+	if total == 0 {
+		return errors.New("variant weights must not all be zero")
+	}
+	return nil
+}
+
+// WithOverrides returns a copy of w with the named weights replaced
+func (w RankingWeights) WithOverrides(overrides map[string]float64) (RankingWeights, error) {
+	if len(overrides) == 0 {
+		return w, nil
+	}
+
+	data, err := json.Marshal(overrides)
+	if err != nil {
+		return w, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&w); err != nil {
+		return w, fmt.Errorf("invalid ranking override: %w", err)
+	}
+	for name, value := range overrides {
+		if value < 0 || (name == "popularity_scale" && value == 0) {
+			return w, fmt.Errorf("ranking override %s is out of range", name)
+		}
+	}
+	return w, nil
+}
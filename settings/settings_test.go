@@ -0,0 +1,188 @@
+package settings
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(s *Settings)
+		want   []string // substrings of the error; none means valid
+	}{
+		{name: "defaults", modify: func(s *Settings) {}},
+		{
+			name:   "negative ranking weight",
+			modify: func(s *Settings) { s.Ranking.RecencyWeight = -0.1 },
+			want:   []string{"ranking.recency_weight: must not be negative"},
+		},
+		{
+			name:   "zero popularity scale",
+			modify: func(s *Settings) { s.Ranking.PopularityScale = 0 },
+			want:   []string{"ranking.popularity_scale"},
+		},
+		{
+			name:   "default page size above maximum",
+			modify: func(s *Settings) { s.Limits.DefaultPageSize = s.Limits.MaxPageSize + 1 },
+			want:   []string{"limits.default_page_size"},
+		},
+		{
+			name:   "recommended ratio above 1",
+			modify: func(s *Settings) { s.Home.RecommendedRatio = 1.5 },
+			want:   []string{"home.recommended_ratio"},
+		},
+		{
+			name: "home shares above 1",
+			modify: func(s *Settings) {
+				s.Home.RecommendedRatio = 0.8
+				s.Home.TrendingRatio = 0.3
+			},
+			want: []string{"home.trending_ratio"},
+		},
+		{
+			name: "no candidate source for every user",
+			modify: func(s *Settings) {
+				s.Candidates.Popular = 0
+				s.Candidates.Fresh = 0
+			},
+			want: []string{"candidates: popular or fresh"},
+		},
+		{
+			name:   "seen cooldown beyond retention",
+			modify: func(s *Settings) { s.SeenPosts.CooldownDays = 31 },
+			want:   []string{"seen_posts.cooldown_days"},
+		},
+		{
+			name: "every community signal disabled",
+			modify: func(s *Settings) {
+				s.Communities = CommunitySuggestions{LookbackDays: 1, MaxSimilarUsers: 1}
+			},
+			want: []string{"community_suggestions: at least one signal"},
+		},
+		{
+			name:   "near duplicate threshold above 1",
+			modify: func(s *Settings) { s.Diversity.NearDuplicateThreshold = 1.1 },
+			want:   []string{"diversity.near_duplicate_threshold"},
+		},
+		{
+			name:   "malformed rate limit",
+			modify: func(s *Settings) { s.RateLimits["feed"] = RateLimit{User: "lots", IP: "off"} },
+			want:   []string{"rate_limits.feed.user"},
+		},
+		{
+			name: "every error is reported",
+			modify: func(s *Settings) {
+				s.Limits.MaxPageSize = 0
+				s.GraphQL.MaxDepth = 0
+			},
+			want: []string{"limits.max_page_size", "graphql.max_depth"},
+		},
+		{
+			name: "experiment with one variant",
+			modify: func(s *Settings) {
+				s.Experiments = []Experiment{{Name: "e", Feeds: []string{"personal"}, Variants: []Variant{{Name: "a", Weight: 1}}}}
+			},
+			want: []string{"experiments[0]: at least two variants"},
+		},
+		{
+			name: "duplicate experiment names",
+			modify: func(s *Settings) {
+				exp := Experiment{Name: "e", Feeds: []string{"personal"}, Variants: []Variant{{Name: "a", Weight: 1}, {Name: "b", Weight: 1}}}
+				s.Experiments = []Experiment{exp, exp}
+			},
+			want: []string{`experiments[1]: duplicate name "e"`},
+		},
+		{
+			name: "experiment on an unknown feed",
+			modify: func(s *Settings) {
+				s.Experiments = []Experiment{{Name: "e", Feeds: []string{"home"}, Variants: []Variant{{Name: "a", Weight: 1}, {Name: "b", Weight: 1}}}}
+			},
+			want: []string{`unknown feed "home"`},
+		},
+		{
+			name: "variant overriding an unknown weight",
+			modify: func(s *Settings) {
+				s.Experiments = []Experiment{{Name: "e", Feeds: []string{"personal"}, Variants: []Variant{
+					{Name: "a", Weight: 1},
+					{Name: "b", Weight: 1, Ranking: map[string]float64{"recency": 1}},
+				}}}
+			},
+			want: []string{"invalid ranking override"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Default()
+			tt.modify(&s)
+			err := s.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() = nil, want errors containing %q", tt.want)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() = %q, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestWithOverrides(t *testing.T) {
+	base := Default().Ranking
+	tests := []struct {
+		name      string
+		overrides map[string]float64
+		want      func(w RankingWeights) bool
+		wantErr   bool
+	}{
+		{name: "none", want: func(w RankingWeights) bool { return w == base }},
+		{
+			name:      "replaces named weights only",
+			overrides: map[string]float64{"recency_weight": 0.9},
+			want: func(w RankingWeights) bool {
+				return w.RecencyWeight == 0.9 && w.PopularityWeight == base.PopularityWeight
+			},
+		},
+		{name: "unknown weight", overrides: map[string]float64{"recency": 0.9}, wantErr: true},
+		{name: "negative weight", overrides: map[string]float64{"popularity_weight": -1}, wantErr: true},
+		{name: "zero popularity scale", overrides: map[string]float64{"popularity_scale": 0}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := base.WithOverrides(tt.overrides)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WithOverrides() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !tt.want(got) {
+				t.Errorf("WithOverrides() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestSnapshotRateLimitRules(t *testing.T) {
+	s := Default()
+	s.RateLimits["feed"] = RateLimit{User: "60/m", IP: "off"}
+	snap, err := newSnapshot(s, 1, time.Now(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, ip := snap.RateLimitRules("feed")
+	if user.Burst != 60 || user.Rate != 1 || ip.Enabled() {
+		t.Errorf("feed rules = %+v, %+v, want 60/m and off", user, ip)
+	}
+	if user, ip := snap.RateLimitRules("unknown"); user.Enabled() || ip.Enabled() {
+		t.Error("groups without settings must not be limited")
+	}
+}
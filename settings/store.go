@@ -0,0 +1,360 @@
+package settings
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gopkg.in/yaml.v3"
+)
+
+const overridesDocumentID = "runtime"
+
+// ErrConflict is returned by Update when another writer changed the
+// overrides first; the store has reloaded and the caller may retry
+var ErrConflict = errors.New("settings were modified concurrently")
+
+// Change is one audited update to the overrides
+type Change struct {
+	Version int64           `json:"version"`
+	Actor   string          `json:"actor"`
+	At      time.Time       `json:"at"`
+	Patch   json.RawMessage `json:"patch"`
+}
+
+type overridesDocument struct {
+	ID        string    `bson:"_id"`
+	Overrides bson.Raw  `bson:"overrides"`
+	Version   int64     `bson:"version"`
+	UpdatedAt time.Time `bson:"updated_at"`
+	UpdatedBy string    `bson:"updated_by"`
+}
+
+type historyDocument struct {
+	Version int64     `bson:"version"`
+	Actor   string    `bson:"actor"`
+	At      time.Time `bson:"at"`
+	Patch   bson.Raw  `bson:"patch"`
+}
+
+// Store layers the runtime settings from built-in defaults, an optional YAML
+// or JSON file on disk and overrides saved in MongoDB by the admin API, in
+// increasing precedence. The merged result is published as an immutable
+// Snapshot that is swapped atomically, so readers never lock.
+type Store struct {
+	defaults   Settings
+	filePath   string
+	collection *mongo.Collection
+	history    *mongo.Collection
+
+	current atomic.Pointer[Snapshot]
+
+	// mu serialises reloads and updates, and guards the fields below
+	mu          sync.Mutex
+	fileModTime time.Time
+	fileLayer   map[string]interface{}
+	overrides   map[string]interface{}
+	version     int64
+	updatedAt   time.Time
+	updatedBy   string
+}
+
+// NewStore loads the initial settings. filePath may be empty to skip the file layer.
+func NewStore(ctx context.Context, defaults Settings, filePath string, collection, history *mongo.Collection) (*Store, error) {
+	s := &Store{
+		defaults:   defaults,
+		filePath:   filePath,
+		collection: collection,
+		history:    history,
+	}
+
+	_, err := history.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "version", Value: -1}}})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Current returns the snapshot in effect
+func (s *Store) Current() *Snapshot {
+	return s.current.Load()
+}
+
+// Overrides returns a copy of the overrides saved through the admin API
+func (s *Store) Overrides() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return cloneMap(s.overrides)
+}
+
+// Watch reloads the settings every interval until ctx is done, picking up
+// edits to the file and overrides written by other replicas
+func (s *Store) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloadCtx, cancel := context.WithTimeout(ctx, interval)
+			if err := s.Reload(reloadCtx); err != nil {
+				slog.Warn("Failed to reload runtime settings, keeping previous version", "error", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// Reload re-reads the file (if it changed) and the stored overrides, and
+// publishes a new snapshot if the result is valid
+func (s *Store) Reload(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reload(ctx)
+}
+
+// reload does the work of Reload; the caller must hold mu
+func (s *Store) reload(ctx context.Context) error {
+	fileLayer, fileModTime, err := s.readFile()
+	if err != nil {
+		return err
+	}
+
+	var doc overridesDocument
+	overrides := map[string]interface{}{}
+	err = s.collection.FindOne(ctx, bson.M{"_id": overridesDocumentID}).Decode(&doc)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+	if err == nil {
+		if overrides, err = rawToMap(doc.Overrides); err != nil {
+			return err
+		}
+	}
+
+	previous := s.current.Load()
+	if previous != nil && fileModTime.Equal(s.fileModTime) && doc.Version == s.version {
+		return nil
+	}
+
+	snap, err := s.build(fileLayer, overrides, doc.Version, doc.UpdatedAt, doc.UpdatedBy)
+	if err != nil {
+		return err
+	}
+
+	s.fileLayer, s.fileModTime = fileLayer, fileModTime
+	s.overrides, s.version, s.updatedAt, s.updatedBy = overrides, doc.Version, doc.UpdatedAt, doc.UpdatedBy
+	s.current.Store(snap)
+
+	if previous != nil {
+		slog.Info("Runtime settings reloaded", "version", snap.Version)
+	}
+	return nil
+}
+
+// Update applies patch to the overrides as a JSON merge patch (RFC 7386;
+// null removes a key), validates the result, saves it and records the change
+// in the audit history
+func (s *Store) Update(ctx context.Context, patch map[string]interface{}, actor string) (*Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	overrides := mergePatch(cloneMap(s.overrides), patch)
+	now := time.Now().UTC()
+	version := s.version + 1
+
+	snap, err := s.build(s.fileLayer, overrides, version, now, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := bson.M{
+		"_id":        overridesDocumentID,
+		"overrides":  overrides,
+		"version":    version,
+		"updated_at": now,
+		"updated_by": actor,
+	}
+	if s.version == 0 {
+		_, err = s.collection.InsertOne(ctx, doc)
+		if mongo.IsDuplicateKeyError(err) {
+			err = ErrConflict
+		}
+	} else {
+		var res *mongo.UpdateResult
+		res, err = s.collection.ReplaceOne(ctx, bson.M{"_id": overridesDocumentID, "version": s.version}, doc)
+		if err == nil && res.MatchedCount == 0 {
+			err = ErrConflict
+		}
+	}
+	if err == ErrConflict {
+		if reloadErr := s.reload(ctx); reloadErr != nil {
+			slog.WarnContext(ctx, "Failed to reload runtime settings after conflict", "error", reloadErr)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.history.InsertOne(ctx, bson.M{
+		"version": version,
+		"actor":   actor,
+		"at":      now,
+		"patch":   patch,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to record settings change in audit history", "version", version, "error", err)
+	}
+
+	s.overrides, s.version, s.updatedAt, s.updatedBy = overrides, version, now, actor
+	s.current.Store(snap)
+	slog.InfoContext(ctx, "Runtime settings updated", "version", version, "actor", actor)
+
+	return snap, nil
+}
+
+// History returns the most recent changes, newest first
+func (s *Store) History(ctx context.Context, limit int) ([]Change, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := s.history.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	changes := []Change{}
+	for cursor.Next(ctx) {
+		var doc historyDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		patch, err := bson.MarshalExtJSON(doc.Patch, false, false)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, Change{Version: doc.Version, Actor: doc.Actor, At: doc.At, Patch: patch})
+	}
+	return changes, cursor.Err()
+}
+
+// build merges the layers over the defaults and validates the result
+func (s *Store) build(fileLayer, overrides map[string]interface{}, version int64, updatedAt time.Time, updatedBy string) (*Snapshot, error) {
+	base, err := toMap(s.defaults)
+	if err != nil {
+		return nil, err
+	}
+	merged := mergePatch(mergePatch(base, fileLayer), overrides)
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	// Unknown keys are rejected so that a misspelt setting is not saved as
+	// an override that silently does nothing
+	var settings Settings
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&settings); err != nil {
+		return nil, &ValidationError{Err: err}
+	}
+
+	if updatedAt.IsZero() {
+		updatedAt = time.Now().UTC()
+	}
+	return newSnapshot(settings, version, updatedAt, updatedBy)
+}
+
+// readFile returns the file layer, reusing the cached one if the file has not changed
+func (s *Store) readFile() (map[string]interface{}, time.Time, error) {
+	if s.filePath == "" {
+		return nil, time.Time{}, nil
+	}
+
+	info, err := os.Stat(s.filePath)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if s.fileLayer != nil && info.ModTime().Equal(s.fileModTime) {
+		return s.fileLayer, s.fileModTime, nil
+	}
+
+	data, err := os.ReadFile(s.filePath)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	// YAML is a superset of JSON, so this accepts both
+	layer := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &layer); err != nil {
+		return nil, time.Time{}, err
+	}
+	return layer, info.ModTime(), nil
+}
+
+// mergePatch applies patch to target following RFC 7386 and returns target
+func mergePatch(target, patch map[string]interface{}) map[string]interface{} {
+	if target == nil {
+		target = map[string]interface{}{}
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+		if patchMap, ok := value.(map[string]interface{}); ok {
+			targetMap, _ := target[key].(map[string]interface{})
+			target[key] = mergePatch(cloneMap(targetMap), patchMap)
+			continue
+		}
+		target[key] = value
+	}
+	return target
+}
+
+func cloneMap(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		if nested, ok := value.(map[string]interface{}); ok {
+			value = cloneMap(nested)
+		}
+		out[key] = value
+	}
+	return out
+}
+
+func toMap(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]interface{}{}
+	return m, json.Unmarshal(data, &m)
+}
+
+// rawToMap converts a stored BSON document to plain JSON-compatible values
+func rawToMap(raw bson.Raw) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	if len(raw) == 0 {
+		return m, nil
+	}
+	data, err := bson.MarshalExtJSON(raw, false, false)
+	if err != nil {
+		return nil, err
+	}
+	return m, json.Unmarshal(data, &m)
+}
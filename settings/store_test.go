@@ -0,0 +1,231 @@
+package settings
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		target map[string]interface{}
+		patch  map[string]interface{}
+		want   map[string]interface{}
+	}{
+		{
+			name:   "adds and replaces keys",
+			target: map[string]interface{}{"a": 1.0},
+			patch:  map[string]interface{}{"a": 2.0, "b": 3.0},
+			want:   map[string]interface{}{"a": 2.0, "b": 3.0},
+		},
+		{
+			name:   "null removes a key",
+			target: map[string]interface{}{"a": 1.0, "b": 2.0},
+			patch:  map[string]interface{}{"a": nil},
+			want:   map[string]interface{}{"b": 2.0},
+		},
+		{
+			name:   "merges nested objects",
+			target: map[string]interface{}{"ranking": map[string]interface{}{"recency_weight": 0.7, "popularity_weight": 0.3}},
+			patch:  map[string]interface{}{"ranking": map[string]interface{}{"recency_weight": 0.5}},
+			want:   map[string]interface{}{"ranking": map[string]interface{}{"recency_weight": 0.5, "popularity_weight": 0.3}},
+		},
+		{
+			name:   "replaces arrays whole",
+			target: map[string]interface{}{"feeds": []interface{}{"personal", "recommended"}},
+			patch:  map[string]interface{}{"feeds": []interface{}{"personal"}},
+			want:   map[string]interface{}{"feeds": []interface{}{"personal"}},
+		},
+		{
+			name:  "nil target",
+			patch: map[string]interface{}{"a": map[string]interface{}{"b": 1.0}},
+			want:  map[string]interface{}{"a": map[string]interface{}{"b": 1.0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergePatch(tt.target, tt.patch); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergePatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// newMockStore creates a store against a mocked collection with no stored
+// overrides. filePath may be empty.
+func newMockStore(mt *mtest.T, filePath string) *Store {
+	mt.AddMockResponses(
+		mtest.CreateSuccessResponse(),                                    // history index
+		mtest.CreateCursorResponse(0, "feed.settings", mtest.FirstBatch), // no overrides yet
+	)
+	store, err := NewStore(context.Background(), Default(), filePath, mt.DB.Collection("settings"), mt.DB.Collection("settings_history"))
+	if err != nil {
+		mt.Fatalf("NewStore() error = %v", err)
+	}
+	return store
+}
+
+func TestStoreUpdate(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	tests := []struct {
+		name      string
+		patch     map[string]interface{}
+		responses []bson.D
+		wantErr   bool
+		wantValid bool // the error is a ValidationError
+		check     func(t *testing.T, snap *Snapshot)
+	}{
+		{
+			name:      "saves a valid patch",
+			patch:     map[string]interface{}{"ranking": map[string]interface{}{"recency_weight": 0.5}},
+			responses: []bson.D{mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse()},
+			check: func(t *testing.T, snap *Snapshot) {
+				if snap.Version != 1 || snap.UpdatedBy != "admin" {
+					t.Errorf("snapshot version %d by %q, want 1 by admin", snap.Version, snap.UpdatedBy)
+				}
+				if snap.Ranking.RecencyWeight != 0.5 || snap.Ranking.PopularityWeight != Default().Ranking.PopularityWeight {
+					t.Errorf("ranking = %+v, want only recency_weight replaced", snap.Ranking)
+				}
+			},
+		},
+		{
+			name:      "rejects an unknown section",
+			patch:     map[string]interface{}{"rankng": map[string]interface{}{"recency_weight": 0.5}},
+			wantErr:   true,
+			wantValid: true,
+		},
+		{
+			name:      "rejects an unknown key in a section",
+			patch:     map[string]interface{}{"ranking": map[string]interface{}{"recency": 0.5}},
+			wantErr:   true,
+			wantValid: true,
+		},
+		{
+			name:      "rejects an out of range value",
+			patch:     map[string]interface{}{"limits": map[string]interface{}{"max_page_size": 0}},
+			wantErr:   true,
+			wantValid: true,
+		},
+		{
+			name:      "rejects a value of the wrong type",
+			patch:     map[string]interface{}{"limits": map[string]interface{}{"max_page_size": "lots"}},
+			wantErr:   true,
+			wantValid: true,
+		},
+		{
+			name:      "reports a failed write",
+			patch:     map[string]interface{}{"ranking": map[string]interface{}{"recency_weight": 0.5}},
+			responses: []bson.D{mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11600, Message: "interrupted"})},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			store := newMockStore(mt, "")
+			before := store.Current()
+			mt.ClearEvents()
+			mt.AddMockResponses(tt.responses...)
+
+			snap, err := store.Update(context.Background(), tt.patch, "admin")
+			if (err != nil) != tt.wantErr {
+				mt.Fatalf("Update() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var validationErr *ValidationError
+			if errors.As(err, &validationErr) != tt.wantValid {
+				mt.Errorf("Update() error = %v, want ValidationError %v", err, tt.wantValid)
+			}
+			if tt.wantErr {
+				if store.Current() != before {
+					mt.Error("a failed update must not replace the current snapshot")
+				}
+				if tt.wantValid && len(mt.GetAllStartedEvents()) > 0 {
+					mt.Error("an invalid patch must not be written")
+				}
+				return
+			}
+			if store.Current() != snap {
+				mt.Error("Update() must publish the new snapshot")
+			}
+			tt.check(t, snap)
+		})
+	}
+}
+
+func TestStoreUpdateConflict(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("reloads the winning version", func(mt *mtest.T) {
+		store := newMockStore(mt, "")
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
+		if _, err := store.Update(context.Background(), map[string]interface{}{"features": map[string]interface{}{"demographic_boost": false}}, "admin"); err != nil {
+			mt.Fatal(err)
+		}
+
+		// Another replica saved version 2 first, so the replace matches nothing
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+			mtest.CreateCursorResponse(0, "feed.settings", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: overridesDocumentID},
+				{Key: "overrides", Value: bson.D{{Key: "limits", Value: bson.D{{Key: "default_page_size", Value: 10}}}}},
+				{Key: "version", Value: int64(2)},
+				{Key: "updated_by", Value: "other"},
+			}),
+		)
+		_, err := store.Update(context.Background(), map[string]interface{}{"limits": map[string]interface{}{"default_page_size": 30}}, "admin")
+		if !errors.Is(err, ErrConflict) {
+			mt.Fatalf("Update() error = %v, want ErrConflict", err)
+		}
+
+		snap := store.Current()
+		if snap.Version != 2 || snap.UpdatedBy != "other" || snap.Limits.DefaultPageSize != 10 {
+			mt.Errorf("after conflict snapshot = version %d by %q with page size %d, want the reloaded version 2", snap.Version, snap.UpdatedBy, snap.Limits.DefaultPageSize)
+		}
+		if snap.Enabled("demographic_boost") != Default().Features["demographic_boost"] {
+			mt.Error("the losing writer's earlier overrides must be replaced by the stored ones")
+		}
+	})
+}
+
+func TestStoreFileLayer(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "yaml", content: "limits:\n  default_page_size: 15\n"},
+		{name: "json", content: `{"limits": {"default_page_size": 15}}`},
+		{name: "unknown key", content: "limits:\n  default_pagesize: 15\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			path := filepath.Join(t.TempDir(), "settings.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				mt.Fatal(err)
+			}
+			mt.AddMockResponses(
+				mtest.CreateSuccessResponse(),
+				mtest.CreateCursorResponse(0, "feed.settings", mtest.FirstBatch),
+			)
+			store, err := NewStore(context.Background(), Default(), path, mt.DB.Collection("settings"), mt.DB.Collection("settings_history"))
+			if (err != nil) != tt.wantErr {
+				mt.Fatalf("NewStore() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && store.Current().Limits.DefaultPageSize != 15 {
+				mt.Errorf("default page size = %d, want 15 from the file", store.Current().Limits.DefaultPageSize)
+			}
+		})
+	}
+}
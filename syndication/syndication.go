@@ -0,0 +1,255 @@
+package syndication
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"mime"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/models"
+)
+
+// Content types of the supported formats
+const (
+	RSSContentType      = "application/rss+xml; charset=utf-8"
+	AtomContentType     = "application/atom+xml; charset=utf-8"
+	JSONFeedContentType = "application/feed+json; charset=utf-8"
+)
+
+// Channel describes the feed being exported
+type Channel struct {
+	Title       string
+	Description string
+	// Link is the page the feed mirrors in the web app
+	Link string
+	// FeedURL is the URL the feed itself is served from
+	FeedURL string
+	// PostURL returns the web app link of a post
+	PostURL func(item models.FeedItem) string
+	Updated time.Time
+}
+
+// RSS renders items as an RSS 2.0 document. Authors use dc:creator since RSS
+// author elements must be email addresses.
+func RSS(ch Channel, items []models.FeedItem) ([]byte, error) {
+	type enclosure struct {
+		URL    string `xml:"url,attr"`
+		Length int    `xml:"length,attr"`
+		Type   string `xml:"type,attr"`
+	}
+	type guid struct {
+		IsPermaLink bool   `xml:"isPermaLink,attr"`
+		Value       string `xml:",chardata"`
+	}
+	type item struct {
+		Title       string      `xml:"title"`
+		Link        string      `xml:"link"`
+		GUID        guid        `xml:"guid"`
+		PubDate     string      `xml:"pubDate"`
+		Creator     string      `xml:"dc:creator,omitempty"`
+		Description string      `xml:"description"`
+		Categories  []string    `xml:"category"`
+		Enclosures  []enclosure `xml:"enclosure"`
+	}
+	type channel struct {
+		Title         string `xml:"title"`
+		Link          string `xml:"link"`
+		Description   string `xml:"description"`
+		LastBuildDate string `xml:"lastBuildDate"`
+		AtomLink      struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+			Type string `xml:"type,attr"`
+		} `xml:"atom:link"`
+		Items []item `xml:"item"`
+	}
+	type rss struct {
+		XMLName xml.Name `xml:"rss"`
+		Version string   `xml:"version,attr"`
+		Atom    string   `xml:"xmlns:atom,attr"`
+		DC      string   `xml:"xmlns:dc,attr"`
+		Channel channel  `xml:"channel"`
+	}
+
+	doc := rss{Version: "2.0", Atom: "http://www.w3.org/2005/Atom", DC: "http://purl.org/dc/elements/1.1/"}
+	doc.Channel.Title = ch.Title
+	doc.Channel.Link = ch.Link
+	doc.Channel.Description = ch.Description
+	doc.Channel.LastBuildDate = ch.Updated.UTC().Format(time.RFC1123Z)
+	doc.Channel.AtomLink.Href = ch.FeedURL
+	doc.Channel.AtomLink.Rel = "self"
+	doc.Channel.AtomLink.Type = "application/rss+xml"
+
+	for _, it := range items {
+		link := ch.PostURL(it)
+		entry := item{
+			Title:       it.Title,
+			Link:        link,
+			GUID:        guid{IsPermaLink: false, Value: it.PostID.Hex()},
+			PubDate:     it.CreatedAt.UTC().Format(time.RFC1123Z),
+			Creator:     it.AuthorName,
+			Description: it.Content,
+			Categories:  it.Tags,
+		}
+		for _, url := range it.MediaURLs {
+			// Readers require a length; 0 is the accepted value when it is unknown
+			entry.Enclosures = append(entry.Enclosures, enclosure{URL: url, Type: mediaType(url)})
+		}
+		doc.Channel.Items = append(doc.Channel.Items, entry)
+	}
+
+	return marshalXML(doc)
+}
+
+// Atom renders items as an Atom 1.0 document, with media as enclosure links
+func Atom(ch Channel, items []models.FeedItem) ([]byte, error) {
+	type link struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr,omitempty"`
+		Type string `xml:"type,attr,omitempty"`
+	}
+	type person struct {
+		Name string `xml:"name"`
+	}
+	type category struct {
+		Term string `xml:"term,attr"`
+	}
+	type text struct {
+		Type  string `xml:"type,attr"`
+		Value string `xml:",chardata"`
+	}
+	type entry struct {
+		ID         string     `xml:"id"`
+		Title      string     `xml:"title"`
+		Updated    string     `xml:"updated"`
+		Published  string     `xml:"published"`
+		Author     *person    `xml:"author,omitempty"`
+		Links      []link     `xml:"link"`
+		Categories []category `xml:"category"`
+		Content    text       `xml:"content"`
+	}
+	type feed struct {
+		XMLName  xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		ID       string   `xml:"id"`
+		Title    string   `xml:"title"`
+		Subtitle string   `xml:"subtitle,omitempty"`
+		Updated  string   `xml:"updated"`
+		Links    []link   `xml:"link"`
+		Entries  []entry  `xml:"entry"`
+	}
+
+	doc := feed{
+		ID:       ch.Link,
+		Title:    ch.Title,
+		Subtitle: ch.Description,
+		Updated:  ch.Updated.UTC().Format(time.RFC3339),
+		Links: []link{
+			{Href: ch.FeedURL, Rel: "self", Type: "application/atom+xml"},
+			{Href: ch.Link, Rel: "alternate", Type: "text/html"},
+		},
+	}
+
+	for _, it := range items {
+		published := it.CreatedAt.UTC().Format(time.RFC3339)
+		e := entry{
+			ID:        "urn:circleconnect:post:" + it.PostID.Hex(),
+			Title:     it.Title,
+			Updated:   published,
+			Published: published,
+			Links:     []link{{Href: ch.PostURL(it), Rel: "alternate", Type: "text/html"}},
+			Content:   text{Type: "text", Value: it.Content},
+		}
+		if it.AuthorName != "" {
+			e.Author = &person{Name: it.AuthorName}
+		}
+		for _, tag := range it.Tags {
+			e.Categories = append(e.Categories, category{Term: tag})
+		}
+		for _, url := range it.MediaURLs {
+			e.Links = append(e.Links, link{Href: url, Rel: "enclosure", Type: mediaType(url)})
+		}
+		doc.Entries = append(doc.Entries, e)
+	}
+
+	return marshalXML(doc)
+}
+
+// JSONFeed renders items as a JSON Feed 1.1 document
+func JSONFeed(ch Channel, items []models.FeedItem) ([]byte, error) {
+	type author struct {
+		Name   string `json:"name"`
+		Avatar string `json:"avatar,omitempty"`
+	}
+	type attachment struct {
+		URL      string `json:"url"`
+		MimeType string `json:"mime_type"`
+	}
+	type item struct {
+		ID            string       `json:"id"`
+		URL           string       `json:"url"`
+		Title         string       `json:"title,omitempty"`
+		ContentText   string       `json:"content_text"`
+		DatePublished string       `json:"date_published"`
+		Authors       []author     `json:"authors,omitempty"`
+		Tags          []string     `json:"tags,omitempty"`
+		Attachments   []attachment `json:"attachments,omitempty"`
+	}
+	type feed struct {
+		Version     string `json:"version"`
+		Title       string `json:"title"`
+		HomePageURL string `json:"home_page_url"`
+		FeedURL     string `json:"feed_url"`
+		Description string `json:"description,omitempty"`
+		Items       []item `json:"items"`
+	}
+
+	doc := feed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       ch.Title,
+		HomePageURL: ch.Link,
+		FeedURL:     ch.FeedURL,
+		Description: ch.Description,
+		Items:       make([]item, 0, len(items)),
+	}
+
+	for _, it := range items {
+		entry := item{
+			ID:            it.PostID.Hex(),
+			URL:           ch.PostURL(it),
+			Title:         it.Title,
+			ContentText:   it.Content,
+			DatePublished: it.CreatedAt.UTC().Format(time.RFC3339),
+			Tags:          it.Tags,
+		}
+		if it.AuthorName != "" {
+			entry.Authors = []author{{Name: it.AuthorName, Avatar: it.AuthorAvatar}}
+		}
+		for _, url := range it.MediaURLs {
+			entry.Attachments = append(entry.Attachments, attachment{URL: url, MimeType: mediaType(url)})
+		}
+		doc.Items = append(doc.Items, entry)
+	}
+
+	return json.Marshal(doc)
+}
+
+func marshalXML(doc interface{}) ([]byte, error) {
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// mediaType guesses a media URL's type from its extension
+func mediaType(url string) string {
+	if i := strings.IndexAny(url, "?#"); i >= 0 {
+		url = url[:i]
+	}
+	if t := mime.TypeByExtension(strings.ToLower(path.Ext(url))); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
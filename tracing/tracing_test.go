@@ -0,0 +1,199 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// setupRecorder installs tracing with an in-memory exporter, sampling every
+// trace, and returns a recorder that sees spans as soon as they end
+func setupRecorder(t *testing.T) (*tracetest.SpanRecorder, *tracetest.InMemoryExporter) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	shutdown, err := Setup(context.Background(), exporter, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { shutdown(context.Background()) })
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.GetTracerProvider().(*sdktrace.TracerProvider).RegisterSpanProcessor(recorder)
+	return recorder, exporter
+}
+
+func spansByKind(spans []sdktrace.ReadOnlySpan, kind trace.SpanKind) []sdktrace.ReadOnlySpan {
+	var found []sdktrace.ReadOnlySpan
+	for _, s := range spans {
+		if s.SpanKind() == kind {
+			found = append(found, s)
+		}
+	}
+	return found
+}
+
+func TestServerAndClientSpansPropagate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder, exporter := setupRecorder(t)
+
+	// The upstream captures the trace context the service sends it
+	var upstreamHeader http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamHeader = r.Header.Clone()
+	}))
+	defer upstream.Close()
+
+	httpClient := &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
+	router := gin.New()
+	router.Use(otelgin.Middleware(ServiceName))
+	router.GET("/api/feed", func(c *gin.Context) {
+		ctx, span := Tracer().Start(c.Request.Context(), "buildFeed")
+		defer span.End()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL, nil)
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resp.Body.Close()
+		c.Status(http.StatusOK)
+	})
+
+	// The caller's trace context continues through the service
+	incoming := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	req := httptest.NewRequest(http.MethodGet, "/api/feed", nil)
+	otel.GetTextMapPropagator().Inject(trace.ContextWithSpanContext(context.Background(), incoming), propagation.HeaderCarrier(req.Header))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d", w.Code)
+	}
+
+	spans := recorder.Ended()
+	servers := spansByKind(spans, trace.SpanKindServer)
+	clients := spansByKind(spans, trace.SpanKindClient)
+	internals := spansByKind(spans, trace.SpanKindInternal)
+	if len(servers) != 1 || len(clients) != 1 || len(internals) != 1 {
+		t.Fatalf("got %d server, %d client and %d internal spans, want one of each", len(servers), len(clients), len(internals))
+	}
+	server, client, internal := servers[0], clients[0], internals[0]
+
+	if server.Name() != "/api/feed" {
+		t.Errorf("server span name = %q, want the route", server.Name())
+	}
+	if got := server.SpanContext().TraceID(); got != incoming.TraceID() {
+		t.Errorf("server span trace = %s, want the incoming trace %s", got, incoming.TraceID())
+	}
+	if got := server.Parent().SpanID(); got != incoming.SpanID() || !server.Parent().IsRemote() {
+		t.Errorf("server span parent = %s, want the remote caller %s", got, incoming.SpanID())
+	}
+	if internal.Name() != "buildFeed" || internal.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Errorf("internal span %q is not a child of the server span", internal.Name())
+	}
+	if client.Parent().SpanID() != internal.SpanContext().SpanID() {
+		t.Error("client span is not a child of the span that made the call")
+	}
+	for _, s := range spans {
+		if name, _ := s.Resource().Set().Value(semconv.ServiceNameKey); name.AsString() != ServiceName {
+			t.Errorf("span %q has service name %q, want %q", s.Name(), name.AsString(), ServiceName)
+		}
+	}
+
+	// The upstream receives the client span as its parent
+	remote := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(upstreamHeader))
+	upstreamParent := trace.SpanContextFromContext(remote)
+	if upstreamHeader.Get("traceparent") == "" || !upstreamParent.IsValid() {
+		t.Fatalf("upstream did not receive a traceparent header: %v", upstreamHeader)
+	}
+	if upstreamParent.TraceID() != incoming.TraceID() {
+		t.Errorf("upstream trace = %s, want %s", upstreamParent.TraceID(), incoming.TraceID())
+	}
+	if upstreamParent.SpanID() != client.SpanContext().SpanID() {
+		t.Errorf("upstream parent = %s, want the client span %s", upstreamParent.SpanID(), client.SpanContext().SpanID())
+	}
+
+	// The configured exporter receives the same spans once flushed
+	if err := otel.GetTracerProvider().(*sdktrace.TracerProvider).ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(exporter.GetSpans()); got != len(spans) {
+		t.Errorf("exporter received %d spans, want %d", got, len(spans))
+	}
+}
+
+func TestRecordError(t *testing.T) {
+	recorder, _ := setupRecorder(t)
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus codes.Code
+		wantEvents int
+	}{
+		{name: "nil error", err: nil, wantStatus: codes.Unset, wantEvents: 0},
+		{name: "error", err: errors.New("upstream failed"), wantStatus: codes.Error, wantEvents: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, span := Tracer().Start(context.Background(), tt.name)
+			RecordError(span, tt.err)
+			span.End()
+
+			ended := recorder.Ended()
+			got := ended[len(ended)-1]
+			if got.Status().Code != tt.wantStatus {
+				t.Errorf("status = %v, want %v", got.Status().Code, tt.wantStatus)
+			}
+			if len(got.Events()) != tt.wantEvents {
+				t.Errorf("events = %d, want %d", len(got.Events()), tt.wantEvents)
+			}
+		})
+	}
+}
+
+func TestNewExporter(t *testing.T) {
+	tests := []struct {
+		kind    string
+		wantNil bool
+		wantErr bool
+	}{
+		{kind: "", wantNil: true},
+		{kind: "none", wantNil: true},
+		{kind: "otlp"},
+		{kind: "jaeger", wantNil: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			exporter, err := NewExporter(context.Background(), tt.kind)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (exporter == nil) != tt.wantNil {
+				t.Errorf("exporter = %v, wantNil %v", exporter, tt.wantNil)
+			}
+			if exporter != nil {
+				exporter.Shutdown(context.Background())
+			}
+		})
+	}
+}
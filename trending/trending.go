@@ -0,0 +1,286 @@
+package trending
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/CircleConnectApp/feed-service/metrics"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Post event types counted towards trending
+const (
+	Liked   = "post_liked"
+	Unliked = "post_unliked"
+)
+
+const (
+	// bucketSize is the resolution of the like counters
+	bucketSize = 5 * time.Minute
+	// retention covers the longest window
+	retention = 7*24*time.Hour + time.Hour
+	cacheTTL  = 30 * time.Second
+	// maxUpsertRetries bounds the retries of writes that lost a race to
+	// create a bucket
+	maxUpsertRetries = 2
+)
+
+// Windows are the supported trending periods
+var Windows = map[string]time.Duration{
+	"1h":  time.Hour,
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+}
+
+// Event is a change to a post's likes reported by the post service
+type Event struct {
+	Type        string
+	PostID      string
+	CommunityID int
+	AuthorID    int
+	Tags        []string
+	OccurredAt  time.Time
+}
+
+// Post is a trending post with its likes in the window
+type Post struct {
+	PostID      string   `bson:"_id" json:"post_id"`
+	CommunityID int      `bson:"community_id" json:"community_id"`
+	AuthorID    int      `bson:"author_id" json:"author_id"`
+	Tags        []string `bson:"tags" json:"tags,omitempty"`
+	Likes       int      `bson:"likes" json:"likes"`
+	Velocity    float64  `bson:"-" json:"velocity"` // likes per hour over the window
+	Score       float64  `bson:"score" json:"score"`
+}
+
+// Tag is a trending tag with the likes its posts received in the window
+type Tag struct {
+	Tag   string  `bson:"_id" json:"tag"`
+	Posts int     `bson:"posts" json:"posts"`
+	Likes int     `bson:"likes" json:"likes"`
+	Score float64 `bson:"score" json:"score"`
+}
+
+// Store keeps per-post like counters in time buckets and ranks posts and
+// tags by like velocity. Likes are weighted by age with a half-life of a
+// quarter of the window, so posts gaining likes now outrank posts that
+// gained as many earlier in the window.
+type Store struct {
+	collection *mongo.Collection
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// NewStore creates the store's indexes. Buckets expire once they fall out of
+// the longest window.
+func NewStore(ctx context.Context, collection *mongo.Collection) (*Store, error) {
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "post_id", Value: 1}, {Key: "bucket", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "bucket", Value: 1}, {Key: "community_id", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{collection: collection, cache: make(map[string]cacheEntry)}, nil
+}
+
+// Record adds events to the like counters. Events older than the longest
+// window are ignored, and events reported in the future are counted now.
+func (s *Store) Record(ctx context.Context, events []Event) error {
+	now := time.Now()
+	cutoff := now.Add(-retention)
+	writes := make([]mongo.WriteModel, 0, len(events))
+	for _, e := range events {
+		if e.OccurredAt.Before(cutoff) {
+			continue
+		}
+		if e.OccurredAt.After(now) {
+			e.OccurredAt = now
+		}
+		delta := 1
+		if e.Type == Unliked {
+			delta = -1
+		}
+
+		bucket := e.OccurredAt.UTC().Truncate(bucketSize)
+		set := bson.M{"community_id": e.CommunityID, "author_id": e.AuthorID, "expires_at": bucket.Add(retention)}
+		if e.Tags != nil {
+			set["tags"] = e.Tags
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"post_id": e.PostID, "bucket": bucket}).
+			SetUpdate(bson.M{"$inc": bson.M{"likes": delta}, "$set": set}).
+			SetUpsert(true))
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+
+	// Concurrent upserts creating the same bucket race on the unique index
+	// and the loser fails with a duplicate key error; retried, it finds the
+	// bucket and updates it
+	for attempt := 0; ; attempt++ {
+		_, err := s.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+		var bulkErr mongo.BulkWriteException
+		if err == nil || attempt == maxUpsertRetries || !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+			return err
+		}
+
+		retry := make([]mongo.WriteModel, 0, len(bulkErr.WriteErrors))
+		for _, writeErr := range bulkErr.WriteErrors {
+			if !mongo.IsDuplicateKeyError(writeErr) {
+				return err
+			}
+			retry = append(retry, writes[writeErr.Index])
+		}
+		writes = retry
+	}
+}
+
+// Posts returns the top trending posts in window, optionally within one community
+func (s *Store) Posts(ctx context.Context, window time.Duration, communityID, limit int) ([]Post, error) {
+	key := fmt.Sprintf("posts/%s/%d/%d", window, communityID, limit)
+	if cached, ok := s.cached(key); ok {
+		return clonePosts(cached.([]Post)), nil
+	}
+
+	pipeline := append(s.weighted(window, communityID),
+		// Oldest first, so $last picks the most recently reported metadata
+		bson.D{{Key: "$sort", Value: bson.M{"bucket": 1}}},
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":          "$post_id",
+			"community_id": bson.M{"$last": "$community_id"},
+			"author_id":    bson.M{"$last": "$author_id"},
+			"tags":         bson.M{"$last": "$tags"},
+			"likes":        bson.M{"$sum": "$likes"},
+			"score":        bson.M{"$sum": "$weight"},
+		}}},
+		bson.D{{Key: "$match", Value: bson.M{"likes": bson.M{"$gt": 0}, "score": bson.M{"$gt": 0}}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "score", Value: -1}, {Key: "_id", Value: 1}}}},
+		bson.D{{Key: "$limit", Value: limit}},
+	)
+
+	posts := []Post{}
+	if err := s.aggregate(ctx, pipeline, &posts); err != nil {
+		return nil, err
+	}
+	for i := range posts {
+		posts[i].Velocity = float64(posts[i].Likes) / window.Hours()
+	}
+
+	// Callers get their own copy, so they cannot change the cached result
+	s.store(key, clonePosts(posts))
+	return posts, nil
+}
+
+// Tags returns the top trending tags in window, optionally within one community
+func (s *Store) Tags(ctx context.Context, window time.Duration, communityID, limit int) ([]Tag, error) {
+	key := fmt.Sprintf("tags/%s/%d/%d", window, communityID, limit)
+	if cached, ok := s.cached(key); ok {
+		tags := cached.([]Tag)
+		return append(make([]Tag, 0, len(tags)), tags...), nil
+	}
+
+	pipeline := append(s.weighted(window, communityID),
+		bson.D{{Key: "$unwind", Value: "$tags"}},
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":      "$tags",
+			"post_ids": bson.M{"$addToSet": "$post_id"},
+			"likes":    bson.M{"$sum": "$likes"},
+			"score":    bson.M{"$sum": "$weight"},
+		}}},
+		bson.D{{Key: "$match", Value: bson.M{"likes": bson.M{"$gt": 0}, "score": bson.M{"$gt": 0}}}},
+		bson.D{{Key: "$project", Value: bson.M{"posts": bson.M{"$size": "$post_ids"}, "likes": 1, "score": 1}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "score", Value: -1}, {Key: "_id", Value: 1}}}},
+		bson.D{{Key: "$limit", Value: limit}},
+	)
+
+	tags := []Tag{}
+	if err := s.aggregate(ctx, pipeline, &tags); err != nil {
+		return nil, err
+	}
+
+	s.store(key, append(make([]Tag, 0, len(tags)), tags...))
+	return tags, nil
+}
+
+// weighted selects the buckets in window and weights each bucket's likes by
+// its age
+func (s *Store) weighted(window time.Duration, communityID int) mongo.Pipeline {
+	now := time.Now().UTC()
+	match := bson.M{"bucket": bson.M{"$gte": now.Add(-window).Truncate(bucketSize)}}
+	if communityID > 0 {
+		match["community_id"] = communityID
+	}
+	halfLife := float64((window / 4).Milliseconds())
+
+	return mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$addFields", Value: bson.M{
+			"weight": bson.M{"$multiply": bson.A{
+				"$likes",
+				bson.M{"$pow": bson.A{0.5, bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{now, "$bucket"}}, halfLife}}}},
+			}},
+		}}},
+	}
+}
+
+func (s *Store) aggregate(ctx context.Context, pipeline mongo.Pipeline, out interface{}) error {
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, out)
+}
+
+// cached returns a result computed within cacheTTL; trending changes slowly
+// and the aggregations scan every bucket in the window
+func (s *Store) cached(key string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.cache[key]
+	hit := ok && time.Now().Before(entry.expires)
+	metrics.CacheLookup("trending", hit)
+	return entry.value, hit
+}
+
+func (s *Store) store(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for k, entry := range s.cache {
+		if now.After(entry.expires) {
+			delete(s.cache, k)
+		}
+	}
+	s.cache[key] = cacheEntry{value: value, expires: now.Add(cacheTTL)}
+}
+
+func clonePosts(posts []Post) []Post {
+	out := make([]Post, len(posts))
+	for i, post := range posts {
+		post.Tags = append([]string(nil), post.Tags...)
+		out[i] = post
+	}
+	return out
+}
@@ -0,0 +1,198 @@
+package trending
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func newMockStore(mt *mtest.T) *Store {
+	mt.AddMockResponses(mtest.CreateSuccessResponse())
+	store, err := NewStore(context.Background(), mt.Coll)
+	if err != nil {
+		mt.Fatal(err)
+	}
+	mt.ClearEvents()
+	return store
+}
+
+// updateFilters returns the filters of the updates sent in each update command
+func updateFilters(mt *mtest.T) [][]bson.Raw {
+	var commands [][]bson.Raw
+	for _, event := range mt.GetAllStartedEvents() {
+		if event.CommandName != "update" {
+			continue
+		}
+		values, err := event.Command.Lookup("updates").Array().Values()
+		if err != nil {
+			mt.Fatal(err)
+		}
+		filters := make([]bson.Raw, len(values))
+		for i, value := range values {
+			filters[i] = value.Document().Lookup("q").Document()
+		}
+		commands = append(commands, filters)
+	}
+	return commands
+}
+
+func TestRecord(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		events      []Event
+		responses   []bson.D
+		wantErr     bool
+		wantUpdates []int // updates per command sent
+	}{
+		{
+			name:        "one upsert per event",
+			events:      []Event{{Type: Liked, PostID: "a", OccurredAt: now}, {Type: Unliked, PostID: "b", OccurredAt: now}},
+			responses:   []bson.D{mtest.CreateSuccessResponse()},
+			wantUpdates: []int{2},
+		},
+		{
+			name:   "events beyond retention are ignored",
+			events: []Event{{Type: Liked, PostID: "a", OccurredAt: now.Add(-8 * 24 * time.Hour)}},
+		},
+		{
+			name:   "upserts losing the bucket race are retried",
+			events: []Event{{Type: Liked, PostID: "a", OccurredAt: now}, {Type: Liked, PostID: "b", OccurredAt: now}},
+			responses: []bson.D{
+				mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 1, Code: 11000, Message: "E11000 duplicate key error"}),
+				mtest.CreateSuccessResponse(),
+			},
+			wantUpdates: []int{2, 1},
+		},
+		{
+			name:   "retries are bounded",
+			events: []Event{{Type: Liked, PostID: "a", OccurredAt: now}},
+			responses: []bson.D{
+				mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "E11000 duplicate key error"}),
+				mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "E11000 duplicate key error"}),
+				mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "E11000 duplicate key error"}),
+			},
+			wantErr:     true,
+			wantUpdates: []int{1, 1, 1},
+		},
+		{
+			name:        "other write errors are not retried",
+			events:      []Event{{Type: Liked, PostID: "a", OccurredAt: now}, {Type: Liked, PostID: "b", OccurredAt: now}},
+			responses:   []bson.D{mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "E11000"}, mtest.WriteError{Index: 1, Code: 2, Message: "bad value"})},
+			wantErr:     true,
+			wantUpdates: []int{2},
+		},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			store := newMockStore(mt)
+			mt.AddMockResponses(tt.responses...)
+
+			err := store.Record(context.Background(), tt.events)
+			if (err != nil) != tt.wantErr {
+				mt.Fatalf("Record() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []int
+			for _, filters := range updateFilters(mt) {
+				got = append(got, len(filters))
+			}
+			if !reflect.DeepEqual(got, tt.wantUpdates) {
+				mt.Errorf("updates per command = %v, want %v", got, tt.wantUpdates)
+			}
+		})
+	}
+}
+
+func TestRecordClampsFutureEvents(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("future", func(mt *mtest.T) {
+		store := newMockStore(mt)
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		before := time.Now().UTC().Truncate(bucketSize)
+		if err := store.Record(context.Background(), []Event{{Type: Liked, PostID: "a", OccurredAt: time.Now().Add(48 * time.Hour)}}); err != nil {
+			mt.Fatal(err)
+		}
+		after := time.Now().UTC().Truncate(bucketSize)
+
+		filters := updateFilters(mt)
+		if len(filters) != 1 {
+			mt.Fatalf("sent %d update commands, want 1", len(filters))
+		}
+		bucket := filters[0][0].Lookup("bucket").Time()
+		if bucket.Before(before) || bucket.After(after) {
+			mt.Errorf("bucket = %v, want the current bucket %v", bucket, before)
+		}
+	})
+}
+
+func TestCachedResultsAreCopies(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("posts", func(mt *mtest.T) {
+		store := newMockStore(mt)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "feed.trending", mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: "a"}, {Key: "tags", Value: bson.A{"go"}}, {Key: "likes", Value: 3}, {Key: "score", Value: 2.5}},
+		))
+
+		first, err := store.Posts(context.Background(), time.Hour, 0, 10)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		first[0].Likes = 100
+		first[0].Tags[0] = "changed"
+
+		// Served from the cache; no second aggregation is queued
+		second, err := store.Posts(context.Background(), time.Hour, 0, 10)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if second[0].Likes != 3 || second[0].Tags[0] != "go" || second[0].Velocity != 3 {
+			mt.Errorf("cached post = %+v, want the original", second[0])
+		}
+	})
+
+	mt.Run("tags", func(mt *mtest.T) {
+		store := newMockStore(mt)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "feed.trending", mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: "go"}, {Key: "posts", Value: 2}, {Key: "likes", Value: 3}, {Key: "score", Value: 2.5}},
+		))
+
+		first, err := store.Tags(context.Background(), time.Hour, 0, 10)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		first[0].Likes = 100
+
+		second, err := store.Tags(context.Background(), time.Hour, 0, 10)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if second[0].Likes != 3 {
+			mt.Errorf("cached tag = %+v, want the original", second[0])
+		}
+	})
+
+	mt.Run("empty results stay empty lists", func(mt *mtest.T) {
+		store := newMockStore(mt)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "feed.trending", mtest.FirstBatch))
+
+		for i := 0; i < 2; i++ {
+			posts, err := store.Posts(context.Background(), time.Hour, 0, 10)
+			if err != nil {
+				mt.Fatal(err)
+			}
+			if posts == nil {
+				mt.Fatal("Posts() = nil, want an empty list")
+			}
+		}
+	})
+}